@@ -0,0 +1,48 @@
+package smartdoor
+
+import (
+	"context"
+	"time"
+)
+
+// ActionContext is what led to a door action, captured when it was decided
+// and carried to the executor and into events so integrations need not
+// re-derive it. Label, Confidence and Camera are empty for actions no
+// detection triggered, such as a relock or an override.
+type ActionContext struct {
+	Action DoorAction `json:"action"`
+	// Reason is the StateReason the action leaves, such as
+	// "unlocked: dog 0.94 on cam0".
+	Reason     string    `json:"reason"`
+	Label      string    `json:"label,omitempty"`
+	Confidence float64   `json:"confidence,omitempty"`
+	Camera     string    `json:"camera,omitempty"`
+	Time       time.Time `json:"time"`
+	// capturedAt is when the frame that triggered the action was taken,
+	// for the latency metrics.
+	capturedAt time.Time
+}
+
+func newActionContext(action DoorAction, now time.Time, trigger *Trigger, reason string) ActionContext {
+	ac := ActionContext{Action: action, Reason: reason, Time: now}
+	if trigger != nil {
+		ac.Label = trigger.Classification.Label
+		ac.Confidence = trigger.Classification.Confidence
+		ac.Camera = trigger.Camera
+		ac.capturedAt = trigger.CapturedAt
+	}
+	return ac
+}
+
+type actionContextKey struct{}
+
+// ActionContextFrom returns the ActionContext of the action an
+// ActionExecutor is running, from the context Execute is called with.
+func ActionContextFrom(ctx context.Context) (ActionContext, bool) {
+	ac, ok := ctx.Value(actionContextKey{}).(ActionContext)
+	return ac, ok
+}
+
+func withActionContext(ctx context.Context, ac ActionContext) context.Context {
+	return context.WithValue(ctx, actionContextKey{}, ac)
+}
@@ -0,0 +1,60 @@
+package smartdoor
+
+import "fmt"
+
+// Arm resumes acting on detections after Disarm.
+func (sd *SmartDoor) Arm() {
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callArm})
+	sd.setArmed(true)
+}
+
+// Disarm stops the automation from moving the door: detections are still
+// made, logged and reported as EventDetection, but no action is decided
+// and the relock, cap, hold, fail-safe and reassert rules stand down, so
+// the door stays as it is. ForceLock and ForceUnlock still apply.
+func (sd *SmartDoor) Disarm() {
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callDisarm})
+	sd.setArmed(false)
+}
+
+// Armed reports whether detections are acted on.
+func (sd *SmartDoor) Armed() bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return !sd.disarmed
+}
+
+func (sd *SmartDoor) setArmed(armed bool) {
+	sd.mu.Lock()
+	changed := sd.disarmed == armed
+	sd.disarmed = !armed
+	sd.mu.Unlock()
+	if !changed {
+		return
+	}
+	if armed {
+		sd.logger.Info("armed")
+	} else {
+		sd.logger.Info("disarmed")
+	}
+}
+
+// observeDisarmed evaluates a decided cycle while disarmed. The detection
+// is reported whenever it changes, but nothing else about the controller
+// moves, so arming again resumes from the state disarming left.
+func (sd *SmartDoor) observeDisarmed(batch CycleResult) decision {
+	sd.mu.Lock()
+	sd.stats.Evaluations++
+	sd.mu.Unlock()
+
+	detection, trigger := sd.detect(batch)
+	batch.locate(trigger)
+	if !sd.observing || detection != sd.observed {
+		sd.observing = true
+		sd.observed = detection
+		msg := fmt.Sprintf("disarmed: detected %s", detection)
+		sd.logger.Info(msg)
+		sd.emit(Event{Kind: EventDetection, Time: sd.clock.Now(), Detection: detection, Trigger: trigger, Message: msg})
+	}
+	return decision{Detection: detection, Trigger: trigger, Reason: ReasonDisarmed}
+}
@@ -0,0 +1,84 @@
+package smartdoor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultBreakerCooldown = 30 * time.Second
+
+var (
+	// ErrBreakerOpen reports a cycle skipped while the classifier breaker
+	// is open.
+	ErrBreakerOpen = errors.New("smartdoor: classifier breaker is open")
+	// ErrStaleClassifier reports a result discarded because it tripped the
+	// breaker.
+	ErrStaleClassifier = errors.New("smartdoor: classifier returned identical results for changing frames")
+)
+
+// detectStaleClassifier tracks consecutive identical results returned for
+// changing frames and trips the breaker once Config.MaxIdenticalResults is
+// reached. It reports whether the current result should be discarded.
+func (sd *SmartDoor) detectStaleClassifier(frames []Frame, classifications [][]Classification) bool {
+	limit := sd.currentConfig().MaxIdenticalResults
+	if limit <= 0 {
+		return false
+	}
+
+	framesChanged := sd.staleFrames != nil && !equalFrames(frames, sd.staleFrames)
+	if framesChanged && equalClassifications(classifications, sd.staleResult) {
+		sd.identicalCount++
+	} else {
+		sd.identicalCount = 1
+	}
+	sd.staleFrames = cloneFrames(frames)
+	sd.staleResult = cloneClassifications(classifications)
+
+	if sd.identicalCount < limit {
+		return false
+	}
+
+	count := sd.identicalCount
+	sd.identicalCount = 0
+	sd.staleFrames = nil
+	sd.tripBreaker(fmt.Sprintf("classifier returned %d identical results while frames changed", count))
+	return true
+}
+
+func (sd *SmartDoor) tripBreaker(reason string) {
+	cooldown := sd.currentConfig().ClassifierBreakerCooldown
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	now := sd.clock.Now()
+	sd.mu.Lock()
+	sd.breakerOpenUntil = now.Add(cooldown)
+	sd.stats.BreakerTrips++
+	sd.mu.Unlock()
+
+	sd.emit(Event{Kind: EventError, Time: now, Message: reason})
+}
+
+func equalFrames(a, b []Frame) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Data, b[i].Data) {
+			return false
+		}
+	}
+	return true
+}
+
+func cloneFrames(frames []Frame) []Frame {
+	cloned := make([]Frame, len(frames))
+	for i, frame := range frames {
+		cloned[i] = frame
+		cloned[i].Data = append([]byte(nil), frame.Data...)
+	}
+	return cloned
+}
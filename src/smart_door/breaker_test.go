@@ -0,0 +1,56 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStaleClassifierTripsBreaker(t *testing.T) {
+	config := Config{MaxIdenticalResults: 3, ClassifierBreakerCooldown: time.Minute}
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, camera, _, clock := newTestSmartDoor(config, classifier)
+	camera.changing = true
+	events := sd.Events()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if sd.runCycle(ctx).Outcome != OutcomeDecided {
+			t.Fatalf("cycle %d should succeed before the breaker trips", i)
+		}
+	}
+	if sd.runCycle(ctx).Outcome == OutcomeDecided {
+		t.Fatal("expected the third identical result to be discarded")
+	}
+	if e := <-events; e.Kind != EventError {
+		t.Fatalf("expected error event, got %+v", e)
+	}
+
+	sd.runCycle(ctx)
+	if calls := classifier.Calls(); calls != 3 {
+		t.Fatalf("expected classifier to be skipped while the breaker is open, got %d calls", calls)
+	}
+	if stats := sd.Stats(); stats.BreakerTrips != 1 || stats.BreakerSkipped != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	clock.Advance(time.Minute)
+	if sd.runCycle(ctx).Outcome != OutcomeDecided {
+		t.Fatal("expected classification to resume after the cooldown")
+	}
+}
+
+func TestIdenticalResultsForStaticFramesAreHealthy(t *testing.T) {
+	config := Config{MaxIdenticalResults: 2}
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, _, _, _ := newTestSmartDoor(config, classifier)
+
+	for i := 0; i < 5; i++ {
+		if sd.runCycle(context.Background()).Outcome != OutcomeDecided {
+			t.Fatalf("cycle %d should succeed for unchanged frames", i)
+		}
+	}
+	if trips := sd.Stats().BreakerTrips; trips != 0 {
+		t.Fatalf("expected no breaker trips, got %d", trips)
+	}
+}
@@ -0,0 +1,126 @@
+package smartdoor
+
+import (
+	"math"
+	"sort"
+)
+
+// recentConfidenceLimit bounds how many confidences are kept per label.
+const recentConfidenceLimit = 256
+
+// RecentConfidences returns up to the n most recent confidences the
+// classifier reported for label, oldest first, for picking MinConfidence
+// from real data. Confidences are kept per label on the unlock, lock or
+// ignore lists, under every entry a classification's label matches as
+// matchesLabel does, and at most recentConfidenceLimit values each; n <= 0
+// returns all of them.
+func (sd *SmartDoor) RecentConfidences(label string, n int) []float64 {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	recent := sd.recentConfidences[label]
+	if n > 0 && n < len(recent) {
+		recent = recent[len(recent)-n:]
+	}
+	return append([]float64(nil), recent...)
+}
+
+// adaptiveMinSamples is how many confident detections an adaptive
+// threshold needs before it departs from MinConfidence.
+const adaptiveMinSamples = 10
+
+// EffectiveThreshold returns the confidence label currently needs on the
+// first list entry it matches, after any AdaptiveThreshold and
+// Config.GlobalMinConfidence, or 0 if it matches no entry.
+func (sd *SmartDoor) EffectiveThreshold(label string) float64 {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	config := sd.effectiveConfigLocked()
+	for _, list := range [][]ClassificationConfig{config.ClassificationUnlockList, config.ClassificationLockList, config.IgnoreList} {
+		for _, entry := range list {
+			if labelMatches(label, entry.Label) {
+				return sd.thresholdLocked(entry)
+			}
+		}
+	}
+	return 0
+}
+
+// adaptList returns list with every MinConfidence replaced by its
+// effective threshold.
+func (sd *SmartDoor) adaptList(list []ClassificationConfig) []ClassificationConfig {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	floor := sd.effectiveConfigLocked().GlobalMinConfidence
+	var adapted []ClassificationConfig
+	for i, entry := range list {
+		if entry.Adaptive.Percentile == 0 && entry.MinConfidence >= floor {
+			continue
+		}
+		if adapted == nil {
+			adapted = append([]ClassificationConfig(nil), list...)
+		}
+		adapted[i].MinConfidence = sd.thresholdLocked(entry)
+	}
+	if adapted == nil {
+		return list
+	}
+	return adapted
+}
+
+// thresholdLocked must be called with sd.mu held.
+func (sd *SmartDoor) thresholdLocked(entry ClassificationConfig) float64 {
+	return max(sd.adaptiveThresholdLocked(entry), sd.effectiveConfigLocked().GlobalMinConfidence)
+}
+
+// adaptiveThresholdLocked must be called with sd.mu held.
+func (sd *SmartDoor) adaptiveThresholdLocked(entry ClassificationConfig) float64 {
+	a := entry.Adaptive
+	if a.Percentile == 0 {
+		return entry.MinConfidence
+	}
+	var confident []float64
+	for _, c := range sd.recentConfidences[entry.Label] {
+		if c >= a.Min {
+			confident = append(confident, c)
+		}
+	}
+	if len(confident) < adaptiveMinSamples {
+		return entry.MinConfidence
+	}
+	sort.Float64s(confident)
+	rank := int(math.Ceil(a.Percentile/100*float64(len(confident)))) - 1
+	return min(max(confident[max(rank, 0)], a.Min), a.Max)
+}
+
+// recordConfidencesLocked must be called with sd.mu held.
+func (sd *SmartDoor) recordConfidencesLocked(classifications [][]Classification) {
+	config := sd.effectiveConfigLocked()
+	var tracked []string
+	seen := make(map[string]bool)
+	for _, list := range [][]ClassificationConfig{config.ClassificationUnlockList, config.ClassificationLockList, config.IgnoreList} {
+		for _, entry := range list {
+			if !seen[entry.Label] {
+				seen[entry.Label] = true
+				tracked = append(tracked, entry.Label)
+			}
+		}
+	}
+
+	if sd.recentConfidences == nil {
+		sd.recentConfidences = make(map[string][]float64)
+	}
+	for _, frame := range classifications {
+		for _, c := range frame {
+			for _, label := range tracked {
+				if !labelMatches(c.Label, label) {
+					continue
+				}
+				recent := append(sd.recentConfidences[label], c.Confidence)
+				if len(recent) > recentConfidenceLimit {
+					recent = recent[len(recent)-recentConfidenceLimit:]
+				}
+				sd.recentConfidences[label] = recent
+			}
+		}
+	}
+}
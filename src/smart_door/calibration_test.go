@@ -0,0 +1,131 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecentConfidencesReturnsLatestInOrder(t *testing.T) {
+	var results []fakeResult
+	for _, confidence := range []float64{0.1, 0.2, 0.3, 0.4} {
+		results = append(results, fakeResult{classifications: [][]Classification{{
+			{Label: "dog", Confidence: confidence},
+			{Label: "tree", Confidence: 0.9},
+		}}})
+	}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{results: results})
+	for range results {
+		sd.runCycle(context.Background())
+	}
+
+	got := sd.RecentConfidences("dog", 3)
+	want := []float64{0.2, 0.3, 0.4}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if all := sd.RecentConfidences("dog", 0); len(all) != 4 {
+		t.Fatalf("expected all 4 confidences, got %v", all)
+	}
+	if tree := sd.RecentConfidences("tree", 10); len(tree) != 0 {
+		t.Fatalf("expected untracked label to be empty, got %v", tree)
+	}
+}
+
+func TestCalibrationKeysByMatchedEntry(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].MinConfidence = 0.6
+	config.ClassificationUnlockList[0].Adaptive = AdaptiveThreshold{Percentile: 10, Min: 0.5, Max: 0.8}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	sd.mu.Lock()
+	for range adaptiveMinSamples {
+		sd.recordConfidencesLocked([][]Classification{{{Label: "Big Dog", Confidence: 0.7}, {Label: "CAT", Confidence: 0.4}}})
+	}
+	sd.mu.Unlock()
+
+	if got := sd.RecentConfidences("dog", 0); len(got) != adaptiveMinSamples {
+		t.Fatalf("expected \"Big Dog\" recorded under dog, got %v", got)
+	}
+	if got := sd.RecentConfidences("cat", 1); len(got) != 1 || got[0] != 0.4 {
+		t.Fatalf("expected \"CAT\" recorded under cat, got %v", got)
+	}
+	if got := sd.EffectiveThreshold("dog"); got != 0.7 {
+		t.Fatalf("expected the adaptive threshold to follow the variants, got %v", got)
+	}
+	if got := sd.EffectiveThreshold("Big Dog"); got != 0.7 {
+		t.Fatalf("expected a classifier label to find its entry, got %v", got)
+	}
+}
+
+func TestRecentConfidencesIsBounded(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	for i := 0; i < recentConfidenceLimit+10; i++ {
+		sd.mu.Lock()
+		sd.recordConfidencesLocked([][]Classification{{{Label: "cat", Confidence: float64(i)}}})
+		sd.mu.Unlock()
+	}
+
+	got := sd.RecentConfidences("cat", 0)
+	if len(got) != recentConfidenceLimit || got[len(got)-1] != float64(recentConfidenceLimit+9) {
+		t.Fatalf("expected the last %d confidences, got %d ending %v", recentConfidenceLimit, len(got), got[len(got)-1])
+	}
+}
+
+func TestAdaptiveThresholdTracksShiftingConfidences(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].MinConfidence = 0.6
+	config.ClassificationUnlockList[0].Adaptive = AdaptiveThreshold{Percentile: 10, Min: 0.5, Max: 0.8}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	record := func(confidences ...float64) {
+		sd.mu.Lock()
+		defer sd.mu.Unlock()
+		for _, c := range confidences {
+			sd.recordConfidencesLocked([][]Classification{{{Label: "dog", Confidence: c}}})
+		}
+	}
+
+	if got := sd.EffectiveThreshold("dog"); got != 0.6 {
+		t.Fatalf("expected MinConfidence before enough samples, got %v", got)
+	}
+
+	// Confidences below Min are not confident detections and are ignored.
+	record(0.1, 0.2, 0.3)
+	for range adaptiveMinSamples {
+		record(0.7)
+	}
+	if got := sd.EffectiveThreshold("dog"); got != 0.7 {
+		t.Fatalf("expected threshold to settle at 0.7, got %v", got)
+	}
+
+	// A camera that now reports higher confidences pushes the threshold
+	// up, but never past Max.
+	for range recentConfidenceLimit {
+		record(0.95)
+	}
+	if got := sd.EffectiveThreshold("dog"); got != 0.8 {
+		t.Fatalf("expected threshold clamped to Max 0.8, got %v", got)
+	}
+
+	// Confidences that sink toward Min never pull it below Min.
+	for range recentConfidenceLimit {
+		record(0.5)
+	}
+	if got := sd.EffectiveThreshold("dog"); got != 0.5 {
+		t.Fatalf("expected threshold at Min 0.5, got %v", got)
+	}
+
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.55}}})
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestAdaptiveThresholdRequiresBounds(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].Adaptive = AdaptiveThreshold{Percentile: 10, Min: 0.9, Max: 0.5}
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected inverted adaptive bounds to be rejected")
+	}
+}
@@ -0,0 +1,259 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// cameraSlot pairs a camera with the classifier its frames are routed to.
+type cameraSlot struct {
+	id         string
+	camera     DeviceCamera
+	classifier ImageClassifier
+	events     <-chan DeviceCameraEvent
+}
+
+type cameraEvent struct {
+	camera string
+	event  DeviceCameraEvent
+}
+
+// WithCamera adds a camera identified by id to every polling cycle. Its
+// frames go to classifier, or to the default classifier when nil, and its
+// detections are merged with those of the other cameras. Cameras that push
+// frames are only honoured as the primary camera.
+func WithCamera(id string, camera DeviceCamera, classifier ImageClassifier) Option {
+	return func(sd *SmartDoor) {
+		for i, slot := range sd.cameras {
+			if slot.id == id {
+				sd.cameras[i].camera = camera
+				sd.cameras[i].classifier = classifier
+				return
+			}
+		}
+		sd.cameras = append(sd.cameras, cameraSlot{id: id, camera: camera, classifier: classifier})
+	}
+}
+
+// WithCameraClassifier assigns classifier to the camera identified by id,
+// including the primary camera "cam0".
+func WithCameraClassifier(id string, classifier ImageClassifier) Option {
+	return func(sd *SmartDoor) {
+		for i, slot := range sd.cameras {
+			if slot.id == id {
+				sd.cameras[i].classifier = classifier
+			}
+		}
+	}
+}
+
+// forwardCameraEvents relays slot's events to the main loop. A closed
+// subscription marks the camera disconnected and is retried every
+// resubscribeDelay.
+func (sd *SmartDoor) forwardCameraEvents(slot cameraSlot) func(context.Context) {
+	return func(ctx context.Context) {
+		events := slot.events
+		forward := func(event DeviceCameraEvent) bool {
+			select {
+			case sd.cameraEvents <- cameraEvent{camera: slot.id, event: event}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if ok {
+					if !forward(event) {
+						return
+					}
+					continue
+				}
+				sd.logger.Error(fmt.Sprintf("camera %s event subscription closed", slot.id))
+				if !forward(CameraEventDisconnected) || !sd.sleep(ctx, resubscribeDelay) {
+					return
+				}
+				events = slot.camera.Subscribe()
+				sd.countResubscribe()
+			}
+		}
+	}
+}
+
+// mergeResults combines per-camera results into a single cycle. Any camera
+// with classifications makes the cycle decided; otherwise an error on any
+// camera wins over no signal. A decided cycle keeps the other cameras'
+// errors in Err, for settleMerged.
+func mergeResults(results []CycleResult) CycleResult {
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	merged := CycleResult{Outcome: OutcomeNoSignal}
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+		merged.Frames = append(merged.Frames, r.Frames...)
+		merged.Classifications = append(merged.Classifications, r.Classifications...)
+		merged.Cameras = append(merged.Cameras, r.Cameras...)
+		switch {
+		case r.Outcome == OutcomeDecided:
+			merged.Outcome = OutcomeDecided
+		case r.Outcome == OutcomeError && merged.Outcome == OutcomeNoSignal:
+			merged.Outcome = OutcomeError
+		}
+	}
+	merged.Err = errors.Join(errs...)
+	return merged
+}
+
+// CameraAggregator combines every camera's classifications for a cycle,
+// keyed by camera ID, into a single detection.
+type CameraAggregator func(perCamera map[string][]Classification) Detection
+
+// WithCameraAggregator replaces Config.MultiCameraPolicy with aggregate,
+// for setups the built-in policies do not cover. It sees every frame's
+// classifications, unfiltered by MinConfidence.
+func WithCameraAggregator(aggregate CameraAggregator) Option {
+	return func(sd *SmartDoor) {
+		sd.aggregate = aggregate
+	}
+}
+
+// detect turns a merged batch into a detection under the CameraAggregator
+// or Config.MultiCameraPolicy. The trigger's Frame indexes the merged
+// batch.
+func (sd *SmartDoor) detect(batch CycleResult) (Detection, *Trigger) {
+	if sd.aggregate != nil {
+		return sd.aggregateDetection(batch)
+	}
+	if len(sd.cameras) < 2 || sd.currentConfig().MultiCameraPolicy != MultiCameraMajority {
+		return sd.toDetection(batch.Classifications)
+	}
+
+	votes := make(map[Detection]int)
+	triggers := make(map[Detection]*Trigger)
+	for _, group := range groupByCamera(batch) {
+		detection, trigger := sd.toDetection(batch.Classifications[group.start:group.end])
+		if detection == DetectionHold {
+			trigger.Frame += group.start
+			return DetectionHold, trigger
+		}
+		votes[detection]++
+		if trigger != nil && triggers[detection] == nil {
+			trigger.Frame += group.start
+			triggers[detection] = trigger
+		}
+	}
+	for _, detection := range []Detection{DetectionCat, DetectionDog, DetectionUnknown} {
+		if votes[detection]*2 > len(sd.cameras) {
+			return detection, triggers[detection]
+		}
+	}
+	return DetectionNone, nil
+}
+
+func (sd *SmartDoor) aggregateDetection(batch CycleResult) (Detection, *Trigger) {
+	perCamera := make(map[string][]Classification)
+	for i, frame := range batch.Classifications {
+		camera := batch.cameraOf(i)
+		perCamera[camera] = append(perCamera[camera], frame...)
+	}
+	detection := sd.aggregate(perCamera)
+
+	config := sd.currentConfig()
+	var list []ClassificationConfig
+	switch detection {
+	case DetectionDog:
+		list = config.ClassificationUnlockList
+	case DetectionCat:
+		list = config.ClassificationLockList
+	case DetectionHold:
+		list = config.IgnoreList
+	default:
+		return detection, nil
+	}
+	// The trigger is the best match the lists would have picked; an
+	// aggregator may decide on classifications no list matches.
+	if trigger := findMatch(batch.Classifications, sd.adaptList(list), 0); trigger != nil {
+		return detection, trigger
+	}
+	return detection, &Trigger{Classification: Classification{Label: detection.String()}}
+}
+
+type frameRange struct {
+	start, end int
+}
+
+// groupByCamera splits a merged batch into the contiguous frame ranges
+// each camera contributed.
+func groupByCamera(batch CycleResult) []frameRange {
+	var groups []frameRange
+	for i := range batch.Classifications {
+		if i == 0 || batch.cameraOf(i) != batch.cameraOf(i-1) {
+			groups = append(groups, frameRange{start: i})
+		}
+		groups[len(groups)-1].end = i + 1
+	}
+	return groups
+}
+
+func repeatCameraID(id string, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = id
+	}
+	return ids
+}
+
+func (c *Connectivity) initCamera(id string) {
+	if c.Cameras == nil {
+		c.Cameras = make(map[string]DeviceConnectivity)
+	}
+	c.Cameras[id] = DeviceConnectivity{}
+}
+
+// setCamera records an event for one camera and recomputes the aggregate
+// Camera state, reporting whether the camera's state changed.
+func (c *Connectivity) setCamera(id string, connected bool, now time.Time) bool {
+	camera := c.Cameras[id]
+	if !camera.set(connected, now) {
+		return false
+	}
+	if c.Cameras == nil {
+		c.Cameras = make(map[string]DeviceConnectivity)
+	}
+	c.Cameras[id] = camera
+
+	all := true
+	for _, camera := range c.Cameras {
+		all = all && camera.Connected
+	}
+	c.Camera.set(all, now)
+	return true
+}
+
+func (c Connectivity) clone() Connectivity {
+	if c.Cameras != nil {
+		cameras := make(map[string]DeviceConnectivity, len(c.Cameras))
+		for id, camera := range c.Cameras {
+			cameras[id] = camera
+		}
+		c.Cameras = cameras
+	}
+	if c.Doors != nil {
+		doors := make(map[string]DeviceConnectivity, len(c.Doors))
+		for id, door := range c.Doors {
+			doors[id] = door
+		}
+		c.Doors = doors
+	}
+	return c
+}
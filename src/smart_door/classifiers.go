@@ -0,0 +1,47 @@
+package smartdoor
+
+import "context"
+
+// ClassifierRoute sends frames to Classifier while Schedule contains the
+// current time.
+type ClassifierRoute struct {
+	Schedule   Schedule
+	Classifier ImageClassifier
+}
+
+// TimeRoutedClassifier is an ImageClassifier that picks the model by time
+// of day, such as an IR model overnight and a daylight model otherwise.
+// The first route whose schedule contains the current time wins; outside
+// every schedule frames go to the default classifier.
+type TimeRoutedClassifier struct {
+	clock    Clock
+	fallback ImageClassifier
+	routes   []ClassifierRoute
+}
+
+// NewTimeRoutedClassifier routes by clock, or the wall clock when nil.
+// Pass the SmartDoor's clock so routing follows the same time as profiles.
+func NewTimeRoutedClassifier(clock Clock, fallback ImageClassifier, routes ...ClassifierRoute) *TimeRoutedClassifier {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &TimeRoutedClassifier{
+		clock:    clock,
+		fallback: fallback,
+		routes:   append([]ClassifierRoute(nil), routes...),
+	}
+}
+
+func (c *TimeRoutedClassifier) ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error) {
+	return c.classifierAt().ClassifyFrames(ctx, frames)
+}
+
+func (c *TimeRoutedClassifier) classifierAt() ImageClassifier {
+	now := c.clock.Now()
+	for _, route := range c.routes {
+		if route.Schedule.Contains(now) {
+			return route.Classifier
+		}
+	}
+	return c.fallback
+}
@@ -0,0 +1,38 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeRoutedClassifierFollowsSchedule(t *testing.T) {
+	night, err := NewSchedule("UTC", "22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day, ir := &fakeClassifier{}, &fakeClassifier{}
+	clock := newFakeClock()
+	classifier := NewTimeRoutedClassifier(clock, day, ClassifierRoute{Schedule: night, Classifier: ir})
+
+	steps := []struct {
+		advance   time.Duration
+		day, ir   int
+		timeOfDay string
+	}{
+		{9*time.Hour + 59*time.Minute, 1, 0, "21:59"},
+		{time.Minute, 1, 1, "22:00"},
+		{7*time.Hour + 59*time.Minute, 1, 2, "05:59"},
+		{time.Minute, 2, 2, "06:00"},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		if _, err := classifier.ClassifyFrames(context.Background(), []Frame{{}}); err != nil {
+			t.Fatal(err)
+		}
+		if day.Calls() != step.day || ir.Calls() != step.ir {
+			t.Fatalf("%s: expected day %d and IR %d calls, got %d and %d",
+				step.timeOfDay, step.day, step.ir, day.Calls(), ir.Calls())
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package smartdoor
+
+import (
+	"math"
+	"time"
+)
+
+// confidenceTrack is the latest confidence seen for an unlock-list label,
+// decayed through the cycles since that could not observe it.
+type confidenceTrack struct {
+	value float64
+	at    time.Time
+}
+
+// trackConfidences records the peak confidence of every unlock-list label
+// in a decided batch, matched as matchesLabel does; a label missing from
+// the batch drops to zero.
+func (sd *SmartDoor) trackConfidences(classifications [][]Classification, now time.Time) {
+	if sd.confidences == nil {
+		sd.confidences = make(map[string]confidenceTrack)
+	}
+	for _, entry := range sd.currentConfig().ClassificationUnlockList {
+		peak := 0.0
+		for _, frame := range classifications {
+			for _, c := range frame {
+				if labelMatches(c.Label, entry.Label) {
+					peak = max(peak, c.Confidence)
+				}
+			}
+		}
+		sd.confidences[entry.Label] = confidenceTrack{value: peak, at: now}
+	}
+}
+
+// decayConfidences halves every tracked confidence each
+// Config.ConfidenceDecayHalfLife since it was last updated. Zero leaves
+// them as last seen.
+func (sd *SmartDoor) decayConfidences(now time.Time) {
+	halfLife := sd.currentConfig().ConfidenceDecayHalfLife
+	if halfLife <= 0 {
+		return
+	}
+	for label, track := range sd.confidences {
+		elapsed := now.Sub(track.at)
+		if elapsed <= 0 {
+			continue
+		}
+		track.value *= math.Exp2(-float64(elapsed) / float64(halfLife))
+		track.at = now
+		sd.confidences[label] = track
+	}
+}
+
+// confidentlyPresent reports whether any unlock-list label's tracked
+// confidence still reaches the threshold the decision applies to it.
+func (sd *SmartDoor) confidentlyPresent() bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	for _, entry := range sd.effectiveConfigLocked().ClassificationUnlockList {
+		if sd.confidences[entry.Label].value >= sd.thresholdLocked(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// presenceSmoothing is the weight of the latest cycle in PresenceScore.
+const presenceSmoothing = 0.5
+
+// PresenceScore is a 0-1 "dog present" meter for a UI, updated every
+// cycle. It follows the tracked confidence of the unlock-list labels,
+// scaled per label so its MinConfidence reads 0.5, smoothed across cycles
+// and, with Config.ConfidenceDecayHalfLife, decaying through gaps. While
+// the camera is disconnected it reads as Config.DisconnectPresence says.
+func (sd *SmartDoor) PresenceScore() float64 {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.cameraDisconnectedLocked() {
+		switch sd.effectiveConfigLocked().DisconnectPresence {
+		case DisconnectPresenceZero:
+			return 0
+		case DisconnectPresenceUnknown:
+			return -1
+		}
+	}
+	return sd.presenceScore
+}
+
+// updatePresence folds the current tracked confidences into
+// PresenceScore. It leaves the score alone while the camera is
+// disconnected, but for zeroing it under DisconnectPresenceZero.
+func (sd *SmartDoor) updatePresence() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.cameraDisconnectedLocked() {
+		if sd.effectiveConfigLocked().DisconnectPresence == DisconnectPresenceZero {
+			sd.presenceScore = 0
+		}
+		return
+	}
+	raw := 0.0
+	for _, entry := range sd.effectiveConfigLocked().ClassificationUnlockList {
+		raw = max(raw, presenceOf(sd.confidences[entry.Label].value, sd.thresholdLocked(entry)))
+	}
+	sd.presenceScore += (raw - sd.presenceScore) * presenceSmoothing
+}
+
+// cameraDisconnectedLocked reports whether the camera has reported a
+// disconnection it has not recovered from. It must be called with sd.mu
+// held.
+func (sd *SmartDoor) cameraDisconnectedLocked() bool {
+	camera := sd.connectivity.Camera
+	return !camera.Connected && !camera.ChangedAt.IsZero()
+}
+
+// presenceOf maps confidence linearly onto [0, 0.5] below threshold and
+// [0.5, 1] above it.
+func presenceOf(confidence, threshold float64) float64 {
+	switch {
+	case threshold <= 0:
+		return confidence
+	case confidence < threshold:
+		return 0.5 * confidence / threshold
+	case threshold >= 1:
+		return 1
+	}
+	return 0.5 + 0.5*(confidence-threshold)/(1-threshold)
+}
@@ -0,0 +1,497 @@
+package smartdoor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	MinimalDurationUnlocking time.Duration
+	MinimalDurationLocking   time.Duration
+	MinimalRateCameraProcess time.Duration
+	CameraMode               CameraMode
+	// PollJitter, when positive, adds a random delay below it to every
+	// poll wait, so cameras sharing a network do not capture in lockstep.
+	PollJitter time.Duration
+	// MinInterFrameInterval, when positive, thins a captured batch to
+	// frames at least this far apart by Frame.CapturedAt before it is
+	// classified, so a high-FPS burst is not classified frame by frame.
+	MinInterFrameInterval time.Duration
+	// MaxFramesPerCycle, when positive, caps how many frames of a batch
+	// are classified, keeping the most recent, so a burst cannot make one
+	// ClassifyFrames call outrun CycleTimeout.
+	MaxFramesPerCycle int
+	// InputResolution, when set, is the size frames are resized to before
+	// they are classified, for models that expect a fixed input; see
+	// WithFrameResizer. Frames that cannot be decoded are passed on as
+	// captured.
+	InputResolution Resolution
+	// LabelSource, when set, is the path of a labels file whose lists
+	// replace ClassificationUnlockList, ClassificationLockList and
+	// IgnoreList whenever the config is applied; see Labels.
+	LabelSource              string
+	ClassificationUnlockList []ClassificationConfig
+	ClassificationLockList   []ClassificationConfig
+	// IgnoreList labels, such as a person holding the door, suppress any
+	// decision for the cycle they are matched in.
+	IgnoreList []ClassificationConfig
+	// CycleTimeout bounds a single capture and classify cycle, retries
+	// included. Zero leaves the cycle bounded only by the run context.
+	CycleTimeout time.Duration
+	// ClassifyRetries is how many extra ClassifyFrames attempts a cycle
+	// makes after an error before it is counted as a failure.
+	ClassifyRetries    int
+	ClassifyRetryDelay time.Duration
+	// SkipUnchangedClassifications skips the decision step when a batch is
+	// identical to the previous one, as happens with a static scene.
+	SkipUnchangedClassifications bool
+	// MaxContinuousUnlock is a hard ceiling on how long the door stays
+	// unlocked, even while the dog is still detected. Zero disables it.
+	MaxContinuousUnlock time.Duration
+	// HeartbeatInterval is how often EventHeartbeat is emitted. Zero
+	// disables heartbeats.
+	HeartbeatInterval time.Duration
+	// SummaryInterval is how often EventSummary is emitted. Zero emits
+	// one only on EmitSummary.
+	SummaryInterval time.Duration
+	// ShutdownTimeout bounds how long shutdown waits for in-flight door
+	// actions before closing the event stream anyway. Zero waits for them.
+	ShutdownTimeout time.Duration
+	// MaxIdenticalResults, when positive, trips the classifier circuit
+	// breaker after that many consecutive identical results for frames
+	// that kept changing, which is how a wedged model server looks.
+	MaxIdenticalResults int
+	// ClassifierBreakerCooldown is how long classification is skipped once
+	// the breaker trips. Zero uses defaultBreakerCooldown.
+	ClassifierBreakerCooldown time.Duration
+	// CaptureTrainingData writes sampled cycles to the TrainingSink, one
+	// in every TrainingSampleEvery cycles (every cycle when below two).
+	CaptureTrainingData bool
+	TrainingSampleEvery int
+	// VoteThreshold, when positive, replaces the any-frame match with a
+	// weighted vote: a list matches when the sum over frames of the best
+	// matching confidence, weighted by recency, reaches the threshold.
+	VoteThreshold float64
+	// VoteRecencyDecay is the weight multiplier applied per frame of age,
+	// newest frame last. Zero or one weighs all frames equally.
+	VoteRecencyDecay float64
+	// MinConfidenceMargin, when positive, ignores a lock or unlock match
+	// unless it beats every other label in its frame by at least this
+	// much, so "dog 0.55 / cat 0.52" is too ambiguous to act on.
+	MinConfidenceMargin float64
+	// UnlockOnly ignores ClassificationLockList: the door never locks
+	// actively and only relocks on absence, sparing the relay.
+	UnlockOnly bool
+	// NoLabelsPolicy decides a cycle whose classifier returned no labels at
+	// all, which holds by default since it may be a model issue, and
+	// UnmatchedLabelsPolicy one whose labels matched no list, which is
+	// DetectionNone by default.
+	NoLabelsPolicy        EmptyResultPolicy
+	UnmatchedLabelsPolicy EmptyResultPolicy
+	// UnknownActivity, when positive, makes a batch that matches no list
+	// DetectionUnknown instead of DetectionNone once some frame's
+	// confidences sum to at least this: something is there that cannot be
+	// told apart, as opposed to an empty yard. UnknownPolicy decides what
+	// the door does about it; UnmatchedLabelsPolicy no longer applies.
+	UnknownActivity float64
+	UnknownPolicy   UnknownPolicy
+	// CameraLossGrace, when positive, is how long the camera may stay
+	// disconnected before CameraLossPolicy applies, since the controller
+	// can no longer see whether the dog is still there. Normal logic
+	// resumes once the camera reconnects.
+	CameraLossGrace  time.Duration
+	CameraLossPolicy CameraLossPolicy
+	// DisconnectPresence is what PresenceScore reads while the camera is
+	// disconnected.
+	DisconnectPresence DisconnectPresencePolicy
+	// ConflictPolicy decides between lock and unlock when both lists match
+	// in the same batch.
+	ConflictPolicy ConflictPolicy
+	// CatArrivalPolicy decides what a cat seen while the dog has the door
+	// unlocked does; under CatArrivalWaitForDogToClear the lock waits at
+	// most CatArrivalTimeout.
+	CatArrivalPolicy  CatArrivalPolicy
+	CatArrivalTimeout time.Duration
+	// MultiCameraPolicy combines detections when several cameras are in
+	// use.
+	MultiCameraPolicy MultiCameraPolicy
+	// FailSafeAfterErrors, when positive, locks the door after that many
+	// consecutive error cycles, since the controller can no longer confirm
+	// the dog is there.
+	FailSafeAfterErrors int
+	// MaxConsecutiveSameAction, when positive, is how many times in a row
+	// the same action may be sent to the door before repeats are held
+	// back and reported as a likely logic bug.
+	MaxConsecutiveSameAction int
+	// LogSampleEvery logs one in every LogSampleEvery routine cycles
+	// (every cycle when below two). Cycles that decide an action or fail
+	// are always logged.
+	LogSampleEvery int
+	// PreemptCooldown is the cooldown that applies instead of
+	// MinimalDurationUnlocking when an action outranks the last one by
+	// ClassificationConfig.Priority.
+	PreemptCooldown time.Duration
+	// DoorCallTimeout bounds each Lock and Unlock call. A call that times
+	// out is abandoned and the door state becomes unknown. Zero waits for
+	// the device however long it takes.
+	DoorCallTimeout time.Duration
+	// AbsenceDebounce is how long nothing must be detected before an
+	// unlocking dog counts as gone, and RelockDelay how long after that
+	// the door relocks. Both zero relock on the first cycle without a
+	// detection.
+	AbsenceDebounce time.Duration
+	RelockDelay     time.Duration
+	// ReturnGrace holds back the relock until at least ReturnGrace after
+	// the dog counts as gone. A dog that reappears in that window picks up
+	// the unlock it left, with no relock and no new unlock sent, smoothing
+	// over a dog that hesitates halfway through.
+	ReturnGrace time.Duration
+	// GlobalMinConfidence is a floor under every label's MinConfidence,
+	// adaptive thresholds and PresenceConfidence included, so a label
+	// configured too low is still gated by it.
+	GlobalMinConfidence float64
+	// PresenceConfidence, when positive, is a looser test for the dog
+	// still being there while unlocked: a batch with any unlock-list label
+	// at this confidence in a single frame is not absence, even though it
+	// would not trigger an unlock. Zero makes presence the same as the
+	// unlock trigger, so the door is quick to open and, set below
+	// MinConfidence, slow to relock.
+	PresenceConfidence float64
+	// ConfidenceDecayHalfLife, when positive, halves the last seen
+	// unlock-list confidence every half-life while cycles are held by
+	// errors or missing frames. Once it falls below MinConfidence the gap
+	// counts as absence and the relock timers run. Zero holds the door
+	// as last decided through any gap.
+	ConfidenceDecayHalfLife time.Duration
+	// ConnectivityStabilization defers unlocking until the door has been
+	// connected this long, so a device still coming up after a
+	// (re)connection is not commanded. Locks are never deferred.
+	ConnectivityStabilization time.Duration
+	// ManualGracePeriod is how long after an unlock by hand, seen through
+	// a DoorStateReader, the controller waits before the relock timers
+	// start, so it does not fight the person at the door.
+	ManualGracePeriod time.Duration
+	// ReassertInterval, when positive, re-sends the last decided action
+	// that often so the device cannot drift from it unnoticed.
+	ReassertInterval time.Duration
+	// StartArmed makes the SmartDoor start armed, moving the door as it
+	// decides. Without it the SmartDoor starts disarmed, watching but not
+	// moving the door until Arm is called, so a zero Config never acts
+	// unasked; DefaultDogDoorConfig and StrictSecurityConfig set it.
+	StartArmed bool
+	// ConfirmDelay, when positive, announces every action as an
+	// EventPendingAction this long before applying it, so a person can
+	// call CancelPending first.
+	ConfirmDelay time.Duration
+	// ConfirmWithExtraCapture makes a detection's action wait for one
+	// extra capture and classification, taken at once, to come out the
+	// same, trading a little latency for fewer false actions.
+	ConfirmWithExtraCapture bool
+	// QuietHours holds back non-critical events from Notifications while
+	// it is in effect. Unlike a night-lock profile it leaves the door's
+	// behaviour alone: detections are acted on and critical events are
+	// delivered as usual.
+	QuietHours Schedule
+	// OrderedDeviceEvents makes Run take device events that are ready at
+	// the same time in a fixed priority rather than select's random pick:
+	// shutdown first, then door events, then camera events, then a door
+	// resubscription.
+	OrderedDeviceEvents bool
+	// AllowEventInjection enables InjectCameraEvent and InjectDoorEvent,
+	// for failure drills against a running deployment.
+	AllowEventInjection bool
+}
+
+type CameraMode int
+
+const (
+	// CameraModePoll captures frames on a ticker every
+	// MinimalRateCameraProcess.
+	CameraModePoll CameraMode = iota
+	// CameraModePush classifies frames as the camera delivers them, at most
+	// once per MinimalRateCameraProcess. The camera must implement
+	// FramePusher.
+	CameraModePush
+)
+
+type ConflictPolicy int
+
+const (
+	// ConflictPreferLock locks when a lock-list and an unlock-list label are
+	// both matched, keeping a cat out at the cost of making the dog wait.
+	ConflictPreferLock ConflictPolicy = iota
+	ConflictPreferUnlock
+)
+
+type CatArrivalPolicy int
+
+const (
+	// CatArrivalImmediateLock locks as soon as the cat is seen, which is
+	// safest but may trap the dog on the wrong side.
+	CatArrivalImmediateLock CatArrivalPolicy = iota
+	// CatArrivalWaitForDogToClear locks only once the dog is out of view,
+	// or CatArrivalTimeout after the cat appeared if it never is.
+	CatArrivalWaitForDogToClear
+)
+
+type EmptyResultPolicy int
+
+const (
+	// EmptyResultDefault holds on no labels and is DetectionNone on
+	// unmatched labels.
+	EmptyResultDefault EmptyResultPolicy = iota
+	// EmptyResultNone treats the cycle as DetectionNone, so it counts
+	// towards the relock.
+	EmptyResultNone
+	// EmptyResultHold holds the current state, as an error cycle does.
+	EmptyResultHold
+)
+
+type UnknownPolicy int
+
+const (
+	// UnknownHold keeps the door as it is while DetectionUnknown lasts.
+	UnknownHold UnknownPolicy = iota
+	// UnknownLock locks on DetectionUnknown, as a fail-safe, regardless of
+	// cooldown.
+	UnknownLock
+)
+
+type CameraLossPolicy int
+
+const (
+	// CameraLossLock locks, as a fail-safe, regardless of cooldown.
+	CameraLossLock CameraLossPolicy = iota
+	// CameraLossUnlock unlocks, so a dog outside is not shut out while
+	// nothing can see it.
+	CameraLossUnlock
+	// CameraLossHold keeps the door as it is.
+	CameraLossHold
+)
+
+type DisconnectPresencePolicy int
+
+const (
+	// DisconnectPresenceHold keeps the score from before the camera was
+	// lost.
+	DisconnectPresenceHold DisconnectPresencePolicy = iota
+	// DisconnectPresenceZero drops the score to zero, rising again from
+	// there once the camera is back.
+	DisconnectPresenceZero
+	// DisconnectPresenceUnknown reads -1 until the camera is back.
+	DisconnectPresenceUnknown
+)
+
+type MultiCameraPolicy int
+
+const (
+	// MultiCameraAny pools every camera's frames, so any camera can
+	// trigger and ConflictPolicy settles a dog on one camera against a cat
+	// on another; with the default ConflictPreferLock any cat locks.
+	MultiCameraAny MultiCameraPolicy = iota
+	// MultiCameraMajority decides per camera and acts only on a detection
+	// reported by more than half of all cameras. An ignore-list match on
+	// any camera still holds.
+	MultiCameraMajority
+)
+
+type ClassificationConfig struct {
+	Label         string
+	MinConfidence float64
+	// Priority ranks the action a match triggers. An action may cut short
+	// the cooldown of one with a lower priority, waiting only
+	// Config.PreemptCooldown. List the same label again with a higher
+	// MinConfidence and Priority to let only confident matches preempt.
+	Priority int
+	// HoldDuration, when positive, reverses the action a match triggers
+	// once it has held for this long, whatever the camera sees then. A
+	// new action for the label restarts the hold.
+	HoldDuration time.Duration
+	// MinPresentDuration, when positive, is how long the label must be
+	// detected without a break before its action fires, so an animal
+	// darting past does not. Unlike VoteThreshold it counts time, not
+	// frames.
+	MinPresentDuration time.Duration
+	// Adaptive, when its Percentile is set, replaces MinConfidence with a
+	// threshold learned from the label's recent confidences.
+	Adaptive AdaptiveThreshold
+}
+
+// AdaptiveThreshold sets a label's threshold at the Percentile (0-100] of
+// its recent confident detections, those at or above Min, clamped to
+// [Min, Max] so it never drifts unsafe. Until adaptiveMinSamples have been
+// seen MinConfidence applies.
+type AdaptiveThreshold struct {
+	Percentile float64
+	Min, Max   float64
+}
+
+func (c Config) clone() Config {
+	c.ClassificationUnlockList = append([]ClassificationConfig(nil), c.ClassificationUnlockList...)
+	c.ClassificationLockList = append([]ClassificationConfig(nil), c.ClassificationLockList...)
+	c.IgnoreList = append([]ClassificationConfig(nil), c.IgnoreList...)
+	c.QuietHours.Windows = append([]TimeWindow(nil), c.QuietHours.Windows...)
+	return c
+}
+
+// Validate reports every problem with the config at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.MinimalRateCameraProcess > 0, "MinimalRateCameraProcess must be positive")
+	for name, d := range map[string]time.Duration{
+		"MinimalDurationUnlocking":  c.MinimalDurationUnlocking,
+		"MinimalDurationLocking":    c.MinimalDurationLocking,
+		"CycleTimeout":              c.CycleTimeout,
+		"ClassifyRetryDelay":        c.ClassifyRetryDelay,
+		"MaxContinuousUnlock":       c.MaxContinuousUnlock,
+		"HeartbeatInterval":         c.HeartbeatInterval,
+		"SummaryInterval":           c.SummaryInterval,
+		"ShutdownTimeout":           c.ShutdownTimeout,
+		"ClassifierBreakerCooldown": c.ClassifierBreakerCooldown,
+		"PreemptCooldown":           c.PreemptCooldown,
+		"DoorCallTimeout":           c.DoorCallTimeout,
+		"AbsenceDebounce":           c.AbsenceDebounce,
+		"RelockDelay":               c.RelockDelay,
+		"ReturnGrace":               c.ReturnGrace,
+		"ReassertInterval":          c.ReassertInterval,
+		"ConfirmDelay":              c.ConfirmDelay,
+		"CameraLossGrace":           c.CameraLossGrace,
+		"ConfidenceDecayHalfLife":   c.ConfidenceDecayHalfLife,
+		"PollJitter":                c.PollJitter,
+		"ManualGracePeriod":         c.ManualGracePeriod,
+		"MinInterFrameInterval":     c.MinInterFrameInterval,
+		"CatArrivalTimeout":         c.CatArrivalTimeout,
+		"ConnectivityStabilization": c.ConnectivityStabilization,
+	} {
+		check(d >= 0, "%s must not be negative", name)
+	}
+	check(c.ClassifyRetries >= 0, "ClassifyRetries must not be negative")
+	check(c.MaxIdenticalResults >= 0, "MaxIdenticalResults must not be negative")
+	check(c.MaxFramesPerCycle >= 0, "MaxFramesPerCycle must not be negative")
+	check(c.InputResolution == Resolution{} || (c.InputResolution.Width > 0 && c.InputResolution.Height > 0),
+		"InputResolution must be zero or positive in both dimensions")
+	check(c.FailSafeAfterErrors >= 0, "FailSafeAfterErrors must not be negative")
+	check(c.LogSampleEvery >= 0, "LogSampleEvery must not be negative")
+	check(c.VoteThreshold >= 0, "VoteThreshold must not be negative")
+	check(c.UnknownActivity >= 0, "UnknownActivity must not be negative")
+	check(c.MaxConsecutiveSameAction >= 0, "MaxConsecutiveSameAction must not be negative")
+	check(c.CatArrivalPolicy != CatArrivalWaitForDogToClear || c.CatArrivalTimeout > 0,
+		"CatArrivalTimeout must be positive with CatArrivalWaitForDogToClear")
+	check(c.MinConfidenceMargin >= 0 && c.MinConfidenceMargin <= 1, "MinConfidenceMargin must be within [0, 1]")
+	check(c.PresenceConfidence >= 0 && c.PresenceConfidence <= 1, "PresenceConfidence must be within [0, 1]")
+	check(c.GlobalMinConfidence >= 0 && c.GlobalMinConfidence <= 1, "GlobalMinConfidence must be within [0, 1]")
+	check(c.VoteRecencyDecay >= 0 && c.VoteRecencyDecay <= 1, "VoteRecencyDecay must be within [0, 1]")
+	check(c.CameraMode == CameraModePoll || c.CameraMode == CameraModePush, "unknown CameraMode %d", c.CameraMode)
+	check(c.ConflictPolicy == ConflictPreferLock || c.ConflictPolicy == ConflictPreferUnlock,
+		"unknown ConflictPolicy %d", c.ConflictPolicy)
+	for name, policy := range map[string]EmptyResultPolicy{
+		"NoLabelsPolicy":        c.NoLabelsPolicy,
+		"UnmatchedLabelsPolicy": c.UnmatchedLabelsPolicy,
+	} {
+		check(policy >= EmptyResultDefault && policy <= EmptyResultHold, "unknown %s %d", name, policy)
+	}
+	check(c.CameraLossPolicy >= CameraLossLock && c.CameraLossPolicy <= CameraLossHold,
+		"unknown CameraLossPolicy %d", c.CameraLossPolicy)
+	check(c.DisconnectPresence >= DisconnectPresenceHold && c.DisconnectPresence <= DisconnectPresenceUnknown,
+		"unknown DisconnectPresence %d", c.DisconnectPresence)
+	check(c.MultiCameraPolicy == MultiCameraAny || c.MultiCameraPolicy == MultiCameraMajority,
+		"unknown MultiCameraPolicy %d", c.MultiCameraPolicy)
+	check(len(c.ClassificationUnlockList) > 0, "ClassificationUnlockList must not be empty")
+
+	lists := map[string][]ClassificationConfig{
+		"ClassificationUnlockList": c.ClassificationUnlockList,
+		"ClassificationLockList":   c.ClassificationLockList,
+		"IgnoreList":               c.IgnoreList,
+	}
+	for name, list := range lists {
+		for i, entry := range list {
+			check(strings.TrimSpace(entry.Label) != "", "%s[%d]: label must not be empty", name, i)
+			check(entry.MinConfidence >= 0 && entry.MinConfidence <= 1,
+				"%s[%d]: MinConfidence %v must be within [0, 1]", name, i, entry.MinConfidence)
+			check(entry.Priority >= 0, "%s[%d]: Priority must not be negative", name, i)
+			check(entry.HoldDuration >= 0, "%s[%d]: HoldDuration must not be negative", name, i)
+			check(entry.MinPresentDuration >= 0, "%s[%d]: MinPresentDuration must not be negative", name, i)
+			if a := entry.Adaptive; a.Percentile != 0 {
+				check(a.Percentile > 0 && a.Percentile <= 100, "%s[%d]: Adaptive.Percentile must be within (0, 100]", name, i)
+				check(a.Min >= 0 && a.Min <= a.Max && a.Max <= 1, "%s[%d]: Adaptive bounds must satisfy 0 <= Min <= Max <= 1", name, i)
+			}
+		}
+	}
+	for _, unlock := range c.ClassificationUnlockList {
+		for _, lock := range c.ClassificationLockList {
+			check(!strings.EqualFold(unlock.Label, lock.Label),
+				"label %q is in both the unlock and lock lists", unlock.Label)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DefaultDogDoorConfig is a starting point for a single dog door:
+//   - a frame every second, with one retry for a busy classifier;
+//   - unlock for "dog" at 0.6, lock for "cat" at 0.5, hold while a
+//     "person" is in the doorway;
+//   - three seconds between actions, except that a cat locks at once, five
+//     seconds for the door to answer, and never more than five minutes
+//     unlocked in one go;
+//   - cat wins over dog, and five failed cycles in a row lock the door.
+func DefaultDogDoorConfig() Config {
+	return Config{
+		MinimalDurationUnlocking: 3 * time.Second,
+		MinimalDurationLocking:   3 * time.Second,
+		MinimalRateCameraProcess: time.Second,
+		ClassificationUnlockList: []ClassificationConfig{{Label: "dog", MinConfidence: 0.6}},
+		ClassificationLockList:   []ClassificationConfig{{Label: "cat", MinConfidence: 0.5}},
+		IgnoreList:               []ClassificationConfig{{Label: "person", MinConfidence: 0.7}},
+		CycleTimeout:             5 * time.Second,
+		ClassifyRetries:          1,
+		ClassifyRetryDelay:       200 * time.Millisecond,
+		MaxContinuousUnlock:      5 * time.Minute,
+		HeartbeatInterval:        time.Minute,
+		ShutdownTimeout:          10 * time.Second,
+		DoorCallTimeout:          5 * time.Second,
+		ConflictPolicy:           ConflictPreferLock,
+		FailSafeAfterErrors:      5,
+		StartArmed:               true,
+	}
+}
+
+// StrictSecurityConfig trades responsiveness for fewer false unlocks:
+//   - "dog" needs 0.8 confidence and a weighted vote of 1.5, so at least
+//     two confident recent frames, while "cat" locks from 0.3;
+//   - five seconds between actions, except that a cat locks at once, and
+//     at most one minute unlocked;
+//   - a wedged classifier trips the breaker after ten identical results,
+//     and two failed cycles in a row lock the door.
+func StrictSecurityConfig() Config {
+	return Config{
+		MinimalDurationUnlocking:  5 * time.Second,
+		MinimalDurationLocking:    5 * time.Second,
+		MinimalRateCameraProcess:  time.Second,
+		ClassificationUnlockList:  []ClassificationConfig{{Label: "dog", MinConfidence: 0.8}},
+		ClassificationLockList:    []ClassificationConfig{{Label: "cat", MinConfidence: 0.3}},
+		IgnoreList:                []ClassificationConfig{{Label: "person", MinConfidence: 0.5}},
+		CycleTimeout:              3 * time.Second,
+		ClassifyRetries:           1,
+		ClassifyRetryDelay:        200 * time.Millisecond,
+		MaxContinuousUnlock:       time.Minute,
+		HeartbeatInterval:         30 * time.Second,
+		ShutdownTimeout:           10 * time.Second,
+		DoorCallTimeout:           5 * time.Second,
+		MaxIdenticalResults:       10,
+		ClassifierBreakerCooldown: time.Minute,
+		VoteThreshold:             1.5,
+		VoteRecencyDecay:          0.9,
+		ConflictPolicy:            ConflictPreferLock,
+		FailSafeAfterErrors:       2,
+		StartArmed:                true,
+	}
+}
@@ -0,0 +1,960 @@
+package smartdoor
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPresetsValidate(t *testing.T) {
+	for name, config := range map[string]Config{
+		"DefaultDogDoorConfig": DefaultDogDoorConfig(),
+		"StrictSecurityConfig": StrictSecurityConfig(),
+	} {
+		if err := config.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := DefaultDogDoorConfig()
+	config.MinimalRateCameraProcess = 0
+	config.ClassifyRetries = -1
+	config.ClassificationLockList = append(config.ClassificationLockList,
+		ClassificationConfig{Label: "Dog", MinConfidence: 1.5})
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"MinimalRateCameraProcess", "ClassifyRetries", "MinConfidence 1.5", `"dog" is in both`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestDefaultPresetSequence(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(DefaultDogDoorConfig(), &fakeClassifier{})
+
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.7}}})
+	expectActions(t, sd, ActionUnlock)
+
+	// A cat locks at once, but the dog's next unlock waits for the cooldown.
+	clock.Advance(time.Second)
+	sd.handleClassifications([][]Classification{{{Label: "cat", Confidence: 0.6}}})
+	expectActions(t, sd, ActionLock)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.7}}})
+	expectActions(t, sd)
+
+	clock.Advance(3 * time.Second)
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.7}}})
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestStrictPresetSequence(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(StrictSecurityConfig(), &fakeClassifier{})
+	dog := []Classification{{Label: "dog", Confidence: 0.85}}
+
+	sd.handleClassifications([][]Classification{dog})
+	expectActions(t, sd)
+
+	sd.handleClassifications([][]Classification{dog, dog})
+	expectActions(t, sd, ActionUnlock)
+
+	cat := []Classification{{Label: "cat", Confidence: 0.9}}
+	sd.handleClassifications([][]Classification{cat, cat})
+	expectActions(t, sd, ActionLock)
+}
+
+func TestConflictPolicy(t *testing.T) {
+	both := [][]Classification{{{Label: "dog", Confidence: 0.9}, {Label: "cat", Confidence: 0.9}}}
+
+	for policy, want := range map[ConflictPolicy]Detection{
+		ConflictPreferLock:   DetectionCat,
+		ConflictPreferUnlock: DetectionDog,
+	} {
+		config := dogDoorConfig()
+		config.ConflictPolicy = policy
+		sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+		if got, _ := sd.toDetection(both); got != want {
+			t.Fatalf("policy %v: expected %v, got %v", policy, want, got)
+		}
+	}
+}
+
+func TestFailSafeLocksAfterConsecutiveErrors(t *testing.T) {
+	config := dogDoorConfig()
+	config.FailSafeAfterErrors = 3
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+
+	sd.handleCycle(context.Background(), CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided})
+	expectActions(t, sd, ActionUnlock)
+
+	for i := 0; i < 2; i++ {
+		sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	}
+	expectActions(t, sd)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	expectActions(t, sd, ActionLock)
+
+	var failSafe bool
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventFailSafe {
+			failSafe = true
+		}
+	}
+	if !failSafe {
+		t.Fatal("expected a fail-safe event")
+	}
+
+	sd.handleCycle(context.Background(), CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided})
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestRecoveryAfterFailSafeReportsOutageDuration(t *testing.T) {
+	config := dogDoorConfig()
+	config.FailSafeAfterErrors = 2
+	var called []Recovery
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{}, WithRecoveryCallback(func(r Recovery) {
+		called = append(called, r)
+	}))
+	events := sd.Events()
+
+	// A streak too short to engage the fail-safe recovers silently.
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	sd.handleClassifications(dogBatch())
+
+	start := clock.Now()
+	for i := 0; i < 3; i++ {
+		sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+		clock.Advance(10 * time.Second)
+	}
+	sd.handleClassifications(dogBatch())
+	sd.handleClassifications(dogBatch())
+
+	var recovered []Event
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventClassifierRecovered {
+			recovered = append(recovered, e)
+		}
+	}
+	if len(recovered) != 1 {
+		t.Fatalf("expected one recovery event, got %d", len(recovered))
+	}
+	want := Recovery{Since: start, Duration: 30 * time.Second}
+	if got := *recovered[0].Recovery; got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if len(called) != 1 || called[0] != want {
+		t.Fatalf("expected the callback with %+v, got %+v", want, called)
+	}
+	if got := sd.Stats().ClassifierRecoveries; got != 1 {
+		t.Fatalf("expected one recovery counted, got %d", got)
+	}
+}
+
+func cooldownConfig() Config {
+	config := DefaultDogDoorConfig()
+	config.IgnoreList = nil
+	config.MinimalDurationUnlocking = 10 * time.Second
+	return config
+}
+
+func TestCooldownDoesNotBlockFirstActionAfterStart(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(cooldownConfig(), &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestCooldownDoesNotBlockFirstActionAfterReload(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(cooldownConfig(), &fakeClassifier{})
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	sd.handleClassifications(cat)
+	expectActions(t, sd, ActionLock)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(time.Second)
+	if err := sd.UpdateConfig(cooldownConfig()); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd)
+}
+
+func TestUpdateConfigRejectsInvalidConfig(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(cooldownConfig(), &fakeClassifier{})
+
+	if err := sd.UpdateConfig(Config{}); err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	if sd.currentConfig().MinimalDurationUnlocking != 10*time.Second {
+		t.Fatal("rejected config should not be applied")
+	}
+}
+
+func TestConfidentDogPreemptsCatLockCooldown(t *testing.T) {
+	config := cooldownConfig()
+	config.ClassificationUnlockList = append(config.ClassificationUnlockList,
+		ClassificationConfig{Label: "dog", MinConfidence: 0.9, Priority: 1})
+	config.PreemptCooldown = 2 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	dog := func(confidence float64) [][]Classification {
+		return [][]Classification{{{Label: "dog", Confidence: confidence}}}
+	}
+
+	sd.handleClassifications([][]Classification{{{Label: "cat", Confidence: 0.9}}})
+	expectActions(t, sd, ActionLock)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(dog(0.95))
+	expectActions(t, sd)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(dog(0.7))
+	expectActions(t, sd)
+
+	d := sd.handleClassifications(dog(0.95))
+	expectActions(t, sd, ActionUnlock)
+	if d.Trigger == nil || d.Trigger.Priority != 1 {
+		t.Fatalf("expected the priority match to trigger, got %+v", d.Trigger)
+	}
+}
+
+func TestAbsenceDebounceThenRelockDelay(t *testing.T) {
+	config := dogDoorConfig()
+	config.AbsenceDebounce = 3 * time.Second
+	config.RelockDelay = 10 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	// A dog back within the debounce restarts the absence.
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	clock.Advance(2 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(time.Second)
+	if d := sd.handleClassifications(noneBatch()); d.Detection != DetectionDog {
+		t.Fatalf("expected the dog to still count as present, got %v", d.Detection)
+	}
+	clock.Advance(3 * time.Second)
+	if d := sd.handleClassifications(noneBatch()); d.Detection != DetectionNone || d.Action != ActionNone {
+		t.Fatalf("expected the dog gone but no relock yet, got %+v", d)
+	}
+	clock.Advance(9 * time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd, ActionLock)
+
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd)
+}
+
+func TestSafetyLockBypassesCooldown(t *testing.T) {
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	config := dogDoorConfig()
+	config.MinimalDurationUnlocking = time.Minute
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	clock.Advance(time.Second)
+	if d := sd.handleClassifications(cat); d.Action != ActionLock {
+		t.Fatalf("expected the cat to lock during the cooldown, got %+v", d)
+	}
+	expectActions(t, sd, ActionLock)
+
+	// The unlock after it still waits for the cooldown.
+	clock.Advance(time.Second)
+	if d := sd.handleClassifications(dogBatch()); d.Reason != ReasonOnCooldown {
+		t.Fatalf("expected the unlock to wait for the cooldown, got %+v", d)
+	}
+	expectActions(t, sd)
+}
+
+func TestReturnGraceCoalescesLeaveAndReturn(t *testing.T) {
+	config := dogDoorConfig()
+	config.ReturnGrace = 5 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(time.Second)
+	if d := sd.handleClassifications(noneBatch()); d.Detection != DetectionNone || d.Action != ActionNone {
+		t.Fatalf("expected the dog gone but no relock yet, got %+v", d)
+	}
+	clock.Advance(3 * time.Second)
+	if d := sd.handleClassifications(dogBatch()); d.Detection != DetectionDog || d.Action != ActionNone {
+		t.Fatalf("expected the returning dog to keep the unlock, got %+v", d)
+	}
+	expectActions(t, sd)
+	if got := sd.Stats().ReturnsCoalesced; got != 1 {
+		t.Fatalf("expected 1 coalesced return, got %d", got)
+	}
+
+	// Leaving for longer than the grace relocks as usual.
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	clock.Advance(4 * time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd)
+	clock.Advance(time.Second)
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd, ActionLock)
+}
+
+func TestUnlockOnlyIgnoresLockList(t *testing.T) {
+	config := dogDoorConfig()
+	config.UnlockOnly = true
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	if d := sd.handleClassifications(cat); d.Detection != DetectionNone || d.Action != ActionNone {
+		t.Fatalf("expected the cat to be ignored, got %+v", d)
+	}
+	expectActions(t, sd)
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.9}, {Label: "cat", Confidence: 0.9}}})
+	expectActions(t, sd)
+
+	// The cat alone counts as absence, so the door relocks.
+	sd.handleClassifications(cat)
+	expectActions(t, sd, ActionLock)
+}
+
+func TestHoldDurationRevertsOnSchedule(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].HoldDuration = 15 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(14 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	// The dog is still there, but the hold is up.
+	clock.Advance(time.Second)
+	d := sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionLock)
+	if d.Action != ActionLock {
+		t.Fatalf("expected the hold to relock, got %+v", d)
+	}
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	// Leaving and coming back triggers a fresh hold.
+	sd.handleClassifications(noneBatch())
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	clock.Advance(10 * time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeNoSignal})
+	expectActions(t, sd)
+	clock.Advance(5 * time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeNoSignal})
+	expectActions(t, sd, ActionLock)
+}
+
+func TestRepeatedNoneCyclesReachScheduledRelock(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		config := dogDoorConfig()
+		config.SkipUnchangedClassifications = skip
+		config.AbsenceDebounce = 2 * time.Second
+		config.RelockDelay = 3 * time.Second
+		sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+		sd.handleClassifications(dogBatch())
+		expectActions(t, sd, ActionUnlock)
+
+		var locked int
+		for i := 0; i < 5; i++ {
+			sd.handleClassifications(noneBatch())
+			locked += len(sd.actions.Drain())
+			clock.Advance(time.Second)
+		}
+		if locked != 0 {
+			t.Fatalf("skip=%v: expected no relock before the delay, got %d", skip, locked)
+		}
+		sd.handleClassifications(noneBatch())
+		expectActions(t, sd, ActionLock)
+	}
+}
+
+func TestConfidenceDecaysAcrossSkippedCycles(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].MinConfidence = 0.2
+	config.ConfidenceDecayHalfLife = time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	want := 0.9
+	for i := 0; i < 2; i++ {
+		clock.Advance(time.Second)
+		sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+		want /= 2
+		if got := sd.confidences["dog"].value; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("cycle %d: expected confidence %v, got %v", i, want, got)
+		}
+		expectActions(t, sd)
+	}
+
+	clock.Advance(time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeNoSignal})
+	expectActions(t, sd, ActionLock)
+
+	clock.Advance(time.Minute)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	if got := sd.confidences["dog"].value; got > 1e-9 {
+		t.Fatalf("expected confidence to decay toward zero, got %v", got)
+	}
+}
+
+func TestConfidenceTracksLabelsAsMatched(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfidenceDecayHalfLife = time.Hour
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications([][]Classification{{{Label: "Big Dog", Confidence: 0.9}}})
+	expectActions(t, sd, ActionUnlock)
+	if got := sd.confidences["dog"].value; got != 0.9 {
+		t.Fatalf("expected \"Big Dog\" tracked under dog, got %v", got)
+	}
+	if got := sd.PresenceScore(); got <= 0 {
+		t.Fatalf("expected a presence score, got %v", got)
+	}
+
+	clock.Advance(time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	expectActions(t, sd)
+}
+
+func TestConfidenceDecaysAgainstTheDecisionThreshold(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfidenceDecayHalfLife = time.Minute
+	config.GlobalMinConfidence = 0.8
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.9}}})
+	expectActions(t, sd, ActionUnlock)
+
+	// Decayed to about 0.64: above the dog's MinConfidence of 0.5 but
+	// below the 0.8 floor the unlock was decided against.
+	clock.Advance(30 * time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	expectActions(t, sd, ActionLock)
+}
+
+func TestConfidenceHeldWithoutDecay(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	clock.Advance(time.Hour)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	expectActions(t, sd)
+}
+
+func TestCooldownRemainingCountsDownToZero(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalDurationUnlocking = 10 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	if got := sd.CooldownRemaining(); got != 0 {
+		t.Fatalf("expected no cooldown before any action, got %s", got)
+	}
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	last := sd.CooldownRemaining()
+	if last != 10*time.Second {
+		t.Fatalf("expected the full cooldown right after the action, got %s", last)
+	}
+	for i := 0; i < 9; i++ {
+		clock.Advance(time.Second)
+		got := sd.CooldownRemaining()
+		if got >= last {
+			t.Fatalf("expected cooldown to decrease from %s, got %s", last, got)
+		}
+		last = got
+	}
+	if last != time.Second {
+		t.Fatalf("expected 1s left, got %s", last)
+	}
+	clock.Advance(time.Second)
+	if got := sd.CooldownRemaining(); got != 0 {
+		t.Fatalf("expected zero at the boundary, got %s", got)
+	}
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	if d := sd.handleClassifications(cat); d.Action != ActionLock {
+		t.Fatalf("expected the lock to be allowed at zero, got %+v", d)
+	}
+}
+
+func TestPresenceAndTriggerUseIndependentThresholds(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].MinConfidence = 0.8
+	config.PresenceConfidence = 0.3
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	faint := [][]Classification{{{Label: "dog", Confidence: 0.5}}}
+
+	steps := []struct {
+		name      string
+		batch     [][]Classification
+		action    DoorAction
+		detection Detection
+	}{
+		{"faint dog does not trigger", faint, ActionNone, DetectionNone},
+		{"confident dog unlocks", dogBatch(), ActionUnlock, DetectionDog},
+		{"faint dog keeps it unlocked", faint, ActionNone, DetectionDog},
+		{"no dog relocks", noneBatch(), ActionLock, DetectionNone},
+	}
+	for _, step := range steps {
+		d := sd.handleClassifications(step.batch)
+		if d.Action != step.action || d.Detection != step.detection {
+			t.Fatalf("%s: expected %v/%v, got %v/%v", step.name, step.detection, step.action, d.Detection, d.Action)
+		}
+		sd.actions.Drain()
+	}
+}
+
+func TestEmptyResultPolicies(t *testing.T) {
+	noLabels := [][]Classification{{}}
+	tests := []struct {
+		name      string
+		batch     [][]Classification
+		noLabels  EmptyResultPolicy
+		unmatched EmptyResultPolicy
+		relock    bool
+	}{
+		{"no labels hold by default", noLabels, EmptyResultDefault, EmptyResultDefault, false},
+		{"no labels as none", noLabels, EmptyResultNone, EmptyResultDefault, true},
+		{"unmatched labels are none by default", noneBatch(), EmptyResultDefault, EmptyResultDefault, true},
+		{"unmatched labels held", noneBatch(), EmptyResultDefault, EmptyResultHold, false},
+		{"policies are independent", noneBatch(), EmptyResultHold, EmptyResultNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := dogDoorConfig()
+			config.NoLabelsPolicy = tt.noLabels
+			config.UnmatchedLabelsPolicy = tt.unmatched
+			classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}, {classifications: tt.batch}}}
+			sd, _, _, _ := newTestSmartDoor(config, classifier)
+			ctx := context.Background()
+
+			sd.handleCycle(ctx, sd.runCycle(ctx))
+			expectActions(t, sd, ActionUnlock)
+
+			d := sd.handleCycle(ctx, sd.runCycle(ctx))
+			if tt.relock {
+				expectActions(t, sd, ActionLock)
+				return
+			}
+			expectActions(t, sd)
+			if d.Detection != DetectionDog || sd.Stats().HeldCycles != 1 {
+				t.Fatalf("expected the dog held, got %v with %d held cycles", d.Detection, sd.Stats().HeldCycles)
+			}
+		})
+	}
+}
+
+func TestUnmatchedActivityIsDetectionUnknown(t *testing.T) {
+	config := dogDoorConfig()
+	config.UnknownActivity = 1.2
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	ambiguous := [][]Classification{{{Label: "raccoon", Confidence: 0.7}, {Label: "fox", Confidence: 0.6}}}
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	d := sd.handleClassifications(ambiguous)
+	if d.Detection != DetectionUnknown || d.Reason != ReasonUnknown || d.Trigger.Classification.Label != "raccoon" {
+		t.Fatalf("expected DetectionUnknown on raccoon, got %v (%v) on %+v", d.Detection, d.Reason, d.Trigger)
+	}
+	expectActions(t, sd)
+
+	if d := sd.handleClassifications(noneBatch()); d.Detection != DetectionNone {
+		t.Fatalf("expected a quiet yard to stay DetectionNone, got %v", d.Detection)
+	}
+	expectActions(t, sd, ActionLock)
+}
+
+func TestUnknownLockPolicyLocks(t *testing.T) {
+	config := dogDoorConfig()
+	config.UnknownActivity = 1.2
+	config.UnknownPolicy = UnknownLock
+	config.MinimalDurationUnlocking = time.Hour
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	ambiguous := [][]Classification{{{Label: "raccoon", Confidence: 0.7}, {Label: "fox", Confidence: 0.6}}}
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	sd.handleClassifications(ambiguous)
+	expectActions(t, sd, ActionLock)
+	if d := sd.handleClassifications(ambiguous); d.Reason != ReasonUnchanged {
+		t.Fatalf("expected the door to stay locked, got %v", d.Reason)
+	}
+	expectActions(t, sd)
+}
+
+func TestCatArrivalPolicies(t *testing.T) {
+	dogAndCat := [][]Classification{{{Label: "dog", Confidence: 0.9}, {Label: "cat", Confidence: 0.9}}}
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	tests := []struct {
+		name   string
+		policy CatArrivalPolicy
+		// lockOn is the step, 1 for the cat's arrival or 2 for the dog
+		// leaving, whose cycle locks.
+		lockOn int
+	}{
+		{"immediate lock", CatArrivalImmediateLock, 1},
+		{"wait for dog to clear", CatArrivalWaitForDogToClear, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := dogDoorConfig()
+			config.CatArrivalPolicy = tt.policy
+			config.CatArrivalTimeout = time.Minute
+			sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+			sd.handleClassifications(dogBatch())
+			expectActions(t, sd, ActionUnlock)
+			for step, batch := range [][][]Classification{dogAndCat, cat} {
+				clock.Advance(time.Second)
+				d := sd.handleClassifications(batch)
+				if step+1 == tt.lockOn {
+					expectActions(t, sd, ActionLock)
+					continue
+				}
+				expectActions(t, sd)
+				if step == 0 && d.Reason != ReasonWaitingForDog {
+					t.Fatalf("expected to wait for the dog, got %v", d.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestCatArrivalWaitTimesOut(t *testing.T) {
+	config := dogDoorConfig()
+	config.CatArrivalPolicy = CatArrivalWaitForDogToClear
+	config.CatArrivalTimeout = time.Minute
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	dogAndCat := [][]Classification{{{Label: "dog", Confidence: 0.9}, {Label: "cat", Confidence: 0.9}}}
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	sd.handleClassifications(dogAndCat)
+	clock.Advance(59 * time.Second)
+	sd.handleClassifications(dogAndCat)
+	expectActions(t, sd)
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogAndCat)
+	expectActions(t, sd, ActionLock)
+}
+
+func TestMinPresentDurationIgnoresBriefAppearances(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationLockList[0].MinPresentDuration = 3 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	// A cat darting past for two seconds does not lock.
+	for range 3 {
+		if d := sd.handleClassifications(cat); d.Reason != ReasonNotPresentLongEnough {
+			t.Fatalf("expected the cat not present long enough, got %v", d.Reason)
+		}
+		clock.Advance(time.Second)
+	}
+	sd.handleClassifications(noneBatch())
+	expectActions(t, sd)
+
+	// One that stays does, once it has been seen for three seconds.
+	for range 3 {
+		clock.Advance(time.Second)
+		sd.handleClassifications(cat)
+		expectActions(t, sd)
+	}
+	clock.Advance(time.Second)
+	sd.handleClassifications(cat)
+	expectActions(t, sd, ActionLock)
+
+	// The dog has no minimum and unlocks at once.
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestGlobalMinConfidenceGatesLowLabelThresholds(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassificationUnlockList[0].MinConfidence = 0.1
+	config.GlobalMinConfidence = 0.6
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+
+	if got := sd.EffectiveThreshold("dog"); got != 0.6 {
+		t.Fatalf("expected the global floor as the effective threshold, got %v", got)
+	}
+	d := sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.4}}})
+	if d.Reason != ReasonBelowConfidence {
+		t.Fatalf("expected a dog under the floor to be below confidence, got %v", d.Reason)
+	}
+	expectActions(t, sd)
+
+	sd.handleClassifications([][]Classification{{{Label: "dog", Confidence: 0.7}}})
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestUnlockWaitsForStableConnectivity(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConnectivityStabilization = 10 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleDoorEvent(DoorEventConnected)
+	for _, advance := range []time.Duration{0, 5 * time.Second, 4 * time.Second} {
+		clock.Advance(advance)
+		if d := sd.handleClassifications(dogBatch()); d.Action != ActionNone {
+			t.Fatalf("expected the unlock deferred, got %v", d.Action)
+		}
+		if got := sd.LastNoActionReason(); got != ReasonStabilizing {
+			t.Fatalf("expected stabilizing, got %v", got)
+		}
+	}
+	clock.Advance(time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	// A reconnection starts the wait again, but never holds back a lock.
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	sd.handleDoorEvent(DoorEventDisconnected)
+	sd.handleDoorEvent(DoorEventConnected)
+	sd.handleClassifications(cat)
+	expectActions(t, sd, ActionLock)
+	if d := sd.handleClassifications(dogBatch()); d.Action != ActionNone {
+		t.Fatalf("expected the unlock deferred after reconnecting, got %v", d.Action)
+	}
+}
+
+func TestDisabledLabelTriggersNothingUntilEnabledOrExpired(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	sd.DisableLabel("Dog", time.Minute)
+	if got := sd.EffectiveConfig().ClassificationUnlockList; len(got) != 0 {
+		t.Fatalf("expected the disabled label out of the effective config, got %v", got)
+	}
+	if d := sd.handleClassifications(dogBatch()); d.Action != ActionNone {
+		t.Fatalf("expected no action for a disabled label, got %v", d.Action)
+	}
+	expectActions(t, sd)
+
+	sd.EnableLabel("dog")
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(time.Minute)
+	sd.handleClassifications(cat)
+	expectActions(t, sd, ActionLock)
+	sd.DisableLabel("dog", time.Minute)
+	clock.Advance(30 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(30 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestProlongedCameraLossAppliesPolicy(t *testing.T) {
+	config := dogDoorConfig()
+	config.FailSafeAfterErrors = 0
+	config.CameraLossGrace = 30 * time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+	blind := CycleResult{Outcome: OutcomeError}
+
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	sd.handleCameraEvent(defaultCameraID, CameraEventDisconnected)
+	clock.Advance(20 * time.Second)
+	sd.handleCycle(context.Background(), blind)
+	expectActions(t, sd)
+	clock.Advance(10 * time.Second)
+	sd.handleCycle(context.Background(), blind)
+	expectActions(t, sd, ActionLock)
+	sd.handleCycle(context.Background(), blind)
+	expectActions(t, sd)
+
+	var failSafe bool
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventFailSafe && e.Action == ActionLock {
+			failSafe = true
+		}
+	}
+	if !failSafe {
+		t.Fatal("expected a fail-safe event for the camera loss")
+	}
+
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+	clock.Advance(time.Minute)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	// Under CameraLossHold a loss leaves the door alone.
+	sd.mu.Lock()
+	sd.config.CameraLossPolicy = CameraLossHold
+	sd.mu.Unlock()
+	sd.handleCameraEvent(defaultCameraID, CameraEventDisconnected)
+	clock.Advance(time.Minute)
+	sd.handleCycle(context.Background(), blind)
+	expectActions(t, sd)
+}
+
+func TestDiffConfigReportsFieldAndListChanges(t *testing.T) {
+	old := DefaultDogDoorConfig()
+	updated := old.clone()
+	updated.CycleTimeout = 7 * time.Second
+	updated.StartArmed = false
+	updated.ClassificationUnlockList[0].MinConfidence = 0.75
+	updated.ClassificationLockList = append(updated.ClassificationLockList, ClassificationConfig{Label: "fox", MinConfidence: 0.4})
+	updated.IgnoreList = nil
+
+	want := []ConfigChange{
+		{Path: "ClassificationUnlockList[0].MinConfidence", Old: old.ClassificationUnlockList[0].MinConfidence, New: 0.75},
+		{Path: "ClassificationLockList[1]", New: ClassificationConfig{Label: "fox", MinConfidence: 0.4}},
+		{Path: "IgnoreList[0]", Old: old.IgnoreList[0]},
+		{Path: "CycleTimeout", Old: old.CycleTimeout, New: 7 * time.Second},
+		{Path: "StartArmed", Old: true, New: false},
+	}
+	if got := DiffConfig(old, updated); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected\n%v\ngot\n%v", want, got)
+	}
+	if got := DiffConfig(old, old.clone()); len(got) != 0 {
+		t.Fatalf("expected no changes between equal configs, got %v", got)
+	}
+
+	sd, _, _, _ := newTestSmartDoor(old, &fakeClassifier{})
+	events := sd.Events()
+	if err := sd.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	e := <-events
+	if e.Kind != EventConfigChanged || !reflect.DeepEqual(e.ConfigChanges, want) {
+		t.Fatalf("expected the changes in an EventConfigChanged, got %+v", e)
+	}
+}
+
+func TestPresenceScoreRisesWithDogAndDecaysAfter(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfidenceDecayHalfLife = time.Second
+	config.ClassificationUnlockList[0].MinConfidence = 0.8
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	dog := [][]Classification{{{Label: "dog", Confidence: 0.9}}}
+
+	// dog 0.9 against its 0.8 threshold reads 0.75 once settled.
+	var last float64
+	for i := 0; i < 6; i++ {
+		sd.handleClassifications(dog)
+		score := sd.PresenceScore()
+		if score <= last || score > 0.75 {
+			t.Fatalf("cycle %d: expected the score to rise towards 0.75, got %v after %v", i, score, last)
+		}
+		last = score
+	}
+	if last < 0.7 {
+		t.Fatalf("expected the score near 0.75, got %v", last)
+	}
+
+	// A gap of held cycles decays it, and an empty scene drops it.
+	clock.Advance(2 * time.Second)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeNoSignal})
+	if score := sd.PresenceScore(); score >= last || score <= 0 {
+		t.Fatalf("expected the score to decay during the gap, got %v after %v", score, last)
+	}
+	for i := 0; i < 10; i++ {
+		sd.handleClassifications(noneBatch())
+	}
+	if score := sd.PresenceScore(); score > 0.01 {
+		t.Fatalf("expected the score near zero once the dog left, got %v", score)
+	}
+}
+
+func TestConfirmWithExtraCaptureRequiresMatchingRecapture(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfirmWithExtraCapture = true
+	classifier := &fakeClassifier{results: []fakeResult{
+		{classifications: noneBatch()},
+		{classifications: dogBatch()},
+	}}
+	sd, camera, _, _ := newTestSmartDoor(config, classifier)
+
+	if d := sd.handleClassifications(dogBatch()); d.Action != ActionNone || d.Reason != ReasonNotConfirmed {
+		t.Fatalf("expected an unconfirmed dog to do nothing, got %v (%v)", d.Action, d.Reason)
+	}
+	expectActions(t, sd)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	camera.mu.Lock()
+	defer camera.mu.Unlock()
+	if camera.captures != 2 {
+		t.Fatalf("expected one extra capture per attempt, got %d", camera.captures)
+	}
+	if n := sd.Stats().ConfirmRejected; n != 1 {
+		t.Fatalf("expected one rejected confirmation, got %d", n)
+	}
+}
+
+func TestPresenceScoreOnCameraDisconnect(t *testing.T) {
+	for policy, want := range map[DisconnectPresencePolicy]float64{
+		DisconnectPresenceHold:    0.75,
+		DisconnectPresenceZero:    0,
+		DisconnectPresenceUnknown: -1,
+	} {
+		config := dogDoorConfig()
+		config.DisconnectPresence = policy
+		sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+		sd.mu.Lock()
+		sd.presenceScore = 0.75
+		sd.mu.Unlock()
+
+		sd.handleCameraEvent(defaultCameraID, CameraEventDisconnected)
+		sd.updatePresence()
+		if got := sd.PresenceScore(); got != want {
+			t.Fatalf("policy %d: expected %v while disconnected, got %v", policy, want, got)
+		}
+	}
+}
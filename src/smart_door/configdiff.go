@@ -0,0 +1,138 @@
+package smartdoor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ConfigChange is one field that differs between two configs. Path names
+// it as in Go, such as "CycleTimeout" or
+// "ClassificationUnlockList[0].MinConfidence"; a list entry only one side
+// has is reported whole, with the other side nil.
+type ConfigChange struct {
+	Path string
+	Old  any
+	New  any
+}
+
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// DiffConfig returns the field-level changes from old to new, in field
+// order.
+func DiffConfig(old, new Config) []ConfigChange {
+	var changes []ConfigChange
+	diffValue("", reflect.ValueOf(old), reflect.ValueOf(new), &changes)
+	return changes
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	locationType = reflect.TypeFor[*time.Location]()
+)
+
+func diffValue(path string, old, new reflect.Value, changes *[]ConfigChange) {
+	add := func(old, new any) {
+		*changes = append(*changes, ConfigChange{Path: path, Old: old, New: new})
+	}
+
+	switch {
+	case old.Type() == timeType:
+		if !old.Interface().(time.Time).Equal(new.Interface().(time.Time)) {
+			add(old.Interface(), new.Interface())
+		}
+	case old.Type() == locationType:
+		if a, b := old.Interface().(*time.Location), new.Interface().(*time.Location); a.String() != b.String() {
+			add(a.String(), b.String())
+		}
+	case old.Kind() == reflect.Struct:
+		for i := range old.NumField() {
+			field := old.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValue(name, old.Field(i), new.Field(i), changes)
+		}
+	case old.Kind() == reflect.Slice:
+		for i := range max(old.Len(), new.Len()) {
+			entry := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= old.Len():
+				*changes = append(*changes, ConfigChange{Path: entry, New: new.Index(i).Interface()})
+			case i >= new.Len():
+				*changes = append(*changes, ConfigChange{Path: entry, Old: old.Index(i).Interface()})
+			default:
+				diffValue(entry, old.Index(i), new.Index(i), changes)
+			}
+		}
+	case old.Kind() == reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, m := range []reflect.Value{old, new} {
+			for _, key := range m.MapKeys() {
+				keys[fmt.Sprint(key.Interface())] = key
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry := fmt.Sprintf("%s[%s]", path, name)
+			a, b := old.MapIndex(keys[name]), new.MapIndex(keys[name])
+			switch {
+			case !a.IsValid():
+				*changes = append(*changes, ConfigChange{Path: entry, New: b.Interface()})
+			case !b.IsValid():
+				*changes = append(*changes, ConfigChange{Path: entry, Old: a.Interface()})
+			default:
+				diffValue(entry, a, b, changes)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			add(old.Interface(), new.Interface())
+		}
+	}
+}
+
+// emitConfigChanged reports the changes from old to new, if any, as an
+// EventConfigChanged described by cause.
+func (sd *SmartDoor) emitConfigChanged(cause string, old, new Config) {
+	changes := DiffConfig(old, new)
+	if len(changes) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%s: %d changes", cause, len(changes))
+	sd.logger.Info(fmt.Sprintf("%s %v", msg, changes))
+	sd.emit(Event{Kind: EventConfigChanged, Time: sd.clock.Now(), Message: msg, ConfigChanges: changes})
+}
+
+// noteProfileSwitch reports a change of active profile as the changes
+// between the configs the old and new profiles apply.
+func (sd *SmartDoor) noteProfileSwitch() {
+	sd.mu.Lock()
+	config, name := sd.config, ""
+	if p := sd.activeProfileLocked(); p != nil {
+		config, name = p.Config, p.Name
+	}
+	sd.mu.Unlock()
+
+	previous, previousName, noted := sd.profileConfig, sd.profileName, sd.profileNoted
+	sd.profileConfig, sd.profileName, sd.profileNoted = config, name, true
+	if !noted || name == previousName {
+		return
+	}
+	cause := fmt.Sprintf("profile %q", name)
+	if name == "" {
+		cause = fmt.Sprintf("profile %q ended", previousName)
+	}
+	sd.emitConfigChanged(cause, previous, config)
+}
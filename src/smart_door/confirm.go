@@ -0,0 +1,125 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// confirmAction announces action as an EventPendingAction and waits
+// Config.ConfirmDelay before it is applied, reporting whether it should
+// be. CancelPending during the wait drops it; Run stopping applies it, as
+// it does every queued action.
+func (sd *SmartDoor) confirmAction(ctx context.Context, action DoorAction) bool {
+	delay := sd.currentConfig().ConfirmDelay
+	if delay <= 0 {
+		return true
+	}
+
+	cancelled := make(chan struct{})
+	sd.mu.Lock()
+	sd.cancelPending = cancelled
+	sd.pendingAction = action
+	sd.mu.Unlock()
+	defer func() {
+		sd.mu.Lock()
+		sd.cancelPending = nil
+		sd.pendingAction = ActionNone
+		sd.mu.Unlock()
+	}()
+
+	timer := sd.clock.NewTimer(delay)
+	defer timer.Stop()
+	sd.announcePending(action, delay, sd.clock.Now())
+
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return true
+	case <-cancelled:
+		sd.logger.Info(fmt.Sprintf("pending %s cancelled", action))
+		return false
+	}
+}
+
+// announcePending emits the EventPendingAction of action, due delay after now.
+func (sd *SmartDoor) announcePending(action DoorAction, delay time.Duration, now time.Time) {
+	sd.mu.Lock()
+	ac := sd.pendingContexts[action]
+	sd.mu.Unlock()
+	sd.emit(Event{
+		Kind:    EventPendingAction,
+		Time:    now,
+		Action:  action,
+		Message: fmt.Sprintf("%s in %s unless cancelled", action, delay),
+		Context: &ac,
+	})
+}
+
+// CancelPending drops the action announced by the latest EventPendingAction
+// if it has not been applied yet, reporting whether there was one. The
+// next cycle takes back the decision as though it was never made: the
+// intended state, cooldown, hold and unlock cap return to what they were,
+// so the action is not reasserted and a detection that persists decides
+// it afresh.
+func (sd *SmartDoor) CancelPending() bool {
+	sd.mu.Lock()
+	if sd.cancelPending == nil {
+		sd.mu.Unlock()
+		return false
+	}
+	close(sd.cancelPending)
+	sd.cancelPending = nil
+	sd.dropPendingLocked(sd.pendingAction)
+	sd.mu.Unlock()
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callCancelPending})
+	return true
+}
+
+// dropPending counts action as cancelled and has the next cycle roll back
+// its decision.
+func (sd *SmartDoor) dropPending(action DoorAction) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.dropPendingLocked(action)
+}
+
+// dropPendingLocked must be called with sd.mu held.
+func (sd *SmartDoor) dropPendingLocked(action DoorAction) {
+	sd.cancelled = action
+	sd.stats.PendingCancelled++
+}
+
+// decisionUndo is the decision loop state decide overwrites.
+type decisionUndo struct {
+	action             DoorAction
+	intended           DoorAction
+	lastDetection      Detection
+	lastActionTime     time.Time
+	lastActionPriority int
+	unlockedSince      time.Time
+	holdUntil          time.Time
+	holdDuration       time.Duration
+}
+
+// rollBackCancelled restores the state from before an action CancelPending
+// dropped, unless a later decision has replaced it.
+func (sd *SmartDoor) rollBackCancelled() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	action := sd.cancelled
+	sd.cancelled = ActionNone
+	if action == ActionNone || sd.undo.action != action || sd.intended != action {
+		return
+	}
+	undo := sd.undo
+	sd.undo = decisionUndo{}
+	sd.intended = undo.intended
+	sd.lastDetection = undo.lastDetection
+	sd.lastActionTime = undo.lastActionTime
+	sd.lastActionPriority = undo.lastActionPriority
+	sd.unlockedSince = undo.unlockedSince
+	sd.holdUntil = undo.holdUntil
+	sd.holdDuration = undo.holdDuration
+}
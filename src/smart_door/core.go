@@ -1,35 +1,91 @@
 package smartdoor
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
 	"time"
 )
 
-type Config struct {
-	MinimalDurationUnlocking time.Duration
-	MinimalDurationLocking   time.Duration
-	MinimalRateCameraProcess time.Duration
-	ClassificationUnlockList []ClassificationConfig
-	ClassificationLockList   []ClassificationConfig
-}
-
-type ClassificationConfig struct {
-	Label         string
-	MinConfidence float64
-}
-
+// Device calls take a context that ends when the call should be
+// abandoned; implementations should return promptly with an error once it
+// is done. Implementations written against the earlier context-free
+// methods can add the ctx parameter and ignore it, but then Stop waits for
+// any call already in flight.
 type DeviceCamera interface {
 	Subscribe() <-chan DeviceCameraEvent
-	CaptureFrames() ([]Frame, error)
+	// CaptureFrames runs under the cycle's context, which ends with its
+	// CycleTimeout or when Run stops.
+	CaptureFrames(ctx context.Context) ([]Frame, error)
+}
+
+// FramePusher is implemented by cameras that deliver frames themselves,
+// such as IP cameras emitting frames on motion events.
+type FramePusher interface {
+	Frames() <-chan []Frame
 }
 
 type DeviceDoor interface {
 	Subscribe() <-chan DeviceDoorEvent
-	Lock() error
-	Unlock() error
+	// Lock and Unlock are cancelled after Config.DoorCallTimeout, or when
+	// shutdown stops waiting for them at Config.ShutdownTimeout.
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
 }
 
 type ImageClassifier interface {
-	ClassifyFrames(frames []Frame) ([][]Classification, error)
+	// ClassifyFrames should return promptly once ctx is done; the
+	// context ends with the cycle's CycleTimeout or when Run stops.
+	// A classifier that fails on only some frames returns FrameErrors
+	// alongside the classifications of the frames that succeeded.
+	ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error)
+}
+
+// FrameErrors reports per-frame classification failures, parallel to the
+// frames passed to ClassifyFrames; a nil entry marks a frame that was
+// classified. The cycle proceeds with the successful frames and is only an
+// error when every frame failed.
+type FrameErrors []error
+
+func (e FrameErrors) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d frames failed: %v", failed, len(e), first)
+}
+
+func (e FrameErrors) Unwrap() []error {
+	return e
+}
+
+// partial reports whether at least one frame was classified.
+func (e FrameErrors) partial() bool {
+	for _, err := range e {
+		if err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// partialFrames returns err as FrameErrors if some but not all frames
+// failed.
+func partialFrames(err error) (FrameErrors, bool) {
+	var frameErrs FrameErrors
+	if errors.As(err, &frameErrs) && frameErrs.partial() {
+		return frameErrs, true
+	}
+	return nil, false
 }
 
 type DeviceCameraEvent int
@@ -46,8 +102,17 @@ const (
 	DoorEventDisconnected
 )
 
+// Frame is one captured image. Data is passed to the classifier as is;
+// Format says how to decode it when an image.Image is needed.
 type Frame struct {
-	// Frame data
+	Data   []byte
+	Format FrameFormat
+	// CapturedAt is when the camera took the frame, zero if it does not
+	// say.
+	CapturedAt time.Time
+	// Width and Height are the frame's size in pixels, zero if unknown.
+	// Frames resized to Config.InputResolution always carry it.
+	Width, Height int
 }
 
 type Classification struct {
@@ -55,23 +120,290 @@ type Classification struct {
 	Confidence float64
 }
 
+// Trigger identifies the classification that decided a detection.
+type Trigger struct {
+	Classification Classification
+	Camera         string
+	// Frame is the index of the frame within the classified batch, and
+	// CapturedAt its Frame.CapturedAt.
+	Frame      int
+	CapturedAt time.Time
+	// Priority is the highest ClassificationConfig.Priority the
+	// classification matched, and HoldDuration and MinPresentDuration
+	// those of that entry.
+	Priority           int
+	HoldDuration       time.Duration
+	MinPresentDuration time.Duration
+}
+
+const defaultCameraID = "cam0"
+
 type Detection int
 
 const (
 	DetectionNone Detection = iota
 	DetectionCat
 	DetectionDog
+	// DetectionHold means an IgnoreList label matched, so the door keeps
+	// its current state this cycle.
+	DetectionHold
+	// DetectionUnknown means something is there that no list matches; see
+	// Config.UnknownActivity.
+	DetectionUnknown
 )
 
+func (d Detection) String() string {
+	switch d {
+	case DetectionNone:
+		return "none"
+	case DetectionCat:
+		return "cat"
+	case DetectionDog:
+		return "dog"
+	case DetectionHold:
+		return "hold"
+	case DetectionUnknown:
+		return "unknown"
+	}
+	return fmt.Sprintf("Detection(%d)", int(d))
+}
+
 type SmartDoor struct {
 	config           Config
 	camera           DeviceCamera
 	door             DeviceDoor
 	classifier       ImageClassifier
-	cameraEvents     <-chan DeviceCameraEvent
+	clock            Clock
+	trainingSink     TrainingSink
+	logger           Logger
+	executor         ActionExecutor
+	veto             ActionVeto
+	resizer          FrameResizer
+	tracer           *TraceRecorder
+	onRecovered      func(Recovery)
+	doors            []*doorSlot
+	metrics          Metrics
+	aggregate        CameraAggregator
+	rand             *rand.Rand
+	cameras          []cameraSlot
+	cameraEvents     chan cameraEvent
 	doorEvents       <-chan DeviceDoorEvent
-	classificationCh chan [][]Classification
-	doorActionCh     chan DoorAction
+	doorSlotEvents   chan doorEvent
+	classificationCh chan CycleResult
+	// input and output are where the door controller reads cycles and the
+	// camera pipeline sends them, classificationCh unless one half runs
+	// on its own.
+	input       <-chan CycleResult
+	output      chan<- CycleResult
+	rateChanged chan struct{}
+	actions     *actionQueue
+
+	// Owned by the camera processing goroutine.
+	staleFrames    []Frame
+	staleResult    [][]Classification
+	identicalCount int
+	// confidenceRangeWarning logs the first out-of-range confidence.
+	confidenceRangeWarning sync.Once
+	// captureMu serializes captures between the loop and Snapshot.
+	captureMu sync.Mutex
+
+	// Owned by the controlDoor goroutine.
+	lastDetection Detection
+	previous      [][]Classification
+	hasPrevious   bool
+	// previousDetection is what previous was detected as.
+	previousDetection Detection
+	confidences       map[string]confidenceTrack
+	// unlockedSince is when the current unlock was decided, zero while
+	// locked.
+	unlockedSince    time.Time
+	unlockCapLatched bool
+	trainingCycles   int
+	// consecutiveErrors counts error cycles since the last cycle that
+	// was not an error.
+	consecutiveErrors int
+	// outageSince is when the current error streak began, and
+	// outageEngaged whether it has tripped the fail-safe or breaker and
+	// so ends with EventClassifierRecovered.
+	outageSince   time.Time
+	outageEngaged bool
+	// cameraLostAt is the disconnection CameraLossPolicy last handled.
+	cameraLostAt time.Time
+	// profileName and profileConfig are the profile, empty for none, and
+	// config the last cycle ran under, once profileNoted.
+	profileName   string
+	profileConfig Config
+	profileNoted  bool
+	// observed is the detection last reported while disarmed, valid while
+	// observing.
+	observed  Detection
+	observing bool
+	// absentSince is when the current run of decided absence began while
+	// unlocked, zero otherwise.
+	absentSince time.Time
+	// catSince is when a cat was first seen alongside the dog under
+	// CatArrivalWaitForDogToClear, zero otherwise.
+	catSince time.Time
+	// detectedSince is when the current unbroken run of decided cycles
+	// with the same detection began.
+	detectedSince time.Time
+	// holdUntil is when the current HoldDuration ends, zero without one.
+	holdUntil    time.Time
+	holdDuration time.Duration
+	// lastAssertTime is when intended was last sent to the door.
+	lastAssertTime time.Time
+	// graceUntil ends the Config.ManualGracePeriod of a manual unlock.
+	graceUntil time.Time
+	// logCycles counts cycles seen by logCycle, for sampling.
+	logCycles int
+
+	mu           sync.Mutex
+	stats        Stats
+	doorState    DoorState
+	connectivity Connectivity
+	subscribers  []subscriber
+	taps         []chan ClassificationSnapshot
+	// presenceScore is PresenceScore, updated by the controller.
+	presenceScore float64
+	closed        bool
+	cancel        context.CancelFunc
+	startedAt     time.Time
+	summary       summaryWindow
+	done          <-chan struct{}
+	profiles      []Profile
+	override      *override
+	// pendingContexts holds the context of the latest decision for each
+	// action, whose Reason becomes stateReason once the door applies it.
+	pendingContexts   map[DoorAction]ActionContext
+	stateReason       string
+	recentConfidences map[string][]float64
+	frameSamples      []frameSample
+
+	breakerOpenUntil time.Time
+	cooldownResetAt  time.Time
+	lastActionTime   time.Time
+	// inFlight is the action the executor is applying, and manualUnlockAt
+	// when an unlock nobody commanded was observed, until adopted.
+	inFlight       DoorAction
+	manualUnlockAt time.Time
+	noActionReason NoActionReason
+	// lastDecision is what the latest cycle decided, lastClassifications
+	// the batch of the latest decided cycle and lastSnapshot the trigger
+	// frame of the latest one with a trigger.
+	lastDecision        decision
+	lastClassifications [][]Classification
+	lastSnapshot        Frame
+	// intended is the state the controller means the door to be in; see
+	// IntendedState.
+	intended DoorAction
+	// lastActionPriority is the trigger priority of the last action.
+	lastActionPriority int
+	// lastEnqueued is the last action decided or forced and sameActions
+	// how many times in a row it was, for Config.MaxConsecutiveSameAction.
+	lastEnqueued DoorAction
+	sameActions  int
+	// disabledLabels maps each label turned off by DisableLabel, in lower
+	// case, to when it comes back.
+	disabledLabels map[string]time.Time
+	// disarmed is set between Disarm and Arm.
+	disarmed bool
+	// cancelPending is closed by CancelPending, non-nil while an action
+	// waits out Config.ConfirmDelay, and pendingAction is that action.
+	// cancelled is an action CancelPending dropped that the decision loop
+	// has yet to roll back with undo, the state from before the latest
+	// decided action.
+	cancelPending chan struct{}
+	pendingAction DoorAction
+	cancelled     DoorAction
+	undo          decisionUndo
+}
+
+type Connectivity struct {
+	// Camera is connected only while every camera is.
+	Camera  DeviceConnectivity
+	Cameras map[string]DeviceConnectivity
+	Door    DeviceConnectivity
+	// Doors holds each secondary door by ID; see WithDoor.
+	Doors map[string]DeviceConnectivity
+}
+
+type DeviceConnectivity struct {
+	Connected bool
+	// ChangedAt is when Connected last changed; zero until the first event.
+	ChangedAt time.Time
+}
+
+type Stats struct {
+	Cycles           int
+	ClassifyRetries  int
+	ClassifyFailures int
+	// FrameFailures counts frames a classifier reported in FrameErrors.
+	FrameFailures int
+	// NotificationsSuppressed counts events Config.QuietHours held back
+	// from at least one Notifications subscriber.
+	NotificationsSuppressed int
+	CaptureFailures         int
+	FramesThrottled         int
+	// FramesThinned counts frames dropped by Config.MinInterFrameInterval.
+	FramesThinned int
+	// FramesTruncated counts frames dropped by Config.MaxFramesPerCycle.
+	FramesTruncated  int
+	Evaluations      int
+	UnchangedSkipped int
+	DoorFailures     int
+	EventsDropped    int
+	FramesProcessed  int
+	BreakerTrips     int
+	BreakerSkipped   int
+	// ActionsCapped counts actions not sent to the door under
+	// Config.MaxConsecutiveSameAction.
+	ActionsCapped int
+	// ClassifierRecoveries counts EventClassifierRecovered emissions.
+	ClassifierRecoveries int
+	// PendingCancelled counts pending actions dropped by CancelPending.
+	PendingCancelled int
+	// TapDropped counts snapshots a full ClassificationTap missed.
+	TapDropped int
+	// ConfirmRejected counts detections Config.ConfirmWithExtraCapture's
+	// extra capture did not confirm.
+	ConfirmRejected int
+	// ReturnsCoalesced counts dogs back within Config.ReturnGrace.
+	ReturnsCoalesced int
+
+	HeldCycles int
+	// Reasserts counts intended actions re-sent by Config.ReassertInterval.
+	Reasserts int
+	// Vetoes counts detection actions the Veto hook replaced or cancelled.
+	Vetoes int
+	// Resubscribes counts device event subscriptions renewed after the
+	// device closed them.
+	Resubscribes int
+
+	TrainingRecords       int
+	TrainingWriteFailures int
+
+	// FramesPerSecond is the classified frame rate over the last ten
+	// seconds.
+	FramesPerSecond float64
+	// PollInterval is the current, backpressure-adapted interval between
+	// polling cycles.
+	PollInterval time.Duration
+}
+
+var (
+	ErrAlreadyRunning = errors.New("smartdoor: already running")
+	ErrNotRunning     = errors.New("smartdoor: not running")
+	// ErrDoorCallTimeout reports a door call abandoned after
+	// Config.DoorCallTimeout.
+	ErrDoorCallTimeout = errors.New("smartdoor: door call timed out")
+)
+
+type Option func(*SmartDoor)
+
+func WithClock(clock Clock) Option {
+	return func(sd *SmartDoor) {
+		sd.clock = clock
+	}
 }
 
 type DoorAction int
@@ -82,102 +414,1952 @@ const (
 	ActionUnlock
 )
 
+func (a DoorAction) String() string {
+	switch a {
+	case ActionNone:
+		return "none"
+	case ActionLock:
+		return "lock"
+	case ActionUnlock:
+		return "unlock"
+	}
+	return fmt.Sprintf("DoorAction(%d)", int(a))
+}
+
+type DoorState int
+
+const (
+	DoorStateUnknown DoorState = iota
+	DoorStateLocked
+	DoorStateUnlocked
+)
+
+func (s DoorState) String() string {
+	switch s {
+	case DoorStateUnknown:
+		return "unknown"
+	case DoorStateLocked:
+		return "locked"
+	case DoorStateUnlocked:
+		return "unlocked"
+	}
+	return fmt.Sprintf("DoorState(%d)", int(s))
+}
+
 func NewSmartDoor(
 	config Config,
 	camera DeviceCamera,
 	door DeviceDoor,
 	classifier ImageClassifier,
+	opts ...Option,
 ) *SmartDoor {
-	return &SmartDoor{
+	sd := &SmartDoor{
 		config:           config,
 		camera:           camera,
 		door:             door,
 		classifier:       classifier,
-		cameraEvents:     camera.Subscribe(),
+		clock:            realClock{},
+		logger:           nopLogger{},
+		executor:         doorExecutor{door: door},
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+		pendingContexts:  make(map[DoorAction]ActionContext),
+		cameras:          []cameraSlot{{id: defaultCameraID, camera: camera}},
+		cameraEvents:     make(chan cameraEvent),
+		doorSlotEvents:   make(chan doorEvent),
 		doorEvents:       door.Subscribe(),
-		classificationCh: make(chan [][]Classification),
-		doorActionCh:     make(chan DoorAction),
+		classificationCh: make(chan CycleResult),
+		rateChanged:      make(chan struct{}, 1),
+		actions:          newActionQueue(),
+		disarmed:         !config.StartArmed,
+	}
+	sd.input = sd.classificationCh
+	sd.output = sd.classificationCh
+	for _, opt := range opts {
+		opt(sd)
+	}
+	sd.summary.start = sd.clock.Now()
+	for i, slot := range sd.cameras {
+		if slot.classifier == nil {
+			sd.cameras[i].classifier = classifier
+		}
+		sd.cameras[i].events = slot.camera.Subscribe()
+		sd.connectivity.initCamera(slot.id)
+	}
+	return sd
+}
+
+func (sd *SmartDoor) currentConfig() Config {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.effectiveConfigLocked()
+}
+
+// UpdateConfig validates and swaps in a new config. Decision settings apply
+// from the next cycle and the action cooldown starts afresh, so the first
+// action under the new config is not held back by one taken under the old.
+// Settings read when Run starts, such as MinimalRateCameraProcess,
+// CameraMode and HeartbeatInterval, keep their values until the next Run;
+// SetCaptureRate changes the rate live.
+func (sd *SmartDoor) UpdateConfig(config Config) error {
+	config, err := config.ResolveLabels()
+	if err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if sd.tracer != nil {
+		traced := config.clone()
+		traced.LabelSource = ""
+		sd.trace(TraceEntry{Kind: TraceKindCall, Call: callUpdateConfig, Config: &traced})
+	}
+
+	sd.mu.Lock()
+	old := sd.config
+	sd.config = config
+	sd.cooldownResetAt = sd.clock.Now()
+	sd.mu.Unlock()
+	sd.emitConfigChanged("config updated", old, config)
+	return nil
+}
+
+// SetCaptureRate changes only MinimalRateCameraProcess. A running poll loop
+// restarts its wait at the new rate, dropping any backoff, so the next cycle
+// is d away. Profiles keep the rate in their own configs.
+func (sd *SmartDoor) SetCaptureRate(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("smartdoor: capture rate must be positive, got %s", d)
+	}
+
+	sd.mu.Lock()
+	sd.config.MinimalRateCameraProcess = d
+	sd.mu.Unlock()
+	select {
+	case sd.rateChanged <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (sd *SmartDoor) Stats() Stats {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	stats := sd.stats
+	stats.FramesPerSecond = sd.framesPerSecondLocked(sd.clock.Now())
+	return stats
+}
+
+// DoorState is the state left by the last door action that succeeded.
+func (sd *SmartDoor) DoorState() DoorState {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.doorState
+}
+
+func (sd *SmartDoor) Connectivity() Connectivity {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.connectivity.clone()
+}
+
+// Run blocks until ctx is cancelled or Stop is called, then shuts down
+// gracefully: the camera pipeline stops, actions already decided are
+// applied, and event subscriptions are closed once no more events can be
+// emitted. Config.ShutdownTimeout bounds the drain; zero waits for it.
+// Subscriptions made before Run receive EventStarted first.
+//
+// A SmartDoor runs at most once: later calls return ErrAlreadyRunning
+// rather than starting goroutines that would compete for the same
+// channels. The same goes for RunCameraPipeline and RunDoorController,
+// the halves Run composes.
+func (sd *SmartDoor) Run(ctx context.Context) error {
+	ctx, finish, err := sd.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer finish()
+
+	var pipeline sync.WaitGroup
+	sd.startCameraPipeline(ctx, &pipeline)
+	sd.runDoorController(ctx, &pipeline)
+	return nil
+}
+
+// RunCameraPipeline runs only the capture and classify half of Run, for a
+// monitoring-only deployment: every cycle is sent to results instead of
+// being decided on, and the door is never commanded. Camera connectivity
+// and cycle stats are kept as in Run. It blocks until ctx is cancelled or
+// Stop is called, then closes results and the event subscriptions.
+func (sd *SmartDoor) RunCameraPipeline(ctx context.Context, results chan<- CycleResult) error {
+	ctx, finish, err := sd.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	sd.output = results
+
+	var pipeline sync.WaitGroup
+	sd.startCameraPipeline(ctx, &pipeline)
+	for {
+		select {
+		case <-ctx.Done():
+			pipeline.Wait()
+			close(results)
+			sd.closeSubscribers()
+			return nil
+		case event := <-sd.cameraEvents:
+			sd.handleCameraEvent(event.camera, event.event)
+		}
+	}
+}
+
+// RunDoorController runs only the door half of Run, deciding on the
+// cycles read from results rather than on its own cameras, for
+// deployments that detect by other means, such as a RunCameraPipeline
+// elsewhere. Once results is closed the door is held as last decided. It
+// shuts down as Run does.
+func (sd *SmartDoor) RunDoorController(ctx context.Context, results <-chan CycleResult) error {
+	ctx, finish, err := sd.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	sd.input = results
+
+	var pipeline sync.WaitGroup
+	sd.runDoorController(ctx, &pipeline)
+	return nil
+}
+
+// begin marks the SmartDoor running and emits EventStarted. It returns the
+// run context, which Stop cancels, and finish to call once stopped.
+func (sd *SmartDoor) begin(ctx context.Context) (context.Context, func(), error) {
+	sd.mu.Lock()
+	if sd.cancel != nil {
+		sd.mu.Unlock()
+		return nil, nil, ErrAlreadyRunning
+	}
+	if err := sd.resolveLabelsLocked(); err != nil {
+		sd.mu.Unlock()
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	sd.cancel = cancel
+	sd.done = done
+	sd.startedAt = sd.clock.Now()
+	sd.summary.start = sd.startedAt
+	sd.mu.Unlock()
+
+	sd.emitStarted()
+	return ctx, func() {
+		close(done)
+		cancel()
+	}, nil
+}
+
+// startCameraPipeline starts the camera event forwarders and the capture
+// loop in pipeline.
+func (sd *SmartDoor) startCameraPipeline(ctx context.Context, pipeline *sync.WaitGroup) {
+	for _, slot := range sd.cameras {
+		goRun(ctx, pipeline, sd.forwardCameraEvents(slot))
 	}
+	goRun(ctx, pipeline, sd.processCamera)
 }
 
-func (sd *SmartDoor) Run() {
-	// Start camera processing goroutine
-	go sd.processCamera()
+func goRun(ctx context.Context, group *sync.WaitGroup, run func(context.Context)) {
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		run(ctx)
+	}()
+}
+
+// runDoorController runs the decision loop, the executor and the main
+// event loop until ctx is done, then shuts down, waiting for pipeline as
+// well as its own goroutines.
+func (sd *SmartDoor) runDoorController(ctx context.Context, pipeline *sync.WaitGroup) {
+	start := func(run func(context.Context)) {
+		goRun(ctx, pipeline, run)
+	}
 
 	// Start door control goroutine
-	go sd.controlDoor()
+	start(sd.controlDoor)
+
+	if sd.currentConfig().HeartbeatInterval > 0 {
+		start(sd.heartbeat)
+	}
+	if sd.currentConfig().SummaryInterval > 0 {
+		start(sd.summarize)
+	}
+	start(sd.watchDoorState)
+	for _, slot := range sd.doors {
+		start(sd.forwardDoorEvents(slot))
+	}
+
+	// Start door action executor goroutine. It outlives ctx so it can drain
+	// the actions the controller decided before it stopped; its door calls
+	// are only cancelled once shutdown stops waiting for them.
+	executorCtx, stopExecutor := context.WithCancel(context.Background())
+	defer stopExecutor()
+	callCtx, abandonCalls := context.WithCancel(context.Background())
+	defer abandonCalls()
+	executorDone := make(chan struct{})
+	go func() {
+		defer close(executorDone)
+		var executors sync.WaitGroup
+		goRun(executorCtx, &executors, func(ctx context.Context) {
+			sd.executeDoors(ctx, callCtx)
+		})
+		sd.executeActions(executorCtx, callCtx)
+		executors.Wait()
+	}()
 
-	// Main event loop
+	// Main event loop. A closed door subscription is disabled, rather than
+	// read as a stream of zero events, until it is resubscribed.
+	doorEvents := sd.doorEvents
+	var resubscribeDoor <-chan time.Time
 	for {
+		switch event := sd.nextLoopEvent(ctx, doorEvents, resubscribeDoor); event.source {
+		case sourceShutdown:
+			sd.shutdown(pipeline, stopExecutor, abandonCalls, executorDone)
+			return
+		case sourceCamera:
+			sd.handleCameraEvent(event.camera.camera, event.camera.event)
+		case sourceDoor:
+			if event.closed {
+				sd.logger.Error("door event subscription closed")
+				sd.handleDoorEvent(DoorEventDisconnected)
+				doorEvents = nil
+				resubscribeDoor = sd.clock.After(resubscribeDelay)
+				continue
+			}
+			sd.handleDoorEvent(event.door)
+		case sourceDoors:
+			sd.handleSecondaryDoorEvent(event.secondary.door, event.secondary.event)
+		case sourceResubscribe:
+			resubscribeDoor = nil
+			doorEvents = sd.door.Subscribe()
+			sd.countResubscribe()
+		}
+	}
+}
+
+type loopSource int
+
+// The sources are declared in Config.OrderedDeviceEvents priority order.
+const (
+	sourceShutdown loopSource = iota
+	sourceDoor
+	sourceCamera
+	sourceDoors
+	sourceResubscribe
+)
+
+// loopEvent is one event taken by Run's main loop.
+type loopEvent struct {
+	source loopSource
+	camera cameraEvent
+	door   DeviceDoorEvent
+	// secondary is set for sourceDoors.
+	secondary doorEvent
+	// closed marks a sourceDoor event read from a closed subscription.
+	closed bool
+}
+
+// nextLoopEvent waits for the main loop's next event. With
+// Config.OrderedDeviceEvents, events that are already ready are taken in
+// priority order before falling back to waiting on all of them.
+func (sd *SmartDoor) nextLoopEvent(
+	ctx context.Context,
+	doorEvents <-chan DeviceDoorEvent,
+	resubscribeDoor <-chan time.Time,
+) loopEvent {
+	if sd.currentConfig().OrderedDeviceEvents {
+		select {
+		case <-ctx.Done():
+			return loopEvent{source: sourceShutdown}
+		default:
+		}
+		select {
+		case event, ok := <-doorEvents:
+			return loopEvent{source: sourceDoor, door: event, closed: !ok}
+		default:
+		}
 		select {
 		case event := <-sd.cameraEvents:
-			sd.handleCameraEvent(event)
-		case event := <-sd.doorEvents:
-			sd.handleDoorEvent(event)
+			return loopEvent{source: sourceCamera, camera: event}
+		default:
+		}
+		select {
+		case event := <-sd.doorSlotEvents:
+			return loopEvent{source: sourceDoors, secondary: event}
+		default:
 		}
 	}
+
+	select {
+	case <-ctx.Done():
+		return loopEvent{source: sourceShutdown}
+	case event := <-sd.cameraEvents:
+		return loopEvent{source: sourceCamera, camera: event}
+	case event, ok := <-doorEvents:
+		return loopEvent{source: sourceDoor, door: event, closed: !ok}
+	case event := <-sd.doorSlotEvents:
+		return loopEvent{source: sourceDoors, secondary: event}
+	case <-resubscribeDoor:
+		return loopEvent{source: sourceResubscribe}
+	}
 }
 
-func (sd *SmartDoor) processCamera() {
-	ticker := time.NewTicker(sd.config.MinimalRateCameraProcess)
-	defer ticker.Stop()
+// resubscribeDelay is how long a closed device subscription waits before
+// Subscribe is called again.
+const resubscribeDelay = time.Second
 
-	for range ticker.C {
-		frames, err := sd.camera.CaptureFrames()
-		if err != nil {
-			continue
+func (sd *SmartDoor) countResubscribe() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.stats.Resubscribes++
+}
+
+// sleep waits d on the injected clock, reporting false if ctx ended first.
+func (sd *SmartDoor) sleep(ctx context.Context, d time.Duration) bool {
+	timer := sd.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+// Stop cancels a running Run and waits for it to finish shutting down. It
+// returns ErrNotRunning if Run was never called; stopping an already
+// stopped SmartDoor is a no-op.
+func (sd *SmartDoor) Stop() error {
+	sd.mu.Lock()
+	cancel, done := sd.cancel, sd.done
+	sd.mu.Unlock()
+	if cancel == nil {
+		return ErrNotRunning
+	}
+	cancel()
+	<-done
+	return nil
+}
+
+// EvaluateOnce runs one cycle synchronously for callers that schedule
+// cycles themselves: it captures, classifies, decides under the same
+// cooldowns and policies as Run, and applies the resulting action before
+// returning. The actions are keyed by door ID, the door passed to
+// NewSmartDoor under "door0" and the others as given to WithDoor; doors
+// left alone are missing. It returns the cycle's error for a failed cycle,
+// or the doors' errors if applying an action failed. EvaluateOnce must not
+// be called while Run is running, nor concurrently with itself.
+func (sd *SmartDoor) EvaluateOnce(ctx context.Context) (Detection, map[string]DoorAction, error) {
+	sd.mu.Lock()
+	running := sd.cancel != nil && !sd.closed
+	sd.mu.Unlock()
+	if running {
+		return DetectionNone, nil, ErrAlreadyRunning
+	}
+
+	result := sd.runCycle(ctx)
+	decision := sd.handleCycle(ctx, result)
+	sd.logCycle(result, decision)
+	sd.recordTrainingData(result, decision)
+
+	var err error
+	if result.Outcome == OutcomeError {
+		err = result.Err
+	}
+	for _, action := range sd.actions.Drain() {
+		if doorErr := sd.executeAction(ctx, action); doorErr != nil {
+			err = errors.Join(err, doorErr)
 		}
+	}
+	if doorErr := sd.applyDoors(ctx); doorErr != nil {
+		err = errors.Join(err, doorErr)
+	}
+	return decision.Detection, decision.Doors, err
+}
 
-		classifications, err := sd.classifier.ClassifyFrames(frames)
-		if err != nil {
-			continue
+func (sd *SmartDoor) shutdown(
+	pipeline *sync.WaitGroup,
+	stopExecutor, abandonCalls func(),
+	executorDone <-chan struct{},
+) {
+	var timeout <-chan time.Time
+	if d := sd.currentConfig().ShutdownTimeout; d > 0 {
+		timeout = sd.clock.After(d)
+	}
+
+	pipelineDone := make(chan struct{})
+	go func() {
+		pipeline.Wait()
+		close(pipelineDone)
+	}()
+
+	select {
+	case <-pipelineDone:
+		stopExecutor()
+		select {
+		case <-executorDone:
+		case <-timeout:
+			abandonCalls()
+		}
+	case <-timeout:
+		stopExecutor()
+		abandonCalls()
+	}
+
+	sd.closeSubscribers()
+}
+
+func (sd *SmartDoor) processCamera(ctx context.Context) {
+	if pusher, ok := sd.camera.(FramePusher); ok && sd.currentConfig().CameraMode == CameraModePush {
+		sd.processPushedFrames(ctx, pusher.Frames())
+		return
+	}
+	sd.pollCamera(ctx)
+}
+
+// pollCamera starts a cycle every poll interval. The interval starts at
+// MinimalRateCameraProcess and adapts to backpressure: it doubles, up to
+// maxPollBackoff times the base rate, while cycles, including the wait for
+// the controller to take the result, overrun it, and halves back once they
+// fit in half of it.
+func (sd *SmartDoor) pollCamera(ctx context.Context) {
+	base := sd.currentConfig().MinimalRateCameraProcess
+	interval := base
+	wait := interval
+	sd.setPollInterval(interval)
+
+	for {
+		if wait > 0 {
+			timer := sd.clock.NewTimer(wait + sd.jitter(sd.currentConfig().PollJitter))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-sd.rateChanged:
+				timer.Stop()
+				base = sd.currentConfig().MinimalRateCameraProcess
+				interval = base
+				wait = interval
+				sd.setPollInterval(interval)
+				continue
+			case <-timer.C():
+			}
+		}
+
+		started := sd.clock.Now()
+		if !sd.publishResult(ctx, sd.runCycle(ctx)) {
+			return
 		}
+		elapsed := sd.clock.Now().Sub(started)
+
+		interval = adaptPollInterval(base, interval, elapsed)
+		sd.setPollInterval(interval)
+		wait = interval - elapsed
+	}
+}
 
-		sd.classificationCh <- classifications
+const maxPollBackoff = 8
+
+func adaptPollInterval(base, interval, elapsed time.Duration) time.Duration {
+	switch {
+	case elapsed > interval:
+		return min(interval*2, base*maxPollBackoff)
+	case elapsed <= interval/2:
+		return max(interval/2, base)
 	}
+	return interval
+}
+
+func (sd *SmartDoor) setPollInterval(interval time.Duration) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.stats.PollInterval = interval
 }
 
-func (sd *SmartDoor) controlDoor() {
-	var lastDetection Detection
-	var lastActionTime time.Time
+// Frames pushed sooner than MinimalRateCameraProcess after the last
+// classified batch are dropped rather than queued, so a burst of motion
+// events never backs up the classifier.
+func (sd *SmartDoor) processPushedFrames(ctx context.Context, frames <-chan []Frame) {
+	var lastProcessed time.Time
 
-	for classifications := range sd.classificationCh {
-		detection := sd.toDetection(classifications)
+	for {
+		var batch []Frame
+		select {
+		case <-ctx.Done():
+			return
+		case pushed, ok := <-frames:
+			if !ok {
+				return
+			}
+			batch = pushed
+		}
 
-		if detection == lastDetection {
+		now := sd.clock.Now()
+		if !lastProcessed.IsZero() && now.Sub(lastProcessed) < sd.currentConfig().MinimalRateCameraProcess {
+			sd.mu.Lock()
+			sd.stats.FramesThrottled++
+			sd.mu.Unlock()
 			continue
 		}
+		lastProcessed = now
+
+		if !sd.publishResult(ctx, sd.classifyCycle(ctx, batch, sd.cycleDeadline())) {
+			return
+		}
+	}
+}
+
+func (sd *SmartDoor) publishResult(ctx context.Context, result CycleResult) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case sd.output <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// cycleContext derives a context from the run context that also ends at
+// deadline, as told by the injected clock, with context.DeadlineExceeded as
+// its cause.
+func (sd *SmartDoor) cycleContext(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := sd.clock.NewTimer(deadline.Sub(sd.clock.Now()))
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func (sd *SmartDoor) cycleDeadline() time.Time {
+	timeout := sd.currentConfig().CycleTimeout
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return sd.clock.Now().Add(timeout)
+}
+
+// runCycle captures from every camera, classifies each camera's frames
+// with its assigned classifier and merges the results into one batch.
+func (sd *SmartDoor) runCycle(ctx context.Context) CycleResult {
+	deadline := sd.cycleDeadline()
+	if !sd.beginCycle() {
+		return CycleResult{Outcome: OutcomeError, Err: ErrBreakerOpen}
+	}
+	ctx, cancel := sd.cycleContext(ctx, deadline)
+	defer cancel()
 
-		now := time.Now()
-		if now.Sub(lastActionTime) < sd.config.MinimalDurationUnlocking {
+	results := make([]CycleResult, 0, len(sd.cameras))
+	for _, slot := range sd.cameras {
+		frames, err := sd.capture(ctx, slot)
+		if err != nil {
+			sd.mu.Lock()
+			sd.stats.CaptureFailures++
+			sd.mu.Unlock()
+			results = append(results, CycleResult{
+				Outcome: OutcomeError,
+				Err:     fmt.Errorf("capture from %s: %w", slot.id, err),
+			})
 			continue
 		}
+		results = append(results, sd.classifyFrames(ctx, slot, frames, deadline))
+	}
+
+	return sd.finishCycle(sd.settleMerged(mergeResults(results)))
+}
+
+// settleMerged turns a decided cycle in which some camera failed into a
+// failed one unless the cameras that worked detected something, so absence
+// is never inferred from a camera that failed: the door holds and no relock
+// starts, as on any failed cycle.
+func (sd *SmartDoor) settleMerged(merged CycleResult) CycleResult {
+	if merged.Outcome != OutcomeDecided || merged.Err == nil {
+		return merged
+	}
+	if detection, _ := sd.detect(merged); detection == DetectionNone {
+		merged.Outcome = OutcomeError
+	}
+	return merged
+}
+
+// classifyCycle classifies frames pushed by the primary camera.
+func (sd *SmartDoor) classifyCycle(
+	ctx context.Context,
+	frames []Frame,
+	deadline time.Time,
+) CycleResult {
+	if !sd.beginCycle() {
+		return CycleResult{Frames: frames, Outcome: OutcomeError, Err: ErrBreakerOpen}
+	}
+	ctx, cancel := sd.cycleContext(ctx, deadline)
+	defer cancel()
+	return sd.finishCycle(sd.classifyFrames(ctx, sd.cameras[0], frames, deadline))
+}
+
+// beginCycle counts the cycle and reports whether the classifier breaker
+// lets it run.
+func (sd *SmartDoor) beginCycle() bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.stats.Cycles++
+	if sd.clock.Now().Before(sd.breakerOpenUntil) {
+		sd.stats.BreakerSkipped++
+		return false
+	}
+	return true
+}
+
+func (sd *SmartDoor) classifyFrames(
+	ctx context.Context,
+	slot cameraSlot,
+	frames []Frame,
+	deadline time.Time,
+) CycleResult {
+	if len(frames) == 0 {
+		return CycleResult{Outcome: OutcomeNoSignal}
+	}
+	frames = sd.resizeFrames(sd.capFrames(sd.thinFrames(frames)))
+
+	classifications, err := sd.classifyWithRetry(ctx, slot.classifier, frames, deadline)
+	if frameErrs, ok := partialFrames(err); ok {
+		frames, classifications = sd.dropFailedFrames(frames, classifications, frameErrs)
+		err = nil
+	}
+	if err != nil {
+		// Running out of cycle time is a failure; Run stopping is not.
+		if !errors.Is(context.Cause(ctx), context.Canceled) {
+			sd.mu.Lock()
+			sd.stats.ClassifyFailures++
+			sd.mu.Unlock()
+		}
+		return CycleResult{
+			Frames:  frames,
+			Outcome: OutcomeError,
+			Err:     fmt.Errorf("classify frames from %s: %w", slot.id, err),
+		}
+	}
+
+	// Classifiers may reuse their output across calls, so the controller
+	// gets its own copy rather than a slice the next cycle can overwrite.
+	classifications = cloneClassifications(classifications)
+	sd.clampConfidences(classifications)
+
+	sd.mu.Lock()
+	sd.recordFramesLocked(sd.clock.Now(), len(frames))
+	sd.recordConfidencesLocked(classifications)
+	sd.mu.Unlock()
+
+	result := CycleResult{
+		Frames:          frames,
+		Classifications: classifications,
+		Cameras:         repeatCameraID(slot.id, len(classifications)),
+		Outcome:         OutcomeDecided,
+	}
+	if !hasAnyClassification(classifications) && sd.currentConfig().NoLabelsPolicy != EmptyResultNone {
+		result.Outcome = OutcomeNoSignal
+	}
+	return result
+}
 
-		switch detection {
-		case DetectionDog:
-			if lastDetection != DetectionDog {
-				sd.doorActionCh <- ActionUnlock
-				lastActionTime = now
+// thinFrames keeps the first frame and then each frame captured at least
+// Config.MinInterFrameInterval after the last one kept. Frames without a
+// CapturedAt are always kept.
+func (sd *SmartDoor) thinFrames(frames []Frame) []Frame {
+	interval := sd.currentConfig().MinInterFrameInterval
+	if interval <= 0 {
+		return frames
+	}
+	var kept []Frame
+	var last time.Time
+	for _, frame := range frames {
+		if !frame.CapturedAt.IsZero() {
+			if !last.IsZero() && frame.CapturedAt.Sub(last) < interval {
+				continue
 			}
-		case DetectionCat:
-			sd.doorActionCh <- ActionLock
-			lastActionTime = now
+			last = frame.CapturedAt
 		}
+		kept = append(kept, frame)
+	}
+	if thinned := len(frames) - len(kept); thinned > 0 {
+		sd.mu.Lock()
+		sd.stats.FramesThinned += thinned
+		sd.mu.Unlock()
+	}
+	return kept
+}
+
+// capFrames keeps the Config.MaxFramesPerCycle most recent frames, those
+// last in the batch.
+func (sd *SmartDoor) capFrames(frames []Frame) []Frame {
+	limit := sd.currentConfig().MaxFramesPerCycle
+	if limit <= 0 || len(frames) <= limit {
+		return frames
+	}
+	sd.mu.Lock()
+	sd.stats.FramesTruncated += len(frames) - limit
+	sd.mu.Unlock()
+	return frames[len(frames)-limit:]
+}
 
-		lastDetection = detection
+// dropFailedFrames keeps the frames, and their classifications, that
+// frameErrs does not mark as failed, counting the ones it drops.
+func (sd *SmartDoor) dropFailedFrames(
+	frames []Frame,
+	classifications [][]Classification,
+	frameErrs FrameErrors,
+) ([]Frame, [][]Classification) {
+	var keptFrames []Frame
+	var kept [][]Classification
+	failed := 0
+	for i := range classifications {
+		if i < len(frameErrs) && frameErrs[i] != nil {
+			failed++
+			continue
+		}
+		if i < len(frames) {
+			keptFrames = append(keptFrames, frames[i])
+		}
+		kept = append(kept, classifications[i])
+	}
+	for i := len(classifications); i < len(frameErrs); i++ {
+		if frameErrs[i] != nil {
+			failed++
+		}
 	}
+
+	sd.mu.Lock()
+	sd.stats.FrameFailures += failed
+	sd.mu.Unlock()
+	return keptFrames, kept
 }
 
-func (sd *SmartDoor) toDetection(classifications [][]Classification) Detection {
-	// Implementation similar to Rust version
-	// Returns DetectionCat, DetectionDog, or DetectionNone
-	return DetectionNone
+func (sd *SmartDoor) finishCycle(result CycleResult) CycleResult {
+	sd.publishTap(result)
+	if result.Outcome == OutcomeDecided && sd.detectStaleClassifier(result.Frames, result.Classifications) {
+		result.Outcome = OutcomeError
+		result.Err = ErrStaleClassifier
+	}
+	return result
 }
 
-func (sd *SmartDoor) handleCameraEvent(event DeviceCameraEvent) {
-	// Handle camera connection/disconnection
+func hasAnyClassification(classifications [][]Classification) bool {
+	for _, frame := range classifications {
+		if len(frame) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
-func (sd *SmartDoor) handleDoorEvent(event DeviceDoorEvent) {
-	// Handle door connection/disconnection
+// Retries stop early when the next attempt would start past the cycle
+// deadline or the context is cancelled, returning the last error. A
+// partial FrameErrors result is used as is rather than retried.
+func (sd *SmartDoor) classifyWithRetry(
+	ctx context.Context,
+	classifier ImageClassifier,
+	frames []Frame,
+	deadline time.Time,
+) ([][]Classification, error) {
+	classifications, err := classifier.ClassifyFrames(ctx, frames)
+	config := sd.currentConfig()
+	for attempt := 0; err != nil && attempt < config.ClassifyRetries; attempt++ {
+		if _, ok := partialFrames(err); ok {
+			break
+		}
+		delay := config.ClassifyRetryDelay
+		if !deadline.IsZero() && sd.clock.Now().Add(delay).After(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-sd.clock.After(delay):
+		}
+
+		sd.mu.Lock()
+		sd.stats.ClassifyRetries++
+		sd.mu.Unlock()
+
+		classifications, err = classifier.ClassifyFrames(ctx, frames)
+	}
+	return classifications, err
+}
+
+func (sd *SmartDoor) controlDoor(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, ok := <-sd.input:
+			if !ok {
+				return
+			}
+			decision := sd.handleCycle(ctx, result)
+			sd.logCycle(result, decision)
+			sd.recordTrainingData(result, decision)
+		}
+	}
+}
+
+// CycleResult is what the camera pipeline hands to the decision loop.
+type CycleResult struct {
+	Frames          []Frame
+	Classifications [][]Classification
+	// Cameras holds the ID of the camera behind each entry of
+	// Classifications.
+	Cameras []string
+	Outcome CycleOutcome
+	// Err describes what failed, set for OutcomeError and for a merged
+	// cycle in which some of the cameras failed.
+	Err error
+}
+
+func (r CycleResult) cameraOf(frame int) string {
+	if frame < len(r.Cameras) {
+		return r.Cameras[frame]
+	}
+	return defaultCameraID
+}
+
+// locate sets the camera and capture time of the frame behind trigger.
+func (r CycleResult) locate(trigger *Trigger) {
+	if trigger == nil {
+		return
+	}
+	trigger.Camera = r.cameraOf(trigger.Frame)
+	if trigger.Frame < len(r.Frames) {
+		trigger.CapturedAt = r.Frames[trigger.Frame].CapturedAt
+	}
+}
+
+type CycleOutcome int
+
+const (
+	// OutcomeDecided means the classifier produced labels to decide on,
+	// even if none of them match a list.
+	OutcomeDecided CycleOutcome = iota
+	// OutcomeNoSignal means there was nothing to classify, or the
+	// classifier returned no labels at all under the default
+	// Config.NoLabelsPolicy.
+	OutcomeNoSignal
+	// OutcomeError means capture or classification failed.
+	OutcomeError
+)
+
+// decision is the outcome of one pass of the decision loop.
+type decision struct {
+	Detection Detection
+	Action    DoorAction
+	Trigger   *Trigger
+	// Reason explains a decision without an Action.
+	Reason NoActionReason
+	// Doors holds the action of every door acting this cycle by door ID,
+	// the primary door's Action under defaultDoorID; see WithDoor.
+	Doors map[string]DoorAction
+}
+
+// Only a decided cycle can change the door. NoSignal and Error cycles hold
+// the current state, so a classifier outage is never mistaken for the dog
+// having left, though the unlock cap still applies.
+func (sd *SmartDoor) handleCycle(ctx context.Context, result CycleResult) decision {
+	sd.trace(TraceEntry{Kind: TraceKindCycle, Cycle: traceCycle(result)})
+	d := sd.evaluateCycle(ctx, result)
+	sd.updatePresence()
+	if result.Outcome == OutcomeDecided {
+		classifications := cloneClassifications(result.Classifications)
+		sd.mu.Lock()
+		sd.countDetectionsLocked(classifications)
+		sd.lastClassifications = classifications
+		if d.Trigger != nil && d.Trigger.Frame < len(result.Frames) {
+			sd.lastSnapshot = cloneFrames(result.Frames[d.Trigger.Frame : d.Trigger.Frame+1])[0]
+		}
+		sd.mu.Unlock()
+	}
+	if d.Action == ActionNone {
+		sd.reassert(sd.clock.Now())
+	}
+	sd.decideDoors(&d, result)
+	sd.recordReason(d)
+	return d
+}
+
+func (sd *SmartDoor) evaluateCycle(ctx context.Context, result CycleResult) decision {
+	sd.noteProfileSwitch()
+	sd.adoptManualUnlock(sd.clock.Now())
+	sd.rollBackCancelled()
+	if result.Outcome == OutcomeError {
+		if sd.outageSince.IsZero() {
+			sd.outageSince = sd.clock.Now()
+		}
+		if errors.Is(result.Err, ErrBreakerOpen) || errors.Is(result.Err, ErrStaleClassifier) {
+			sd.outageEngaged = true
+		}
+		sd.consecutiveErrors++
+		sd.mu.Lock()
+		sd.summary.errors++
+		sd.mu.Unlock()
+	} else {
+		sd.endOutage(result.Outcome, sd.clock.Now())
+		sd.consecutiveErrors = 0
+	}
+
+	if result.Outcome == OutcomeDecided {
+		return sd.handleDecided(ctx, result)
+	}
+
+	sd.mu.Lock()
+	sd.stats.HeldCycles++
+	sd.mu.Unlock()
+
+	now := sd.clock.Now()
+	sd.decayConfidences(now)
+	held := decision{Detection: sd.lastDetection, Reason: ReasonHeld}
+	if sd.heldByOverride(now) {
+		held.Reason = ReasonOverridden
+		return held
+	}
+	if !sd.Armed() {
+		held.Reason = ReasonDisarmed
+		return held
+	}
+	if !sd.enforceUnlockCap(&held, now) && !sd.enforceCameraLoss(&held, now) &&
+		!sd.enforceFailSafe(&held, now) && !sd.enforceHold(&held, now) {
+		sd.enforceDecay(&held, now)
+	}
+	return held
+}
+
+// handleUnknown keeps the door as it is while something unknown is there,
+// or under UnknownLock locks it regardless of cooldown. Either way the
+// activity counts as presence, so no relock timer runs.
+func (sd *SmartDoor) handleUnknown(d *decision, now time.Time, trigger *Trigger) {
+	sd.absentSince = time.Time{}
+	d.Reason = ReasonUnknown
+	if sd.currentConfig().UnknownPolicy != UnknownLock {
+		return
+	}
+	if sd.IntendedState() == DoorStateLocked {
+		d.Reason = ReasonUnchanged
+		return
+	}
+	cause := "unknown activity"
+	if trigger != nil {
+		cause += ": " + triggerCause(trigger)
+	}
+	if sd.decide(d, ActionLock, now, trigger, cause) {
+		sd.lastDetection = DetectionUnknown
+	}
+}
+
+// enforceDecay treats a gap of held cycles as absence once the confidence
+// behind an unlock has decayed below its threshold, so a dog that left
+// while frames were being dropped still gets the door relocked.
+func (sd *SmartDoor) enforceDecay(d *decision, now time.Time) {
+	if sd.currentConfig().ConfidenceDecayHalfLife <= 0 || sd.unlockedSince.IsZero() || sd.confidentlyPresent() {
+		return
+	}
+	sd.handleAbsence(d, now)
+}
+
+// enforceCameraLoss applies Config.CameraLossPolicy once the camera has
+// been disconnected for CameraLossGrace, once per disconnection. The last
+// detection is reset so the dog triggers the door afresh once the camera
+// is back.
+func (sd *SmartDoor) enforceCameraLoss(d *decision, now time.Time) bool {
+	config := sd.currentConfig()
+	action := map[CameraLossPolicy]DoorAction{CameraLossLock: ActionLock, CameraLossUnlock: ActionUnlock}[config.CameraLossPolicy]
+	if config.CameraLossGrace <= 0 || action == ActionNone {
+		return false
+	}
+	sd.mu.Lock()
+	camera := sd.connectivity.Camera
+	sd.mu.Unlock()
+	lost := now.Sub(camera.ChangedAt)
+	if camera.Connected || camera.ChangedAt.IsZero() || lost < config.CameraLossGrace ||
+		camera.ChangedAt.Equal(sd.cameraLostAt) {
+		return false
+	}
+	sd.cameraLostAt = camera.ChangedAt
+	if sd.IntendedState() == actionState(action) {
+		return false
+	}
+
+	if !sd.decide(d, action, now, nil, fmt.Sprintf("camera lost for %s", lost)) {
+		return false
+	}
+	sd.lastDetection = DetectionNone
+	sd.emit(Event{
+		Kind:    EventFailSafe,
+		Time:    now,
+		Action:  action,
+		Message: fmt.Sprintf("camera disconnected for %s", lost),
+	})
+	return true
+}
+
+// enforceFailSafe locks once Config.FailSafeAfterErrors consecutive cycles
+// have failed, bypassing the cooldown. The last detection is reset so the
+// dog triggers a fresh unlock once classification recovers.
+func (sd *SmartDoor) enforceFailSafe(d *decision, now time.Time) bool {
+	if limit := sd.currentConfig().FailSafeAfterErrors; limit <= 0 || sd.consecutiveErrors != limit {
+		return false
+	}
+	sd.outageEngaged = true
+
+	cause := fmt.Sprintf("fail-safe after %d classifier errors", sd.consecutiveErrors)
+	if !sd.decide(d, ActionLock, now, nil, cause) {
+		return false
+	}
+	sd.lastDetection = DetectionNone
+	sd.emit(Event{
+		Kind:    EventFailSafe,
+		Time:    now,
+		Action:  ActionLock,
+		Message: fmt.Sprintf("locked after %d consecutive failed cycles", sd.consecutiveErrors),
+	})
+	return true
+}
+
+func (sd *SmartDoor) handleClassifications(classifications [][]Classification) decision {
+	return sd.handleCycle(context.Background(), CycleResult{Classifications: classifications, Outcome: OutcomeDecided})
+}
+
+func (sd *SmartDoor) handleDecided(ctx context.Context, batch CycleResult) decision {
+	classifications := batch.Classifications
+	now := sd.clock.Now()
+	sd.trackConfidences(classifications, now)
+	result := decision{Detection: sd.lastDetection}
+	if sd.heldByOverride(now) {
+		result.Reason = ReasonOverridden
+		return result
+	}
+	if !sd.Armed() {
+		return sd.observeDisarmed(batch)
+	}
+	sd.observing = false
+	if !sd.enforceUnlockCap(&result, now) {
+		sd.enforceHold(&result, now)
+	}
+
+	if sd.currentConfig().SkipUnchangedClassifications && sd.hasPrevious &&
+		equalClassifications(classifications, sd.previous) {
+		sd.mu.Lock()
+		sd.stats.UnchangedSkipped++
+		sd.mu.Unlock()
+		result.Reason = ReasonDeduped
+		// An unchanged empty scene still counts towards the relock.
+		if sd.previousDetection == DetectionNone && !sd.unlockedSince.IsZero() && !sd.present(classifications) {
+			sd.handleAbsence(&result, now)
+		}
+		return result
+	}
+	sd.previous = cloneClassifications(classifications)
+	sd.hasPrevious = true
+
+	sd.mu.Lock()
+	sd.stats.Evaluations++
+	sd.mu.Unlock()
+
+	detection, trigger := sd.detect(batch)
+	batch.locate(trigger)
+	result.Detection = detection
+	result.Trigger = trigger
+	if detection == DetectionNone && hasAnyClassification(classifications) &&
+		sd.currentConfig().UnmatchedLabelsPolicy == EmptyResultHold {
+		sd.mu.Lock()
+		sd.stats.HeldCycles++
+		sd.mu.Unlock()
+		sd.previousDetection = DetectionHold
+		result.Detection = sd.lastDetection
+		result.Reason = ReasonHeld
+		return result
+	}
+	if detection != sd.previousDetection || sd.detectedSince.IsZero() {
+		sd.detectedSince = now
+	}
+	sd.previousDetection = detection
+	if detection != DetectionCat {
+		sd.catSince = time.Time{}
+	}
+
+	if detection == DetectionHold {
+		result.Reason = ReasonIgnored
+		return result
+	}
+	if detection == DetectionUnknown {
+		sd.handleUnknown(&result, now, trigger)
+		return result
+	}
+
+	if sd.unlockCapLatched {
+		if detection == DetectionDog {
+			result.Reason = ReasonCapLatched
+			return result
+		}
+		sd.unlockCapLatched = false
+	}
+
+	if detection == DetectionNone && !sd.unlockedSince.IsZero() {
+		if sd.present(classifications) {
+			sd.absentSince = time.Time{}
+			result.Detection = sd.lastDetection
+			result.Reason = ReasonUnchanged
+		} else {
+			sd.handleAbsence(&result, now)
+		}
+		return result
+	}
+	if detection == DetectionDog && sd.returnedWithinGrace(now) {
+		result.Detection = DetectionDog
+		result.Reason = ReasonUnchanged
+		return result
+	}
+	sd.absentSince = time.Time{}
+
+	if detection == DetectionNone {
+		result.Reason = sd.noMatchReason(classifications)
+	}
+	if detection == sd.lastDetection {
+		if result.Reason == ReasonNone {
+			result.Reason = ReasonUnchanged
+		}
+		return result
+	}
+
+	if trigger != nil && now.Sub(sd.detectedSince) < trigger.MinPresentDuration {
+		result.Reason = ReasonNotPresentLongEnough
+		return result
+	}
+	if detection == DetectionCat && sd.waitForDogToClear(&result, now, classifications) {
+		return result
+	}
+
+	// A lock-list detection locks at once, as the fail-safe, UnknownLock
+	// and unlock cap locks do; unlocks and the relock after the dog
+	// leaves wait for the cooldown.
+	safety := detection == DetectionCat
+	if !safety && sd.onCooldown(now, trigger) {
+		result.Reason = ReasonOnCooldown
+		return result
+	}
+
+	action := ActionNone
+	switch detection {
+	case DetectionDog:
+		action = ActionUnlock
+	case DetectionCat:
+		action = ActionLock
+	}
+	if action == ActionNone {
+		sd.lastDetection = detection
+		return result
+	}
+	if action == ActionUnlock && sd.stabilizing(now) {
+		// Leave lastDetection alone so the unlock follows once stable.
+		result.Reason = ReasonStabilizing
+		return result
+	}
+	if sd.currentConfig().ConfirmWithExtraCapture && !sd.confirmDetection(ctx, detection, trigger) {
+		// Leave lastDetection alone so the next cycle tries again.
+		result.Reason = ReasonNotConfirmed
+		return result
+	}
+	cause := triggerCause(trigger)
+	if sd.veto != nil {
+		if replaced, ok := sd.veto.Veto(ctx, detection, flatten(classifications)); ok {
+			sd.mu.Lock()
+			sd.stats.Vetoes++
+			sd.mu.Unlock()
+			if replaced == ActionNone {
+				// Leave lastDetection alone so the next cycle asks again.
+				result.Reason = ReasonVetoed
+				return result
+			}
+			if safety && replaced != ActionLock && sd.onCooldown(now, trigger) {
+				result.Reason = ReasonOnCooldown
+				return result
+			}
+			action = replaced
+			cause = "veto of " + cause
+		}
+	}
+	sd.decide(&result, action, now, trigger, cause)
+
+	sd.lastDetection = detection
+	return result
+}
+
+// reassert re-sends the intended action every Config.ReassertInterval, so
+// a door that drifted, say relocked by hand, is brought back in line. It
+// leaves the cooldown alone and is counted in Stats.Reasserts rather than as
+// a new action.
+func (sd *SmartDoor) reassert(now time.Time) {
+	interval := sd.currentConfig().ReassertInterval
+	if interval <= 0 || now.Sub(sd.lastAssertTime) < interval {
+		return
+	}
+	sd.mu.Lock()
+	intended := sd.intended
+	overridden := sd.override != nil
+	if intended == ActionNone || overridden || sd.disarmed {
+		sd.mu.Unlock()
+		return
+	}
+	sd.stats.Reasserts++
+	ac := sd.pendingContexts[intended]
+	sd.mu.Unlock()
+
+	sd.lastAssertTime = now
+	sd.actions.Enqueue(intended)
+	sd.emit(Event{Kind: EventAction, Time: now, Action: intended, Reassert: true, Context: &ac})
+}
+
+// IntendedState is the state the controller holds the door to, the single
+// source of truth reassertion sends. It changes only when an action is
+// decided, never with the momentary detection, so it carries through
+// classifier outages unchanged and detection picks it up again on recovery.
+// Precedence, highest first: a ForceLock or ForceUnlock; then the unlock
+// cap and fail-safe locks; then hold expiry; then detection and relock.
+// It is DoorStateUnknown until the first action.
+func (sd *SmartDoor) IntendedState() DoorState {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.intendedStateLocked()
+}
+
+// intendedStateLocked must be called with sd.mu held.
+func (sd *SmartDoor) intendedStateLocked() DoorState {
+	action := sd.intended
+	if sd.override != nil {
+		action = sd.override.action
+	}
+	return actionState(action)
+}
+
+func actionState(action DoorAction) DoorState {
+	switch action {
+	case ActionLock:
+		return DoorStateLocked
+	case ActionUnlock:
+		return DoorStateUnlocked
+	}
+	return DoorStateUnknown
+}
+
+// enforceHold reverses an action taken for a label with a HoldDuration
+// once the hold has elapsed, regardless of cooldown or what the camera
+// sees. Another action for the label before then restarts the hold; any
+// other action cancels it.
+func (sd *SmartDoor) enforceHold(d *decision, now time.Time) bool {
+	if sd.holdUntil.IsZero() || now.Before(sd.holdUntil) {
+		return false
+	}
+	reverse := ActionLock
+	if sd.unlockedSince.IsZero() {
+		reverse = ActionUnlock
+	}
+	return sd.decide(d, reverse, now, nil, fmt.Sprintf("hold of %s elapsed", sd.holdDuration))
+}
+
+// handleAbsence relocks an unlocked door once nothing has been detected
+// for Config.AbsenceDebounce, after which the dog counts as gone, plus
+// Config.RelockDelay. The dog showing up again in between restarts both,
+// and neither starts during the grace period after a manual unlock.
+func (sd *SmartDoor) handleAbsence(d *decision, now time.Time) {
+	d.Reason = ReasonRelockPending
+	if now.Before(sd.graceUntil) {
+		d.Detection = sd.lastDetection
+		return
+	}
+	if sd.absentSince.IsZero() {
+		sd.absentSince = now
+	}
+	config := sd.currentConfig()
+	absent := now.Sub(sd.absentSince)
+	if absent < config.AbsenceDebounce {
+		d.Detection = sd.lastDetection
+		return
+	}
+	sd.lastDetection = DetectionNone
+	d.Detection = DetectionNone
+
+	if absent < config.AbsenceDebounce+max(config.RelockDelay, config.ReturnGrace) {
+		return
+	}
+	if sd.onCooldown(now, nil) {
+		d.Reason = ReasonOnCooldown
+		return
+	}
+	sd.decide(d, ActionLock, now, nil, "no detection")
+}
+
+// returnedWithinGrace resumes the unlock of a dog that reappears within
+// Config.ReturnGrace of counting as gone, before the door has relocked.
+func (sd *SmartDoor) returnedWithinGrace(now time.Time) bool {
+	config := sd.currentConfig()
+	if config.ReturnGrace <= 0 || sd.unlockedSince.IsZero() || sd.absentSince.IsZero() || sd.lastDetection != DetectionNone {
+		return false
+	}
+	if now.Sub(sd.absentSince.Add(config.AbsenceDebounce)) >= config.ReturnGrace {
+		return false
+	}
+	sd.lastDetection = DetectionDog
+	sd.absentSince = time.Time{}
+	sd.mu.Lock()
+	sd.stats.ReturnsCoalesced++
+	sd.mu.Unlock()
+	return true
+}
+
+// stabilizing reports whether the door connected less than
+// Config.ConnectivityStabilization ago.
+func (sd *SmartDoor) stabilizing(now time.Time) bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	door := sd.connectivity.Door
+	return door.Connected && now.Sub(door.ChangedAt) < sd.effectiveConfigLocked().ConnectivityStabilization
+}
+
+// waitForDogToClear defers the lock a cat calls for while the dog that
+// unlocked the door is still in view, under CatArrivalWaitForDogToClear,
+// until Config.CatArrivalTimeout after the cat was first seen.
+func (sd *SmartDoor) waitForDogToClear(d *decision, now time.Time, classifications [][]Classification) bool {
+	config := sd.currentConfig()
+	if config.CatArrivalPolicy != CatArrivalWaitForDogToClear || sd.unlockedSince.IsZero() {
+		return false
+	}
+	dog := findMatch(classifications, sd.adaptList(config.ClassificationUnlockList), 0) != nil
+	if !dog && !sd.present(classifications) {
+		sd.catSince = time.Time{}
+		return false
+	}
+	if sd.catSince.IsZero() {
+		sd.catSince = now
+	}
+	if now.Sub(sd.catSince) >= config.CatArrivalTimeout {
+		sd.catSince = time.Time{}
+		return false
+	}
+	d.Reason = ReasonWaitingForDog
+	return true
+}
+
+// present reports whether a batch that triggered nothing still shows the
+// dog under Config.PresenceConfidence: any single frame with an unlock-list
+// label that confident, whatever the vote, margin or MinConfidence.
+func (sd *SmartDoor) present(classifications [][]Classification) bool {
+	config := sd.currentConfig()
+	if config.PresenceConfidence <= 0 {
+		return false
+	}
+	threshold := max(config.PresenceConfidence, config.GlobalMinConfidence)
+	for _, frame := range classifications {
+		for _, c := range frame {
+			for _, entry := range config.ClassificationUnlockList {
+				if matchesLabel(c, ClassificationConfig{Label: entry.Label, MinConfidence: threshold}) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// onCooldown reports whether the last action is too recent for another.
+// The cooldown only spans actions decided since the controller was last
+// (re)initialized: the first action after startup or UpdateConfig is never
+// suppressed, however recently an action was taken before it. A trigger
+// that outranks the last action only waits out Config.PreemptCooldown.
+func (sd *SmartDoor) onCooldown(now time.Time, trigger *Trigger) bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.cooldownRemainingLocked(now, triggerPriority(trigger) > sd.lastActionPriority) > 0
+}
+
+// CooldownRemaining returns how long until the cooldown allows another lock
+// or unlock, zero when one may be taken now. A trigger that outranks the
+// last action by priority may act sooner, after Config.PreemptCooldown.
+func (sd *SmartDoor) CooldownRemaining() time.Duration {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.cooldownRemainingLocked(sd.clock.Now(), false)
+}
+
+// cooldownRemainingLocked must be called with sd.mu held.
+func (sd *SmartDoor) cooldownRemainingLocked(now time.Time, preempt bool) time.Duration {
+	if sd.lastActionTime.IsZero() || !sd.lastActionTime.After(sd.cooldownResetAt) {
+		return 0
+	}
+
+	config := sd.effectiveConfigLocked()
+	cooldown := config.MinimalDurationUnlocking
+	if preempt {
+		cooldown = config.PreemptCooldown
+	}
+	return max(cooldown-now.Sub(sd.lastActionTime), 0)
+}
+
+// Once the door has been unlocked for MaxContinuousUnlock it is locked
+// regardless of cooldown, and stays locked until the dog detection clears
+// and triggers again.
+func (sd *SmartDoor) enforceUnlockCap(d *decision, now time.Time) bool {
+	limit := sd.currentConfig().MaxContinuousUnlock
+	if limit <= 0 || sd.unlockedSince.IsZero() {
+		return false
+	}
+	unlocked := now.Sub(sd.unlockedSince)
+	if unlocked < limit {
+		return false
+	}
+
+	cause := fmt.Sprintf("unlock cap after %s", unlocked)
+	if !sd.decide(d, ActionLock, now, nil, cause) {
+		return false
+	}
+	sd.unlockCapLatched = true
+	sd.emit(Event{
+		Kind:    EventUnlockCapReached,
+		Time:    now,
+		Action:  ActionLock,
+		Message: fmt.Sprintf("door unlocked for %s, forcing lock", unlocked),
+	})
+	return true
+}
+
+// decide makes action the cycle's decision and enqueues it, reporting
+// whether it did. A cycle yields at most one action: once a rule has
+// decided, later rules in the same cycle are ignored. The safety rules run
+// first, so a forced lock is never followed by a second action from the
+// same batch.
+func (sd *SmartDoor) decide(d *decision, action DoorAction, now time.Time, trigger *Trigger, cause string) bool {
+	if d.Action != ActionNone {
+		return false
+	}
+	d.Action = action
+	sd.mu.Lock()
+	sd.undo = decisionUndo{
+		action:             action,
+		intended:           sd.intended,
+		lastDetection:      sd.lastDetection,
+		lastActionTime:     sd.lastActionTime,
+		lastActionPriority: sd.lastActionPriority,
+		unlockedSince:      sd.unlockedSince,
+		holdUntil:          sd.holdUntil,
+		holdDuration:       sd.holdDuration,
+	}
+	ac := newActionContext(action, now, trigger, actionReason(action, cause))
+	sd.pendingContexts[action] = ac
+	sd.lastActionTime = now
+	sd.lastActionPriority = triggerPriority(trigger)
+	sd.intended = action
+	sd.mu.Unlock()
+	sd.enqueue(action)
+	sd.lastAssertTime = now
+	sd.absentSince = time.Time{}
+	sd.holdUntil = time.Time{}
+	if trigger != nil && trigger.HoldDuration > 0 {
+		sd.holdUntil = now.Add(trigger.HoldDuration)
+		sd.holdDuration = trigger.HoldDuration
+	}
+	if action == ActionUnlock {
+		sd.unlockedSince = now
+	} else {
+		sd.unlockedSince = time.Time{}
+	}
+	sd.emit(Event{Kind: EventAction, Time: now, Action: action, Trigger: trigger, Context: &ac})
+	return true
+}
+
+// enqueue hands a decided or forced action to the executor, unless it
+// would be more than Config.MaxConsecutiveSameAction of the same action in
+// a row. Such repeats should be harmless but point to a logic bug, so the
+// first one over the cap is logged and reported as an EventError rather
+// than sent to the door. Reasserts are not counted.
+func (sd *SmartDoor) enqueue(action DoorAction) {
+	sd.mu.Lock()
+	if action != sd.lastEnqueued {
+		sd.lastEnqueued = action
+		sd.sameActions = 0
+	}
+	sd.sameActions++
+	limit := sd.effectiveConfigLocked().MaxConsecutiveSameAction
+	capped := limit > 0 && sd.sameActions > limit
+	if capped {
+		sd.stats.ActionsCapped++
+	}
+	count := sd.sameActions
+	sd.mu.Unlock()
+
+	if !capped {
+		sd.actions.Enqueue(action)
+		return
+	}
+	if count == limit+1 {
+		msg := fmt.Sprintf("%s decided %d times in a row; not re-sending it to the door", action, count)
+		sd.logger.Error(msg)
+		sd.emit(Event{Kind: EventError, Action: action, Message: msg})
+	}
+}
+
+func triggerPriority(trigger *Trigger) int {
+	if trigger == nil {
+		return 0
+	}
+	return trigger.Priority
+}
+
+func equalClassifications(a, b [][]Classification) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func cloneClassifications(classifications [][]Classification) [][]Classification {
+	cloned := make([][]Classification, len(classifications))
+	for i, frame := range classifications {
+		cloned[i] = append([]Classification(nil), frame...)
+	}
+	return cloned
+}
+
+// clampConfidences pulls confidences into [0, 1], with NaN as 0, so models
+// that emit logits or overshoot compare sanely against MinConfidence. The
+// first out-of-range value is logged once.
+func (sd *SmartDoor) clampConfidences(classifications [][]Classification) {
+	for _, frame := range classifications {
+		for i, c := range frame {
+			if c.Confidence >= 0 && c.Confidence <= 1 {
+				continue
+			}
+			sd.confidenceRangeWarning.Do(func() {
+				sd.logger.Error(fmt.Sprintf("classifier returned %s confidence %v outside [0, 1]; clamping", c.Label, c.Confidence))
+			})
+			switch {
+			case c.Confidence > 1:
+				frame[i].Confidence = 1
+			default:
+				frame[i].Confidence = 0
+			}
+		}
+	}
+}
+
+// Actions still pending when ctx is cancelled are drained and applied
+// before returning, so a shutdown never leaves a decided action unapplied.
+func (sd *SmartDoor) executeActions(ctx, callCtx context.Context) {
+	for {
+		action, ok := sd.actions.Next(ctx)
+		if !ok {
+			break
+		}
+		if sd.confirmAction(ctx, action) {
+			sd.executeAction(callCtx, action)
+		}
+	}
+
+	for _, action := range sd.actions.Drain() {
+		sd.executeAction(callCtx, action)
+	}
+}
+
+func (sd *SmartDoor) executeAction(ctx context.Context, action DoorAction) error {
+	state := actionState(action)
+	if state == DoorStateUnknown {
+		return nil
+	}
+
+	sd.mu.Lock()
+	ac := sd.pendingContexts[action]
+	sd.inFlight = action
+	sd.mu.Unlock()
+
+	err := sd.callDoor(withActionContext(ctx, ac), action)
+	applied := sd.clock.Now()
+
+	sd.mu.Lock()
+	sd.inFlight = ActionNone
+	switch {
+	case errors.Is(err, ErrDoorCallTimeout):
+		sd.stats.DoorFailures++
+		sd.doorState = DoorStateUnknown
+		sd.stateReason = fmt.Sprintf("unknown: door %s timed out", action)
+	case err != nil:
+		sd.stats.DoorFailures++
+	default:
+		sd.doorState = state
+		sd.stateReason = ac.Reason
+		sd.countAppliedLocked(action, applied)
+	}
+	if err != nil {
+		sd.summary.errors++
+	}
+	sd.mu.Unlock()
+
+	if err != nil {
+		sd.logger.Error(fmt.Sprintf("door %s failed: %v", action, err))
+		event := Event{Kind: EventError, Action: action, Message: err.Error(), Context: &ac}
+		if errors.Is(err, ErrDoorCallTimeout) {
+			event.Severity = SeverityCritical
+		}
+		sd.emit(event)
+		return err
+	}
+	sd.observeLatency(ac, applied)
+	sd.emit(Event{Kind: EventActionApplied, Action: action, Context: &ac})
+	return nil
+}
+
+// callDoor runs action through the executor, bounded by
+// Config.DoorCallTimeout. A call that times out is abandoned rather than
+// waited for, so a wedged device cannot stall the executor; its context is
+// cancelled and its eventual result discarded.
+func (sd *SmartDoor) callDoor(ctx context.Context, action DoorAction) error {
+	return sd.callWithTimeout(ctx, action, sd.executor.Execute)
+}
+
+// callWithTimeout runs execute, giving up after Config.DoorCallTimeout.
+func (sd *SmartDoor) callWithTimeout(
+	ctx context.Context,
+	action DoorAction,
+	execute func(context.Context, DoorAction) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	timeout := sd.currentConfig().DoorCallTimeout
+	if timeout <= 0 {
+		return execute(ctx, action)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- execute(ctx, action)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-sd.clock.After(timeout):
+		return ErrDoorCallTimeout
+	}
+}
+
+// An ignore-list match wins over everything, then Config.ConflictPolicy
+// settles a batch matching both the lock and unlock lists. Labels match
+// case-insensitively by substring, as in the Rust core. The returned
+// trigger is the highest-confidence classification matching the winning
+// list, nil for DetectionNone.
+func (sd *SmartDoor) toDetection(classifications [][]Classification) (Detection, *Trigger) {
+	config := sd.currentConfig()
+	config.ClassificationUnlockList = sd.adaptList(config.ClassificationUnlockList)
+	config.ClassificationLockList = sd.adaptList(config.ClassificationLockList)
+	config.IgnoreList = sd.adaptList(config.IgnoreList)
+	if trigger := findMatch(classifications, config.IgnoreList, 0); trigger != nil {
+		return DetectionHold, trigger
+	}
+	var lock *Trigger
+	if !config.UnlockOnly {
+		lock = matchList(classifications, config.ClassificationLockList, config)
+	}
+	unlock := matchList(classifications, config.ClassificationUnlockList, config)
+	switch {
+	case lock != nil && unlock != nil && config.ConflictPolicy == ConflictPreferUnlock:
+		return DetectionDog, unlock
+	case lock != nil:
+		return DetectionCat, lock
+	case unlock != nil:
+		return DetectionDog, unlock
+	}
+	if trigger := findActivity(classifications, config.UnknownActivity); trigger != nil {
+		return DetectionUnknown, trigger
+	}
+	return DetectionNone, nil
+}
+
+// findActivity returns the most confident classification of the first
+// frame whose confidences sum to at least threshold, nil when none does or
+// threshold is not positive.
+func findActivity(classifications [][]Classification, threshold float64) *Trigger {
+	if threshold <= 0 {
+		return nil
+	}
+	for i, frame := range classifications {
+		total := 0.0
+		best := -1
+		for j, c := range frame {
+			total += c.Confidence
+			if best < 0 || c.Confidence > frame[best].Confidence {
+				best = j
+			}
+		}
+		if best >= 0 && total >= threshold {
+			return &Trigger{Classification: frame[best], Frame: i}
+		}
+	}
+	return nil
+}
+
+func matchList(classifications [][]Classification, list []ClassificationConfig, config Config) *Trigger {
+	trigger := findMatch(classifications, list, config.MinConfidenceMargin)
+	if trigger == nil || config.VoteThreshold <= 0 {
+		return trigger
+	}
+	if voteScore(classifications, list, config.VoteRecencyDecay, config.MinConfidenceMargin) < config.VoteThreshold {
+		return nil
+	}
+	return trigger
+}
+
+// findMatch returns the best classification matching list, preferring
+// priority over confidence. With a positive margin, a classification only
+// counts when it beats every other label in its frame by at least margin.
+func findMatch(classifications [][]Classification, list []ClassificationConfig, margin float64) *Trigger {
+	var trigger *Trigger
+	for i, frame := range classifications {
+		for _, c := range frame {
+			for _, config := range list {
+				if !matchesLabel(c, config) || !clearsMargin(frame, c, margin) {
+					continue
+				}
+				if trigger == nil || config.Priority > trigger.Priority ||
+					config.Priority == trigger.Priority && c.Confidence > trigger.Classification.Confidence {
+					trigger = &Trigger{
+						Classification:     c,
+						Frame:              i,
+						Priority:           config.Priority,
+						HoldDuration:       config.HoldDuration,
+						MinPresentDuration: config.MinPresentDuration,
+					}
+				}
+			}
+		}
+	}
+	return trigger
+}
+
+// Frames are ordered oldest first, so the newest frame has weight one and
+// each older frame is weighted by a further factor of decay.
+func voteScore(classifications [][]Classification, list []ClassificationConfig, decay, margin float64) float64 {
+	if decay <= 0 || decay > 1 {
+		decay = 1
+	}
+	var score float64
+	weight := 1.0
+	for i := len(classifications) - 1; i >= 0; i-- {
+		score += weight * bestMatch(classifications[i], list, margin)
+		weight *= decay
+	}
+	return score
+}
+
+// bestMatch returns the highest confidence in frame matching list, or zero.
+func bestMatch(frame []Classification, list []ClassificationConfig, margin float64) float64 {
+	var best float64
+	for _, c := range frame {
+		for _, config := range list {
+			if matchesLabel(c, config) && clearsMargin(frame, c, margin) && c.Confidence > best {
+				best = c.Confidence
+			}
+		}
+	}
+	return best
+}
+
+func clearsMargin(frame []Classification, c Classification, margin float64) bool {
+	if margin <= 0 {
+		return true
+	}
+	for _, other := range frame {
+		if !strings.EqualFold(other.Label, c.Label) && c.Confidence-other.Confidence < margin {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesLabel(c Classification, config ClassificationConfig) bool {
+	return labelMatches(c.Label, config.Label) && c.Confidence >= config.MinConfidence
+}
+
+// labelMatches reports whether a classifier's label falls under a list
+// entry's: it contains it, ignoring case.
+func labelMatches(label, entry string) bool {
+	return strings.Contains(strings.ToLower(label), strings.ToLower(entry))
+}
+
+// handleCameraEvent and handleDoorEvent record connectivity changes. An
+// event repeating the tracked state, as flaky drivers send, changes
+// nothing and is only logged at debug level.
+func (sd *SmartDoor) handleCameraEvent(camera string, event DeviceCameraEvent) {
+	sd.trace(TraceEntry{Kind: TraceKindCameraEvent, Camera: camera, CameraEvent: event})
+	connected := event == CameraEventConnected
+	sd.mu.Lock()
+	changed := sd.connectivity.setCamera(camera, connected, sd.clock.Now())
+	sd.mu.Unlock()
+	if !changed {
+		sd.debug(fmt.Sprintf("camera %s: ignoring repeated %s event", camera, connectedName(connected)))
+	}
+}
+
+func (sd *SmartDoor) handleDoorEvent(event DeviceDoorEvent) {
+	sd.trace(TraceEntry{Kind: TraceKindDoorEvent, DoorEvent: event})
+	connected := event == DoorEventConnected
+	sd.mu.Lock()
+	now := sd.clock.Now()
+	changed := sd.connectivity.Door.set(connected, now)
+	if changed {
+		sd.countDoorConnectivityLocked(connected, now)
+	}
+	sd.mu.Unlock()
+	if !changed {
+		sd.debug(fmt.Sprintf("door: ignoring repeated %s event", connectedName(connected)))
+	}
+}
+
+func connectedName(connected bool) string {
+	if connected {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// set records the state and reports whether it changed.
+func (c *DeviceConnectivity) set(connected bool, now time.Time) bool {
+	if c.Connected == connected && !c.ChangedAt.IsZero() {
+		return false
+	}
+	c.Connected = connected
+	c.ChangedAt = now
+	return true
 }
@@ -0,0 +1,1580 @@
+package smartdoor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBusy = errors.New("classifier busy")
+
+func dogBatch() [][]Classification {
+	return [][]Classification{{{Label: "dog", Confidence: 0.9}}}
+}
+
+func runCycleAsync(sd *SmartDoor, ctx context.Context) <-chan bool {
+	done := make(chan bool, 1)
+	go func() {
+		done <- sd.runCycle(ctx).Outcome == OutcomeDecided
+	}()
+	return done
+}
+
+func TestClassifyRetrySucceedsAfterTransientError(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{
+		{err: errBusy},
+		{classifications: dogBatch()},
+	}}
+	config := Config{ClassifyRetries: 2, ClassifyRetryDelay: 100 * time.Millisecond}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	done := runCycleAsync(sd, context.Background())
+	clock.BlockUntil(1)
+	clock.Advance(100 * time.Millisecond)
+
+	if ok := <-done; !ok {
+		t.Fatal("expected cycle to succeed after retry")
+	}
+	if calls := classifier.Calls(); calls != 2 {
+		t.Fatalf("expected 2 classify calls, got %d", calls)
+	}
+	stats := sd.Stats()
+	if stats.ClassifyRetries != 1 || stats.ClassifyFailures != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestClassifyRetryGivesUpAfterRetries(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{err: errBusy}}}
+	config := Config{ClassifyRetries: 2, ClassifyRetryDelay: 100 * time.Millisecond}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	done := runCycleAsync(sd, context.Background())
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	if ok := <-done; ok {
+		t.Fatal("expected cycle to fail")
+	}
+	if calls := classifier.Calls(); calls != 3 {
+		t.Fatalf("expected 3 classify calls, got %d", calls)
+	}
+	if failures := sd.Stats().ClassifyFailures; failures != 1 {
+		t.Fatalf("expected 1 failure, got %d", failures)
+	}
+}
+
+func TestClassifyRetryRespectsCycleDeadline(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{err: errBusy}}}
+	config := Config{
+		CycleTimeout:       150 * time.Millisecond,
+		ClassifyRetries:    5,
+		ClassifyRetryDelay: 100 * time.Millisecond,
+	}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	done := runCycleAsync(sd, context.Background())
+	clock.BlockUntil(2) // cycle deadline and retry delay
+	clock.Advance(100 * time.Millisecond)
+
+	if ok := <-done; ok {
+		t.Fatal("expected cycle to fail")
+	}
+	if calls := classifier.Calls(); calls != 2 {
+		t.Fatalf("expected 2 classify calls, got %d", calls)
+	}
+}
+
+func TestClassifyRetryStopsOnCancel(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{err: errBusy}}}
+	config := Config{ClassifyRetries: 3, ClassifyRetryDelay: time.Second}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runCycleAsync(sd, ctx)
+	clock.BlockUntil(1)
+	cancel()
+
+	if ok := <-done; ok {
+		t.Fatal("expected cycle to stop")
+	}
+	if calls := classifier.Calls(); calls != 1 {
+		t.Fatalf("expected 1 classify call, got %d", calls)
+	}
+	if failures := sd.Stats().ClassifyFailures; failures != 0 {
+		t.Fatalf("cancelled cycle should not count a failure, got %d", failures)
+	}
+}
+
+// blockingClassifier blocks until its context is done.
+type blockingClassifier struct {
+	started chan struct{}
+}
+
+func (c *blockingClassifier) ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
+
+func TestCycleTimeoutAbortsInFlightClassify(t *testing.T) {
+	classifier := &blockingClassifier{started: make(chan struct{}, 1)}
+	sd, _, _, clock := newTestSmartDoor(Config{CycleTimeout: time.Second}, classifier)
+
+	done := runCycleAsync(sd, context.Background())
+	<-classifier.started
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	if ok := <-done; ok {
+		t.Fatal("expected the timed out cycle to fail")
+	}
+	if failures := sd.Stats().ClassifyFailures; failures != 1 {
+		t.Fatalf("expected the timeout counted as a failure, got %d", failures)
+	}
+}
+
+func TestStopAbortsInFlightClassify(t *testing.T) {
+	classifier := &blockingClassifier{started: make(chan struct{}, 1)}
+	sd, _, _, _ := newTestSmartDoor(Config{}, classifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runCycleAsync(sd, ctx)
+	<-classifier.started
+	cancel()
+
+	if ok := <-done; ok {
+		t.Fatal("expected the cancelled cycle to fail")
+	}
+	if failures := sd.Stats().ClassifyFailures; failures != 0 {
+		t.Fatalf("cancelled cycle should not count a failure, got %d", failures)
+	}
+}
+
+func TestPushModeHonorsRateLimit(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	config := Config{CameraMode: CameraModePush, MinimalRateCameraProcess: time.Second}
+	camera := newFakePushCamera()
+	clock := newFakeClock()
+	sd := NewSmartDoor(config, camera, newFakeDoor(), classifier, WithClock(clock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.processCamera(ctx)
+
+	camera.pushed <- []Frame{{}}
+	<-sd.classificationCh
+
+	clock.Advance(500 * time.Millisecond)
+	camera.pushed <- []Frame{{}}
+	waitFor(t, func() bool { return sd.Stats().FramesThrottled == 1 })
+
+	clock.Advance(500 * time.Millisecond)
+	camera.pushed <- []Frame{{}}
+	<-sd.classificationCh
+
+	if calls := classifier.Calls(); calls != 2 {
+		t.Fatalf("expected 2 classify calls, got %d", calls)
+	}
+	if cycles := sd.Stats().Cycles; cycles != 2 {
+		t.Fatalf("expected 2 cycles, got %d", cycles)
+	}
+}
+
+func TestPushModeFallsBackToPollingWithoutPusher(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	config := Config{CameraMode: CameraModePush, MinimalRateCameraProcess: time.Second}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.processCamera(ctx)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-sd.classificationCh
+}
+
+func TestSkipUnchangedClassifications(t *testing.T) {
+	config := Config{SkipUnchangedClassifications: true, StartArmed: true}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+
+	batch := [][]Classification{{{Label: "tree", Confidence: 0.4}}}
+	sd.handleClassifications(batch)
+	sd.handleClassifications([][]Classification{{{Label: "tree", Confidence: 0.4}}})
+
+	stats := sd.Stats()
+	if stats.Evaluations != 1 || stats.UnchangedSkipped != 1 {
+		t.Fatalf("expected 1 evaluation and 1 skip, got %+v", stats)
+	}
+
+	sd.handleClassifications([][]Classification{{{Label: "tree", Confidence: 0.5}}})
+	if evaluations := sd.Stats().Evaluations; evaluations != 2 {
+		t.Fatalf("expected changed batch to be evaluated, got %d evaluations", evaluations)
+	}
+}
+
+func TestUnchangedClassificationsEvaluatedByDefault(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(Config{}, &fakeClassifier{})
+
+	batch := [][]Classification{{{Label: "tree", Confidence: 0.4}}}
+	sd.handleClassifications(batch)
+	sd.handleClassifications(batch)
+
+	if evaluations := sd.Stats().Evaluations; evaluations != 2 {
+		t.Fatalf("expected 2 evaluations, got %d", evaluations)
+	}
+}
+
+func TestDetectionEnqueuesDoorAction(t *testing.T) {
+	config := Config{
+		ClassificationUnlockList: []ClassificationConfig{{Label: "dog", MinConfidence: 0.5}},
+		ClassificationLockList:   []ClassificationConfig{{Label: "cat", MinConfidence: 0.5}},
+		StartArmed:               true,
+	}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+
+	sd.handleClassifications(dogBatch())
+	if got := sd.actions.Drain(); len(got) != 1 || got[0] != ActionUnlock {
+		t.Fatalf("expected unlock, got %v", got)
+	}
+
+	if detection, _ := sd.toDetection([][]Classification{{
+		{Label: "Golden Retriever dog", Confidence: 0.9},
+		{Label: "tabby cat", Confidence: 0.6},
+	}}); detection != DetectionCat {
+		t.Fatalf("expected lock list to win, got %v", detection)
+	}
+}
+
+func TestConnectivityFollowsDeviceEvents(t *testing.T) {
+	sd, camera, door, clock := newTestSmartDoor(Config{MinimalRateCameraProcess: time.Second}, &fakeClassifier{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+
+	connectedAt := clock.Now()
+	camera.events <- CameraEventConnected
+	door.events <- DoorEventConnected
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return c.Camera.Connected && c.Door.Connected
+	})
+	if c := sd.Connectivity(); !c.Camera.ChangedAt.Equal(connectedAt) {
+		t.Fatalf("expected camera change at %v, got %v", connectedAt, c.Camera.ChangedAt)
+	}
+
+	clock.Advance(time.Minute)
+	camera.events <- CameraEventDisconnected
+	waitFor(t, func() bool { return !sd.Connectivity().Camera.Connected })
+
+	c := sd.Connectivity()
+	if !c.Camera.ChangedAt.Equal(connectedAt.Add(time.Minute)) {
+		t.Fatalf("expected camera change at disconnect, got %v", c.Camera.ChangedAt)
+	}
+	if !c.Door.Connected || !c.Door.ChangedAt.Equal(connectedAt) {
+		t.Fatalf("door connectivity should be unchanged, got %+v", c.Door)
+	}
+}
+
+func TestInjectedDeviceEventsDriveConnectivity(t *testing.T) {
+	config := Config{MinimalRateCameraProcess: time.Second}
+	sd, camera, door, clock := newTestSmartDoor(config, &fakeClassifier{})
+	if err := sd.InjectCameraEvent(defaultCameraID, CameraEventConnected); !errors.Is(err, ErrInjectionDisabled) {
+		t.Fatalf("expected injection refused, got %v", err)
+	}
+	if err := sd.InjectDoorEvent(DoorEventConnected); !errors.Is(err, ErrInjectionDisabled) {
+		t.Fatalf("expected injection refused, got %v", err)
+	}
+	if c := sd.Connectivity(); !c.Camera.ChangedAt.IsZero() || !c.Door.ChangedAt.IsZero() {
+		t.Fatalf("expected refused events to change nothing, got %+v", c)
+	}
+
+	config.AllowEventInjection = true
+	sd, camera, door, clock = newTestSmartDoor(config, &fakeClassifier{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+
+	connectedAt := clock.Now()
+	camera.events <- CameraEventConnected
+	door.events <- DoorEventConnected
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return c.Camera.Connected && c.Door.Connected
+	})
+
+	clock.Advance(time.Minute)
+	if err := sd.InjectCameraEvent(defaultCameraID, CameraEventDisconnected); err != nil {
+		t.Fatal(err)
+	}
+	if err := sd.InjectDoorEvent(DoorEventDisconnected); err != nil {
+		t.Fatal(err)
+	}
+	c := sd.Connectivity()
+	for name, device := range map[string]DeviceConnectivity{
+		"camera": c.Camera, "cam0": c.Cameras[defaultCameraID], "door": c.Door,
+	} {
+		if device.Connected || !device.ChangedAt.Equal(connectedAt.Add(time.Minute)) {
+			t.Fatalf("%s: expected the injected disconnect, got %+v", name, device)
+		}
+	}
+	if err := sd.InjectCameraEvent("garage", CameraEventDisconnected); err == nil {
+		t.Fatal("expected an unknown camera refused")
+	}
+
+	// The devices' own events take over again.
+	camera.events <- CameraEventConnected
+	door.events <- DoorEventConnected
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return c.Camera.Connected && c.Door.Connected
+	})
+}
+
+func dogDoorConfig() Config {
+	return Config{
+		ClassificationUnlockList: []ClassificationConfig{{Label: "dog", MinConfidence: 0.5}},
+		ClassificationLockList:   []ClassificationConfig{{Label: "cat", MinConfidence: 0.5}},
+		StartArmed:               true,
+	}
+}
+
+func noneBatch() [][]Classification {
+	return [][]Classification{{{Label: "tree", Confidence: 0.9}}}
+}
+
+func expectActions(t *testing.T, sd *SmartDoor, want ...DoorAction) {
+	t.Helper()
+	got := sd.actions.Drain()
+	if len(got) != len(want) {
+		t.Fatalf("expected actions %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected actions %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMaxContinuousUnlockForcesLock(t *testing.T) {
+	config := dogDoorConfig()
+	config.MaxContinuousUnlock = 5 * time.Minute
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(2 * time.Minute)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(3 * time.Minute)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionLock)
+
+	var warned bool
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventUnlockCapReached {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Fatal("expected an unlock cap event")
+	}
+
+	clock.Advance(time.Minute)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	sd.handleClassifications(noneBatch())
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+}
+
+func TestAtMostOneActionPerBatch(t *testing.T) {
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	config := dogDoorConfig()
+	config.MaxContinuousUnlock = time.Minute
+	config.FailSafeAfterErrors = 1
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+
+	countActions := func() int {
+		n := 0
+		for len(events) > 0 {
+			if e := <-events; e.Kind == EventAction {
+				n++
+			}
+		}
+		return n
+	}
+
+	steps := []struct {
+		result CycleResult
+		want   DoorAction
+	}{
+		{CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}, ActionUnlock},
+		// The unlock cap and the cat both call for a lock.
+		{CycleResult{Classifications: cat, Outcome: OutcomeDecided}, ActionLock},
+		{CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}, ActionUnlock},
+		// The unlock cap and the fail-safe both call for a lock.
+		{CycleResult{Outcome: OutcomeError}, ActionLock},
+		{CycleResult{Classifications: noneBatch(), Outcome: OutcomeDecided}, ActionNone},
+		{CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}, ActionUnlock},
+		// The unlock cap and the relock on absence both call for a lock.
+		{CycleResult{Classifications: noneBatch(), Outcome: OutcomeDecided}, ActionLock},
+	}
+	for i, step := range steps {
+		clock.Advance(time.Minute)
+		d := sd.handleCycle(context.Background(), step.result)
+		if d.Action != step.want {
+			t.Fatalf("step %d: expected %v, got %v", i, step.want, d.Action)
+		}
+		if n := countActions(); n > 1 {
+			t.Fatalf("step %d: expected at most one action event, got %d", i, n)
+		}
+		if got := len(sd.actions.Drain()); got > 1 {
+			t.Fatalf("step %d: expected at most one queued action, got %d", i, got)
+		}
+	}
+}
+
+func TestWeightedVote(t *testing.T) {
+	dog := func(confidence float64) []Classification {
+		return []Classification{{Label: "dog", Confidence: confidence}}
+	}
+	tests := []struct {
+		name            string
+		decay           float64
+		classifications [][]Classification
+		want            Detection
+	}{
+		{"single confident frame is not enough", 1, [][]Classification{dog(0.95), {}, {}}, DetectionNone},
+		{"several confident frames pass", 1, [][]Classification{dog(0.9), dog(0.8), dog(0.7)}, DetectionDog},
+		{"below min confidence frames do not count", 1, [][]Classification{dog(0.4), dog(0.45), dog(0.95)}, DetectionNone},
+		{"fresh frames outweigh stale ones", 0.5, [][]Classification{{}, dog(0.9), dog(0.9)}, DetectionDog},
+		{"stale frames fade", 0.5, [][]Classification{dog(0.9), dog(0.9), {}}, DetectionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := dogDoorConfig()
+			config.VoteThreshold = 1.2
+			config.VoteRecencyDecay = tt.decay
+			sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+
+			if got, _ := sd.toDetection(tt.classifications); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIgnoreListFreezesDoorState(t *testing.T) {
+	config := dogDoorConfig()
+	config.IgnoreList = []ClassificationConfig{{Label: "person", MinConfidence: 0.6}}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	person := []Classification{{Label: "person", Confidence: 0.8}}
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	sd.handleClassifications([][]Classification{person, {{Label: "cat", Confidence: 0.9}}})
+	expectActions(t, sd)
+	if sd.lastDetection != DetectionDog {
+		t.Fatalf("ignore match should not change the last detection, got %v", sd.lastDetection)
+	}
+
+	sd.handleClassifications([][]Classification{{{Label: "person", Confidence: 0.5}}, {{Label: "cat", Confidence: 0.9}}})
+	expectActions(t, sd, ActionLock)
+}
+
+func TestActionEventCarriesTrigger(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	events := sd.Events()
+
+	sd.handleClassifications([][]Classification{
+		{{Label: "dog", Confidence: 0.7}},
+		{{Label: "tree", Confidence: 0.99}, {Label: "dog", Confidence: 0.94}},
+	})
+
+	e := <-events
+	if e.Kind != EventAction || e.Action != ActionUnlock {
+		t.Fatalf("expected unlock action event, got %+v", e)
+	}
+	want := Trigger{
+		Classification: Classification{Label: "dog", Confidence: 0.94},
+		Camera:         defaultCameraID,
+		Frame:          1,
+	}
+	if e.Trigger == nil || *e.Trigger != want {
+		t.Fatalf("expected trigger %+v, got %+v", want, e.Trigger)
+	}
+}
+
+func TestStopCompletesPendingAction(t *testing.T) {
+	door := &blockingDoor{
+		fakeDoor: newFakeDoor(),
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	config := Config{MinimalRateCameraProcess: time.Second}
+	sd := NewSmartDoor(config, newFakeCamera(), door, &fakeClassifier{}, WithClock(newFakeClock()))
+	events := sd.Events()
+
+	running := make(chan struct{})
+	go func() {
+		sd.Run(context.Background())
+		close(running)
+	}()
+
+	sd.actions.Enqueue(ActionUnlock)
+	<-door.started
+	sd.actions.Enqueue(ActionLock)
+
+	stopped := make(chan struct{})
+	go func() {
+		sd.Stop()
+		close(stopped)
+	}()
+	close(door.release)
+	<-door.started
+	<-stopped
+	<-running
+
+	if got := door.Actions(); len(got) != 2 || got[1] != ActionLock {
+		t.Fatalf("expected pending lock to complete, got %v", got)
+	}
+
+	var applied []DoorAction
+	for e := range events {
+		if e.Kind == EventActionApplied {
+			applied = append(applied, e.Action)
+		}
+	}
+	if len(applied) != 2 || applied[1] != ActionLock {
+		t.Fatalf("expected final locked event before close, got %v", applied)
+	}
+}
+
+func TestStopForceClosesAfterShutdownTimeout(t *testing.T) {
+	door := &blockingDoor{
+		fakeDoor: newFakeDoor(),
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	defer close(door.release)
+	config := Config{CameraMode: CameraModePush, ShutdownTimeout: 5 * time.Second}
+	clock := newFakeClock()
+	sd := NewSmartDoor(config, newFakePushCamera(), door, &fakeClassifier{}, WithClock(clock))
+	events := sd.Events()
+
+	go sd.Run(context.Background())
+	sd.actions.Enqueue(ActionLock)
+	<-door.started
+
+	stopped := make(chan struct{})
+	go func() {
+		sd.Stop()
+		close(stopped)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	<-stopped
+
+	for range events {
+	}
+	if got := door.Actions(); len(got) != 0 {
+		t.Fatalf("blocked lock should not have completed, got %v", got)
+	}
+}
+
+func TestCycleOutcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		camera     func(*fakeCamera)
+		classifier *fakeClassifier
+		want       CycleOutcome
+	}{
+		{"decided on unmatched labels", nil, &fakeClassifier{results: []fakeResult{{classifications: noneBatch()}}}, OutcomeDecided},
+		{"no frames", func(c *fakeCamera) { c.frames = nil }, &fakeClassifier{}, OutcomeNoSignal},
+		{"no labels", nil, &fakeClassifier{results: []fakeResult{{classifications: [][]Classification{{}}}}}, OutcomeNoSignal},
+		{"capture error", func(c *fakeCamera) { c.err = errBusy }, &fakeClassifier{}, OutcomeError},
+		{"classify error", nil, &fakeClassifier{results: []fakeResult{{err: errBusy}}}, OutcomeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd, camera, _, _ := newTestSmartDoor(dogDoorConfig(), tt.classifier)
+			if tt.camera != nil {
+				tt.camera(camera)
+			}
+			if got := sd.runCycle(context.Background()).Outcome; got != tt.want {
+				t.Fatalf("expected outcome %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOnlyDecidedNoneRelocks(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+
+	sd.handleCycle(context.Background(), CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided})
+	expectActions(t, sd, ActionUnlock)
+
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	sd.handleCycle(context.Background(), CycleResult{Classifications: [][]Classification{{}}, Outcome: OutcomeNoSignal})
+	expectActions(t, sd)
+	if held := sd.Stats().HeldCycles; held != 2 {
+		t.Fatalf("expected 2 held cycles, got %d", held)
+	}
+
+	sd.handleCycle(context.Background(), CycleResult{Classifications: noneBatch(), Outcome: OutcomeDecided})
+	expectActions(t, sd, ActionLock)
+}
+
+func TestRunTwiceReturnsErrAlreadyRunning(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(Config{MinimalRateCameraProcess: time.Second}, &fakeClassifier{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+	clock.BlockUntil(1)
+
+	if err := sd.Run(ctx); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning, got %v", err)
+	}
+
+	if err := sd.Stop(); err != nil {
+		t.Fatalf("expected clean stop, got %v", err)
+	}
+	if err := sd.Run(ctx); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected a stopped SmartDoor not to restart, got %v", err)
+	}
+	if err := sd.Stop(); err != nil {
+		t.Fatalf("expected second stop to be a no-op, got %v", err)
+	}
+}
+
+func TestStopBeforeRunReturnsErrNotRunning(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(Config{}, &fakeClassifier{})
+
+	if err := sd.Stop(); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestFramesRouteToPerCameraClassifier(t *testing.T) {
+	front := &fakeClassifier{results: []fakeResult{{classifications: noneBatch()}}}
+	back := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	fallback := &fakeClassifier{results: []fakeResult{{classifications: noneBatch()}}}
+	backCamera := newFakeCamera()
+	sideCamera := newFakeCamera()
+
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), fallback,
+		WithCameraClassifier(defaultCameraID, front),
+		WithCamera("back", backCamera, back),
+		WithCamera("side", sideCamera, nil),
+	)
+
+	result := sd.runCycle(context.Background())
+	if result.Outcome != OutcomeDecided {
+		t.Fatalf("expected merged cycle to be decided, got %v", result.Outcome)
+	}
+	if front.Calls() != 1 || back.Calls() != 1 || fallback.Calls() != 1 {
+		t.Fatalf("expected one call per classifier, got front=%d back=%d fallback=%d",
+			front.Calls(), back.Calls(), fallback.Calls())
+	}
+
+	decision := sd.handleCycle(context.Background(), result)
+	if decision.Action != ActionUnlock || decision.Trigger == nil || decision.Trigger.Camera != "back" {
+		t.Fatalf("expected unlock triggered by back camera, got %+v", decision)
+	}
+}
+
+func TestFailedCameraHoldsAbsence(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		indoor  [][]Classification
+		outcome CycleOutcome
+		action  DoorAction
+	}{
+		{"absence holds", noneBatch(), OutcomeError, ActionNone},
+		{"a detection still acts", [][]Classification{{{Label: "cat", Confidence: 0.9}}}, OutcomeDecided, ActionLock},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			outdoor := newFakeCamera()
+			outdoor.err = errBusy
+			indoor := &fakeClassifier{results: []fakeResult{{classifications: tt.indoor}}}
+			sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), indoor, WithCamera("outdoor", outdoor, &fakeClassifier{}))
+			sd.handleClassifications(dogBatch())
+			expectActions(t, sd, ActionUnlock)
+
+			clock.Advance(time.Minute)
+			result := sd.runCycle(context.Background())
+			if result.Outcome != tt.outcome || result.Err == nil {
+				t.Fatalf("expected outcome %v with the camera error, got %v (%v)", tt.outcome, result.Outcome, result.Err)
+			}
+			if d := sd.handleCycle(context.Background(), result); d.Action != tt.action {
+				t.Fatalf("expected action %v, got %+v", tt.action, d)
+			}
+		})
+	}
+}
+
+// reusingClassifier returns the same backing slice on every call,
+// overwriting it in place, as a pooled model output might.
+type reusingClassifier struct {
+	out   [][]Classification
+	calls int
+}
+
+func (c *reusingClassifier) ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error) {
+	c.calls++
+	label := "dog"
+	if c.calls%2 == 0 {
+		label = "cat"
+	}
+	c.out[0][0] = Classification{Label: label, Confidence: 0.9}
+	return c.out, nil
+}
+
+func TestControllerOwnsReusedClassifierOutput(t *testing.T) {
+	classifier := &reusingClassifier{out: [][]Classification{make([]Classification, 1)}}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), classifier)
+
+	first := sd.runCycle(context.Background())
+	second := sd.runCycle(context.Background())
+	if first.Classifications[0][0].Label != "dog" || second.Classifications[0][0].Label != "cat" {
+		t.Fatalf("expected each cycle to keep its own result, got %v then %v",
+			first.Classifications, second.Classifications)
+	}
+
+	// Under -race, the controller reading a batch the classifier is
+	// overwriting for the next cycle would be reported here.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sd.controlDoor(ctx)
+		close(done)
+	}()
+	for i := 0; i < 50; i++ {
+		if !sd.publishResult(ctx, sd.runCycle(ctx)) {
+			t.Fatal("controller stopped early")
+		}
+	}
+	cancel()
+	<-done
+
+	if got := sd.Stats().Evaluations; got != 50 {
+		t.Fatalf("expected 50 evaluations, got %d", got)
+	}
+}
+
+func TestMinConfidenceMargin(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinConfidenceMargin = 0.1
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+
+	ambiguous := [][]Classification{{{Label: "dog", Confidence: 0.55}, {Label: "cat", Confidence: 0.52}}}
+	if d := sd.handleClassifications(ambiguous); d.Detection != DetectionNone || d.Action != ActionNone {
+		t.Fatalf("expected a low-margin frame to be ignored, got %+v", d)
+	}
+
+	clear := [][]Classification{{{Label: "dog", Confidence: 0.8}, {Label: "cat", Confidence: 0.52}}}
+	if d := sd.handleClassifications(clear); d.Detection != DetectionDog || d.Action != ActionUnlock {
+		t.Fatalf("expected a high-margin dog to unlock, got %+v", d)
+	}
+}
+
+func TestFramesPerSecondOverSlidingWindow(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: [][]Classification{
+		{{Label: "tree", Confidence: 0.9}}, {{Label: "tree", Confidence: 0.9}},
+	}}}}
+	sd, camera, _, clock := newTestSmartDoor(dogDoorConfig(), classifier)
+	camera.frames = []Frame{{}, {}}
+
+	for i := 0; i < 40; i++ {
+		clock.Advance(500 * time.Millisecond)
+		sd.runCycle(context.Background())
+	}
+	if got := sd.Stats().FramesPerSecond; got != 4 {
+		t.Fatalf("expected 4 frames per second, got %v", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	if got := sd.Stats().FramesPerSecond; got != 2 {
+		t.Fatalf("expected the rate to fall as cycles stop, got %v", got)
+	}
+	clock.Advance(5 * time.Second)
+	if got := sd.Stats().FramesPerSecond; got != 0 {
+		t.Fatalf("expected no throughput after a quiet window, got %v", got)
+	}
+}
+
+// slowClassifier advances the fake clock by delay on every call to model
+// classification that takes that long.
+type slowClassifier struct {
+	clock *fakeClock
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func (c *slowClassifier) ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error) {
+	c.mu.Lock()
+	delay := c.delay
+	c.mu.Unlock()
+	c.clock.Advance(delay)
+	return noneBatch(), nil
+}
+
+func (c *slowClassifier) setDelay(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delay = delay
+}
+
+func TestSlowClassificationWidensPollInterval(t *testing.T) {
+	classifier := &slowClassifier{delay: 3 * time.Second}
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+	classifier.clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.pollCamera(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sd.classificationCh:
+			}
+		}
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return sd.Stats().PollInterval == 4*time.Second })
+
+	classifier.setDelay(0)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return sd.Stats().PollInterval == 2*time.Second })
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	waitFor(t, func() bool { return sd.Stats().PollInterval == time.Second })
+}
+
+func TestSetCaptureRateTakesEffectOnNextTick(t *testing.T) {
+	sd, camera, _, clock := newTestSmartDoor(Config{MinimalRateCameraProcess: time.Second}, &fakeClassifier{})
+	captures := func() int {
+		camera.mu.Lock()
+		defer camera.mu.Unlock()
+		return camera.captures
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.pollCamera(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sd.classificationCh:
+			}
+		}
+	}()
+
+	if err := sd.SetCaptureRate(0); err == nil {
+		t.Fatal("expected a non-positive rate to be rejected")
+	}
+	clock.BlockUntil(1)
+	if err := sd.SetCaptureRate(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return sd.Stats().PollInterval == 5*time.Second })
+	if got := sd.EffectiveConfig().MinimalRateCameraProcess; got != 5*time.Second {
+		t.Fatalf("expected the config to carry the new rate, got %s", got)
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(4 * time.Second)
+	if got := captures(); got != 0 {
+		t.Fatalf("expected no cycle before the new rate, got %d", got)
+	}
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return captures() == 1 })
+
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	waitFor(t, func() bool { return captures() == 2 })
+}
+
+// ctxBlockingCamera blocks in CaptureFrames until its context is done.
+type ctxBlockingCamera struct {
+	*fakeCamera
+	started chan struct{}
+}
+
+func (c *ctxBlockingCamera) CaptureFrames(ctx context.Context) ([]Frame, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// ctxBlockingDoor blocks in Lock and Unlock until its context is done and
+// records the error it returned.
+type ctxBlockingDoor struct {
+	*fakeDoor
+	started chan struct{}
+	errs    chan error
+}
+
+func (d *ctxBlockingDoor) Lock(ctx context.Context) error {
+	d.started <- struct{}{}
+	<-ctx.Done()
+	d.errs <- ctx.Err()
+	return ctx.Err()
+}
+
+func (d *ctxBlockingDoor) Unlock(ctx context.Context) error {
+	return d.Lock(ctx)
+}
+
+func TestCancelAbortsBlockedCapture(t *testing.T) {
+	camera := &ctxBlockingCamera{fakeCamera: newFakeCamera(), started: make(chan struct{}, 1)}
+	sd := NewSmartDoor(dogDoorConfig(), camera, newFakeDoor(), &fakeClassifier{}, WithClock(newFakeClock()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runCycleAsync(sd, ctx)
+	<-camera.started
+	cancel()
+
+	if ok := <-done; ok {
+		t.Fatal("expected the cancelled capture to fail the cycle")
+	}
+}
+
+func TestShutdownTimeoutAbortsBlockedDoorCall(t *testing.T) {
+	door := &ctxBlockingDoor{fakeDoor: newFakeDoor(), started: make(chan struct{}, 1), errs: make(chan error, 1)}
+	config := Config{CameraMode: CameraModePush, ShutdownTimeout: 5 * time.Second}
+	clock := newFakeClock()
+	sd := NewSmartDoor(config, newFakePushCamera(), door, &fakeClassifier{}, WithClock(clock))
+
+	go sd.Run(context.Background())
+	sd.actions.Enqueue(ActionLock)
+	<-door.started
+
+	stopped := make(chan struct{})
+	go func() {
+		sd.Stop()
+		close(stopped)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	<-stopped
+
+	if err := <-door.errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the blocked lock to be cancelled, got %v", err)
+	}
+}
+
+func TestMultiCameraPolicies(t *testing.T) {
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	tests := []struct {
+		name    string
+		policy  MultiCameraPolicy
+		batches [3][][]Classification
+		want    Detection
+	}{
+		{"any unlocks on one dog", MultiCameraAny, [3][][]Classification{dogBatch(), noneBatch(), noneBatch()}, DetectionDog},
+		{"any locks on one cat", MultiCameraAny, [3][][]Classification{dogBatch(), dogBatch(), cat}, DetectionCat},
+		{"majority ignores one dog", MultiCameraMajority, [3][][]Classification{dogBatch(), noneBatch(), noneBatch()}, DetectionNone},
+		{"majority unlocks on two dogs", MultiCameraMajority, [3][][]Classification{noneBatch(), dogBatch(), dogBatch()}, DetectionDog},
+		{"majority outvotes one cat", MultiCameraMajority, [3][][]Classification{dogBatch(), dogBatch(), cat}, DetectionDog},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier := func(batch [][]Classification) *fakeClassifier {
+				return &fakeClassifier{results: []fakeResult{{classifications: batch}}}
+			}
+			config := dogDoorConfig()
+			config.MultiCameraPolicy = tt.policy
+			sd, _, _, _ := newTestSmartDoor(config, classifier(tt.batches[0]),
+				WithCamera("cam1", newFakeCamera(), classifier(tt.batches[1])),
+				WithCamera("cam2", newFakeCamera(), classifier(tt.batches[2])),
+			)
+
+			d := sd.handleCycle(context.Background(), sd.runCycle(context.Background()))
+			if d.Detection != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, d.Detection)
+			}
+			if tt.want == DetectionDog && (d.Trigger == nil || d.Trigger.Classification.Label != "dog") {
+				t.Fatalf("expected a dog trigger, got %+v", d.Trigger)
+			}
+		})
+	}
+}
+
+func TestCustomCameraAggregator(t *testing.T) {
+	outdoorDog := func(perCamera map[string][]Classification) Detection {
+		for _, c := range perCamera["outdoor"] {
+			if c.Label == "dog" && c.Confidence >= 0.5 {
+				return DetectionDog
+			}
+		}
+		return DetectionNone
+	}
+	tests := []struct {
+		name            string
+		indoor, outdoor [][]Classification
+		want            Detection
+		wantCamera      string
+	}{
+		{"indoor dog alone is ignored", dogBatch(), noneBatch(), DetectionNone, ""},
+		{"outdoor dog unlocks", noneBatch(), dogBatch(), DetectionDog, "outdoor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier := func(batch [][]Classification) *fakeClassifier {
+				return &fakeClassifier{results: []fakeResult{{classifications: batch}}}
+			}
+			sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), classifier(tt.indoor),
+				WithCamera("outdoor", newFakeCamera(), classifier(tt.outdoor)),
+				WithCameraAggregator(outdoorDog),
+			)
+
+			d := sd.handleCycle(context.Background(), sd.runCycle(context.Background()))
+			if d.Detection != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, d.Detection)
+			}
+			if tt.want == DetectionDog && (d.Action != ActionUnlock || d.Trigger.Camera != tt.wantCamera) {
+				t.Fatalf("expected an unlock from %s, got %v from %+v", tt.wantCamera, d.Action, d.Trigger)
+			}
+		})
+	}
+}
+
+func TestEvaluateOnceRespectsCooldown(t *testing.T) {
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	classifier := &fakeClassifier{results: []fakeResult{
+		{classifications: cat},
+		{classifications: dogBatch()},
+		{classifications: dogBatch()},
+	}}
+	config := dogDoorConfig()
+	config.MinimalDurationUnlocking = 10 * time.Second
+	sd, _, door, clock := newTestSmartDoor(config, classifier)
+	ctx := context.Background()
+
+	steps := []struct {
+		advance   time.Duration
+		detection Detection
+		action    DoorAction
+	}{
+		{0, DetectionCat, ActionLock},
+		{time.Second, DetectionDog, ActionNone},
+		{9 * time.Second, DetectionDog, ActionUnlock},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		detection, actions, err := sd.EvaluateOnce(ctx)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if action := actions[defaultDoorID]; detection != step.detection || action != step.action {
+			t.Fatalf("step %d: expected %v/%v, got %v/%v", i, step.detection, step.action, detection, action)
+		}
+	}
+	if got := door.Actions(); len(got) != 2 || got[0] != ActionLock || got[1] != ActionUnlock {
+		t.Fatalf("expected lock then unlock applied, got %v", got)
+	}
+	if got := sd.DoorState(); got != DoorStateUnlocked {
+		t.Fatalf("expected unlocked, got %v", got)
+	}
+}
+
+func TestEvaluateOnceReportsCycleError(t *testing.T) {
+	sd, camera, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	camera.err = errBusy
+
+	if _, _, err := sd.EvaluateOnce(context.Background()); !errors.Is(err, errBusy) {
+		t.Fatalf("expected the capture error, got %v", err)
+	}
+}
+
+func TestReassertResendsIntendedState(t *testing.T) {
+	config := dogDoorConfig()
+	config.ReassertInterval = time.Minute
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(59 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(time.Second)
+	if d := sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeNoSignal}); d.Action != ActionNone {
+		t.Fatalf("a reassert is not a new decision, got %v", d.Action)
+	}
+	expectActions(t, sd, ActionUnlock)
+
+	clock.Advance(30 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+	clock.Advance(30 * time.Second)
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+
+	if got := sd.Stats().Reasserts; got != 2 {
+		t.Fatalf("expected 2 reasserts, got %d", got)
+	}
+	var reasserts int
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventAction && e.Reassert {
+			reasserts++
+		}
+	}
+	if reasserts != 2 {
+		t.Fatalf("expected 2 reassert events, got %d", reasserts)
+	}
+}
+
+func TestIntendedStateSurvivesClassifierOutage(t *testing.T) {
+	for _, failSafe := range []bool{false, true} {
+		config := dogDoorConfig()
+		config.ReassertInterval = time.Minute
+		if failSafe {
+			config.FailSafeAfterErrors = 3
+		}
+		sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+		ctx := context.Background()
+
+		if got := sd.IntendedState(); got != DoorStateUnknown {
+			t.Fatalf("expected unknown before any action, got %v", got)
+		}
+		sd.handleClassifications(dogBatch())
+		expectActions(t, sd, ActionUnlock)
+
+		// The outage holds the intended unlock, and reassertion keeps
+		// sending it, until the fail-safe takes precedence.
+		var sent []DoorAction
+		for i := 0; i < 4; i++ {
+			clock.Advance(30 * time.Second)
+			sd.handleCycle(ctx, CycleResult{Outcome: OutcomeError})
+			sent = append(sent, sd.actions.Drain()...)
+		}
+		want := DoorStateUnlocked
+		if failSafe {
+			want = DoorStateLocked
+		}
+		if got := sd.IntendedState(); got != want {
+			t.Fatalf("failSafe=%v: expected %v through the outage, got %v (sent %v)", failSafe, want, got, sent)
+		}
+		if !failSafe && (len(sent) != 2 || sent[0] != ActionUnlock || sent[1] != ActionUnlock) {
+			t.Fatalf("expected the unlock reasserted twice, got %v", sent)
+		}
+		if failSafe && (len(sent) != 2 || sent[0] != ActionUnlock || sent[1] != ActionLock) {
+			t.Fatalf("expected a reassert then the fail-safe lock, got %v", sent)
+		}
+
+		// Classification recovers with the dog still there: the unlock
+		// carries on, or the fail-safe lock gives way to a fresh one.
+		clock.Advance(time.Second)
+		d := sd.handleClassifications(dogBatch())
+		if failSafe != (d.Action == ActionUnlock) {
+			t.Fatalf("failSafe=%v: unexpected recovery action %v", failSafe, d.Action)
+		}
+		if got := sd.IntendedState(); got != DoorStateUnlocked {
+			t.Fatalf("expected unlocked after recovery, got %v", got)
+		}
+	}
+}
+
+// resubscribingDoor hands out a fresh event channel on every Subscribe.
+type resubscribingDoor struct {
+	*fakeDoor
+	mu   sync.Mutex
+	subs []chan DeviceDoorEvent
+}
+
+func (d *resubscribingDoor) Subscribe() <-chan DeviceDoorEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ch := make(chan DeviceDoorEvent, 1)
+	d.subs = append(d.subs, ch)
+	return ch
+}
+
+func (d *resubscribingDoor) sub(i int) chan DeviceDoorEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i >= len(d.subs) {
+		return nil
+	}
+	return d.subs[i]
+}
+
+type resubscribingCamera struct {
+	*fakeCamera
+	mu   sync.Mutex
+	subs []chan DeviceCameraEvent
+}
+
+func (c *resubscribingCamera) Subscribe() <-chan DeviceCameraEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan DeviceCameraEvent, 1)
+	c.subs = append(c.subs, ch)
+	return ch
+}
+
+func (c *resubscribingCamera) sub(i int) chan DeviceCameraEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i >= len(c.subs) {
+		return nil
+	}
+	return c.subs[i]
+}
+
+func TestClosedEventSubscriptionsDisconnectAndResubscribe(t *testing.T) {
+	camera := &resubscribingCamera{fakeCamera: newFakeCamera()}
+	door := &resubscribingDoor{fakeDoor: newFakeDoor()}
+	clock := newFakeClock()
+	sd := NewSmartDoor(Config{MinimalRateCameraProcess: time.Hour}, camera, door, &fakeClassifier{}, WithClock(clock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+
+	camera.sub(0) <- CameraEventConnected
+	door.sub(0) <- DoorEventConnected
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return c.Camera.Connected && c.Door.Connected
+	})
+
+	// The poll timer is registered; closing both subscriptions adds a
+	// resubscribe timer each.
+	clock.BlockUntil(1)
+	close(camera.sub(0))
+	close(door.sub(0))
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return !c.Camera.Connected && !c.Door.Connected
+	})
+	clock.BlockUntil(3)
+
+	// Read as zero events, a closed channel would keep reporting the
+	// devices as connected.
+	time.Sleep(10 * time.Millisecond)
+	if c := sd.Connectivity(); c.Camera.Connected || c.Door.Connected {
+		t.Fatalf("expected closed subscriptions to stay disconnected, got %+v", c)
+	}
+
+	clock.Advance(resubscribeDelay)
+	waitFor(t, func() bool { return camera.sub(1) != nil && door.sub(1) != nil })
+	waitFor(t, func() bool { return sd.Stats().Resubscribes == 2 })
+
+	camera.sub(1) <- CameraEventConnected
+	door.sub(1) <- DoorEventConnected
+	waitFor(t, func() bool {
+		c := sd.Connectivity()
+		return c.Camera.Connected && c.Door.Connected
+	})
+}
+
+func TestOutOfRangeConfidencesAreClamped(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{
+		{classifications: [][]Classification{{{Label: "dog", Confidence: -0.3}}}},
+		{classifications: [][]Classification{{{Label: "dog", Confidence: 1.7}}}},
+	}}
+	logger := &recordingLogger{}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), classifier, WithLogger(logger))
+	ctx := context.Background()
+
+	result := sd.runCycle(ctx)
+	if got := result.Classifications[0][0].Confidence; got != 0 {
+		t.Fatalf("expected -0.3 clamped to 0, got %v", got)
+	}
+	if d := sd.handleCycle(ctx, result); d.Action != ActionNone {
+		t.Fatalf("expected a negative confidence not to unlock, got %+v", d)
+	}
+
+	result = sd.runCycle(ctx)
+	d := sd.handleCycle(ctx, result)
+	if d.Action != ActionUnlock || d.Trigger.Classification.Confidence != 1 {
+		t.Fatalf("expected an unlock at confidence 1, got %+v", d)
+	}
+	if len(logger.errs) != 1 || !strings.Contains(logger.errs[0], "-0.3") {
+		t.Fatalf("expected a single warning, got %v", logger.errs)
+	}
+}
+
+func TestPartialFrameErrorsUseSuccessfulFrames(t *testing.T) {
+	config := dogDoorConfig()
+	config.ClassifyRetries = 2
+	classifier := &fakeClassifier{results: []fakeResult{{
+		classifications: [][]Classification{
+			{{Label: "dog", Confidence: 0.9}},
+			{{Label: "cat", Confidence: 0.9}},
+		},
+		err: FrameErrors{nil, errors.New("corrupt frame")},
+	}}}
+	sd, camera, _, _ := newTestSmartDoor(config, classifier)
+	camera.frames = []Frame{{Data: []byte{1}}, {Data: []byte{2}}}
+
+	result := sd.runCycle(context.Background())
+	if result.Outcome != OutcomeDecided || result.Err != nil {
+		t.Fatalf("expected a decided cycle, got %v (%v)", result.Outcome, result.Err)
+	}
+	if len(result.Classifications) != 1 || len(result.Frames) != 1 || result.Frames[0].Data[0] != 1 {
+		t.Fatalf("expected only frame 1 to remain, got %v", result.Classifications)
+	}
+	sd.handleCycle(context.Background(), result)
+	expectActions(t, sd, ActionUnlock)
+
+	stats := sd.Stats()
+	if stats.FrameFailures != 1 || stats.ClassifyFailures != 0 || stats.ClassifyRetries != 0 {
+		t.Fatalf("expected one frame failure and no retries, got %+v", stats)
+	}
+}
+
+func TestFrameErrorsOnEveryFrameFailTheCycle(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{{
+		classifications: [][]Classification{nil},
+		err:             FrameErrors{errors.New("corrupt frame")},
+	}}}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), classifier)
+
+	if result := sd.runCycle(context.Background()); result.Outcome != OutcomeError {
+		t.Fatalf("expected an error cycle, got %v", result.Outcome)
+	}
+}
+
+func TestOrderedDeviceEventsFollowPriority(t *testing.T) {
+	config := dogDoorConfig()
+	config.OrderedDeviceEvents = true
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	sd.cameraEvents = make(chan cameraEvent, 2)
+	doorEvents := make(chan DeviceDoorEvent, 2)
+	resubscribe := make(chan time.Time, 1)
+
+	sd.cameraEvents <- cameraEvent{camera: defaultCameraID, event: CameraEventConnected}
+	resubscribe <- time.Time{}
+	doorEvents <- DoorEventConnected
+	sd.cameraEvents <- cameraEvent{camera: defaultCameraID, event: CameraEventDisconnected}
+	doorEvents <- DoorEventDisconnected
+
+	want := []loopEvent{
+		{source: sourceDoor, door: DoorEventConnected},
+		{source: sourceDoor, door: DoorEventDisconnected},
+		{source: sourceCamera, camera: cameraEvent{camera: defaultCameraID, event: CameraEventConnected}},
+		{source: sourceCamera, camera: cameraEvent{camera: defaultCameraID, event: CameraEventDisconnected}},
+		{source: sourceResubscribe},
+	}
+	for i, w := range want {
+		if got := sd.nextLoopEvent(context.Background(), doorEvents, resubscribe); got != w {
+			t.Fatalf("event %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doorEvents <- DoorEventConnected
+	if got := sd.nextLoopEvent(ctx, doorEvents, resubscribe); got.source != sourceShutdown {
+		t.Fatalf("expected shutdown to come first, got %+v", got)
+	}
+}
+
+func TestMinInterFrameIntervalThinsBurst(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinInterFrameInterval = 100 * time.Millisecond
+	classifier := &fakeClassifier{}
+	sd, camera, _, _ := newTestSmartDoor(config, classifier)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	camera.frames = nil
+	for i, offset := range []time.Duration{0, 10, 40, 100, 150, 210, 215, 400} {
+		camera.frames = append(camera.frames, Frame{Data: []byte{byte(i)}, CapturedAt: start.Add(offset * time.Millisecond)})
+	}
+
+	sd.runCycle(context.Background())
+
+	var got []byte
+	for _, frame := range classifier.lastFrames() {
+		got = append(got, frame.Data[0])
+	}
+	if want := []byte{0, 3, 5, 7}; string(got) != string(want) {
+		t.Fatalf("expected frames %v, got %v", want, got)
+	}
+	if n := sd.Stats().FramesThinned; n != 4 {
+		t.Fatalf("expected 4 thinned frames, got %d", n)
+	}
+}
+
+func TestMaxFramesPerCycleKeepsMostRecentFrames(t *testing.T) {
+	config := dogDoorConfig()
+	config.MaxFramesPerCycle = 3
+	classifier := &fakeClassifier{}
+	sd, camera, _, _ := newTestSmartDoor(config, classifier)
+	camera.frames = nil
+	for i := 0; i < 8; i++ {
+		camera.frames = append(camera.frames, Frame{Data: []byte{byte(i)}})
+	}
+
+	sd.runCycle(context.Background())
+
+	var got []byte
+	for _, frame := range classifier.lastFrames() {
+		got = append(got, frame.Data[0])
+	}
+	if want := []byte{5, 6, 7}; string(got) != string(want) {
+		t.Fatalf("expected frames %v, got %v", want, got)
+	}
+	if n := sd.Stats().FramesTruncated; n != 5 {
+		t.Fatalf("expected 5 truncated frames, got %d", n)
+	}
+}
+
+func TestInputResolutionResizesFramesBeforeClassifying(t *testing.T) {
+	config := dogDoorConfig()
+	config.InputResolution = Resolution{Width: 16, Height: 12}
+	classifier := &fakeClassifier{}
+	sd, camera, _, _ := newTestSmartDoor(config, classifier)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 48))); err != nil {
+		t.Fatal(err)
+	}
+	camera.frames = []Frame{{Data: buf.Bytes(), Format: FrameFormatPNG}, {Data: []byte{1, 2, 3}}}
+
+	sd.runCycle(context.Background())
+
+	frames := classifier.lastFrames()
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(frames))
+	}
+	if frames[0].Width != 16 || frames[0].Height != 12 || frames[0].Format != FrameFormatPNG {
+		t.Fatalf("expected a 16x12 PNG, got %dx%d format %d", frames[0].Width, frames[0].Height, frames[0].Format)
+	}
+	img, err := StandardFrameDecoder{}.DecodeFrame(frames[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 16, 12) {
+		t.Fatalf("expected the classifier to get 16x12 pixels, got %v", got)
+	}
+	if raw := frames[1]; string(raw.Data) != "\x01\x02\x03" || raw.Width != 0 {
+		t.Fatalf("expected the raw frame passed on as captured, got %+v", raw)
+	}
+}
+
+func TestRunCameraPipelineAlone(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, camera, door, clock := newTestSmartDoor(config, classifier)
+	results := make(chan CycleResult)
+	done := make(chan error, 1)
+	go func() { done <- sd.RunCameraPipeline(context.Background(), results) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	result := <-results
+	if result.Outcome != OutcomeDecided || result.Classifications[0][0].Label != "dog" {
+		t.Fatalf("expected the dog cycle on results, got %+v", result)
+	}
+
+	camera.events <- CameraEventConnected
+	waitFor(t, func() bool { return sd.Connectivity().Camera.Connected })
+
+	if err := sd.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected a clean stop, got %v", err)
+	}
+	if _, ok := <-results; ok {
+		t.Fatal("expected results to be closed")
+	}
+	if got := door.Actions(); len(got) != 0 {
+		t.Fatalf("expected the door never commanded, got %v", got)
+	}
+}
+
+func TestRunDoorControllerAlone(t *testing.T) {
+	classifier := &fakeClassifier{}
+	sd, camera, door, _ := newTestSmartDoor(dogDoorConfig(), classifier)
+	results := make(chan CycleResult)
+	done := make(chan error, 1)
+	go func() { done <- sd.RunDoorController(context.Background(), results) }()
+
+	results <- CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}
+	waitFor(t, func() bool { return len(door.Actions()) == 1 })
+	if got := door.Actions()[0]; got != ActionUnlock {
+		t.Fatalf("expected an unlock from the external detection, got %v", got)
+	}
+
+	close(results)
+	door.events <- DoorEventConnected
+	waitFor(t, func() bool { return sd.Connectivity().Door.Connected })
+
+	if err := sd.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected a clean stop, got %v", err)
+	}
+	if classifier.Calls() != 0 || camera.captures != 0 {
+		t.Fatalf("expected no capture or classification, got %d calls", classifier.Calls())
+	}
+}
+
+func TestSnapshotKeepsPollCadence(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	sd, camera, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	camera.frames = []Frame{{Data: []byte("live")}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.pollCamera(ctx)
+
+	clock.BlockUntil(1)
+	clock.Advance(500 * time.Millisecond)
+	frames, err := sd.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 1 || string(frames[0].Data) != "live" {
+		t.Fatalf("expected the live frame, got %v", frames)
+	}
+	if n := sd.Stats().Cycles; n != 0 {
+		t.Fatalf("expected the snapshot not to count as a cycle, got %d", n)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	<-sd.classificationCh
+	if n := sd.Stats().Cycles; n != 1 {
+		t.Fatalf("expected the cycle on its usual schedule, got %d cycles", n)
+	}
+	camera.mu.Lock()
+	defer camera.mu.Unlock()
+	if camera.captures != 2 {
+		t.Fatalf("expected one snapshot and one cycle capture, got %d", camera.captures)
+	}
+}
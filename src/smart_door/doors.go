@@ -0,0 +1,212 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultDoorID names the door passed to NewSmartDoor in decision.Doors.
+const defaultDoorID = "door0"
+
+// DoorRules maps what a decided cycle detected to the action a secondary
+// door takes, such as an inner door that locks for DetectionCat while the
+// primary door unlocks for DetectionDog. Detections missing from the map
+// leave the door as it is.
+type DoorRules map[Detection]DoorAction
+
+type doorSlot struct {
+	id      string
+	door    DeviceDoor
+	rules   DoorRules
+	actions *actionQueue
+	events  <-chan DeviceDoorEvent
+	// intended is the last action decided for the door. Owned by the
+	// controlDoor goroutine.
+	intended DoorAction
+	// state is left by the last action the door applied. Guarded by
+	// sd.mu.
+	state DoorState
+}
+
+type doorEvent struct {
+	door  string
+	event DeviceDoorEvent
+}
+
+// WithDoor adds a secondary door, driven by rules alongside the door
+// passed to NewSmartDoor, so one cycle can lock one door and unlock
+// another. Secondary doors act as soon as a decided cycle's detection
+// calls for a different action; the primary door's cooldowns, relock
+// timers, caps and overrides do not apply to them. Their events are
+// tracked in Connectivity.Doors and their state in DoorStateOf.
+func WithDoor(id string, door DeviceDoor, rules DoorRules) Option {
+	return func(sd *SmartDoor) {
+		sd.doors = append(sd.doors, &doorSlot{
+			id:      id,
+			door:    door,
+			rules:   rules,
+			actions: newActionQueue(),
+			events:  door.Subscribe(),
+		})
+		if sd.connectivity.Doors == nil {
+			sd.connectivity.Doors = make(map[string]DeviceConnectivity)
+		}
+		sd.connectivity.Doors[id] = DeviceConnectivity{}
+	}
+}
+
+// DoorStateOf is the state left by the last action the door identified by
+// id applied: "door0" for the door passed to NewSmartDoor, as DoorState,
+// or a door added with WithDoor. It reports false for an unknown id.
+func (sd *SmartDoor) DoorStateOf(id string) (DoorState, bool) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if id == defaultDoorID {
+		return sd.doorState, true
+	}
+	for _, slot := range sd.doors {
+		if slot.id == id {
+			return slot.state, true
+		}
+	}
+	return DoorStateUnknown, false
+}
+
+// forwardDoorEvents relays a secondary door's events to the main loop. A
+// closed subscription marks the door disconnected and is retried every
+// resubscribeDelay.
+func (sd *SmartDoor) forwardDoorEvents(slot *doorSlot) func(context.Context) {
+	return func(ctx context.Context) {
+		events := slot.events
+		forward := func(event DeviceDoorEvent) bool {
+			select {
+			case sd.doorSlotEvents <- doorEvent{door: slot.id, event: event}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if ok {
+					if !forward(event) {
+						return
+					}
+					continue
+				}
+				sd.logger.Error(fmt.Sprintf("door %s event subscription closed", slot.id))
+				if !forward(DoorEventDisconnected) || !sd.sleep(ctx, resubscribeDelay) {
+					return
+				}
+				events = slot.door.Subscribe()
+				sd.countResubscribe()
+			}
+		}
+	}
+}
+
+func (sd *SmartDoor) handleSecondaryDoorEvent(id string, event DeviceDoorEvent) {
+	sd.trace(TraceEntry{Kind: TraceKindDoorEvent, Door: id, DoorEvent: event})
+	connected := event == DoorEventConnected
+	sd.mu.Lock()
+	door, known := sd.connectivity.Doors[id]
+	changed := known && door.set(connected, sd.clock.Now())
+	if changed {
+		sd.connectivity.Doors[id] = door
+	}
+	sd.mu.Unlock()
+	if known && !changed {
+		sd.debug(fmt.Sprintf("door %s: ignoring repeated %s event", id, connectedName(connected)))
+	}
+}
+
+// decideDoors records the action of every door that acts this cycle in
+// d.Doors, applying the rules of the secondary doors to the cycle's
+// detection. Only decided cycles move a secondary door, and not while
+// overridden or disarmed.
+func (sd *SmartDoor) decideDoors(d *decision, result CycleResult) {
+	if d.Action != ActionNone {
+		d.Doors = map[string]DoorAction{defaultDoorID: d.Action}
+	}
+	if result.Outcome != OutcomeDecided || d.Reason == ReasonOverridden || d.Reason == ReasonDisarmed {
+		return
+	}
+	for _, slot := range sd.doors {
+		action := slot.rules[d.Detection]
+		if action == ActionNone || action == slot.intended {
+			continue
+		}
+		slot.intended = action
+		if d.Doors == nil {
+			d.Doors = make(map[string]DoorAction)
+		}
+		d.Doors[slot.id] = action
+		slot.actions.Enqueue(action)
+		sd.emit(Event{
+			Kind:    EventAction,
+			Time:    sd.clock.Now(),
+			Action:  action,
+			Door:    slot.id,
+			Trigger: d.Trigger,
+			Message: fmt.Sprintf("%s %s for %s", slot.id, action, d.Detection),
+		})
+	}
+}
+
+// executeDoors runs an executor for each secondary door until ctx is
+// cancelled, then drains it like executeActions.
+func (sd *SmartDoor) executeDoors(ctx, callCtx context.Context) {
+	var executors sync.WaitGroup
+	for _, slot := range sd.doors {
+		goRun(ctx, &executors, func(ctx context.Context) {
+			for {
+				action, ok := slot.actions.Next(ctx)
+				if !ok {
+					break
+				}
+				sd.executeDoorAction(callCtx, slot, action)
+			}
+			for _, action := range slot.actions.Drain() {
+				sd.executeDoorAction(callCtx, slot, action)
+			}
+		})
+	}
+	executors.Wait()
+}
+
+// applyDoors applies every queued action of the secondary doors in turn,
+// for callers that apply actions synchronously, such as EvaluateOnce.
+func (sd *SmartDoor) applyDoors(ctx context.Context) error {
+	var errs []error
+	for _, slot := range sd.doors {
+		for _, action := range slot.actions.Drain() {
+			if err := sd.executeDoorAction(ctx, slot, action); err != nil {
+				errs = append(errs, fmt.Errorf("door %s: %w", slot.id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (sd *SmartDoor) executeDoorAction(ctx context.Context, slot *doorSlot, action DoorAction) error {
+	executor := doorExecutor{door: slot.door}
+	if err := sd.callWithTimeout(ctx, action, executor.Execute); err != nil {
+		sd.mu.Lock()
+		sd.stats.DoorFailures++
+		sd.summary.errors++
+		sd.mu.Unlock()
+		sd.logger.Error(fmt.Sprintf("door %s %s failed: %v", slot.id, action, err))
+		sd.emit(Event{Kind: EventError, Action: action, Door: slot.id, Message: err.Error()})
+		return err
+	}
+	sd.mu.Lock()
+	slot.state = actionState(action)
+	sd.mu.Unlock()
+	sd.emit(Event{Kind: EventActionApplied, Action: action, Door: slot.id})
+	return nil
+}
@@ -0,0 +1,99 @@
+package smartdoor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDoorsTakeDifferentActionsInOneCycle(t *testing.T) {
+	inner := newFakeDoor()
+	sd, _, outer, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{},
+		WithDoor("inner", inner, DoorRules{DetectionCat: ActionLock, DetectionDog: ActionLock, DetectionNone: ActionUnlock}))
+	drain := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		applyActions(sd)
+		sd.executeDoors(ctx, context.Background())
+	}
+
+	d := sd.handleClassifications(dogBatch())
+	want := map[string]DoorAction{defaultDoorID: ActionUnlock, "inner": ActionLock}
+	if !reflect.DeepEqual(d.Doors, want) {
+		t.Fatalf("expected %v, got %v", want, d.Doors)
+	}
+	drain()
+	if got := outer.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionUnlock}) {
+		t.Fatalf("expected the outer door unlocked, got %v", got)
+	}
+	if got := inner.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionLock}) {
+		t.Fatalf("expected the inner door locked, got %v", got)
+	}
+
+	// A cat keeps the inner door locked, so only the outer door acts.
+	d = sd.handleClassifications([][]Classification{{{Label: "cat", Confidence: 0.9}}})
+	if want := map[string]DoorAction{defaultDoorID: ActionLock}; !reflect.DeepEqual(d.Doors, want) {
+		t.Fatalf("expected %v, got %v", want, d.Doors)
+	}
+	d = sd.handleClassifications(noneBatch())
+	if want := map[string]DoorAction{"inner": ActionUnlock}; !reflect.DeepEqual(d.Doors, want) {
+		t.Fatalf("expected %v, got %v", want, d.Doors)
+	}
+	drain()
+	if got := inner.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionLock, ActionUnlock}) {
+		t.Fatalf("expected the inner door unlocked, got %v", got)
+	}
+}
+
+func TestEvaluateOnceAppliesEveryDoor(t *testing.T) {
+	inner := newFakeDoor()
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, _, outer, _ := newTestSmartDoor(dogDoorConfig(), classifier,
+		WithDoor("inner", inner, DoorRules{DetectionDog: ActionLock}))
+
+	_, actions, err := sd.EvaluateOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]DoorAction{"door0": ActionUnlock, "inner": ActionLock}; !reflect.DeepEqual(actions, want) {
+		t.Fatalf("expected %v, got %v", want, actions)
+	}
+	if got := outer.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionUnlock}) {
+		t.Fatalf("expected the outer door unlocked, got %v", got)
+	}
+	if got := inner.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionLock}) {
+		t.Fatalf("expected the inner door locked, got %v", got)
+	}
+	for id, want := range map[string]DoorState{"door0": DoorStateUnlocked, "inner": DoorStateLocked} {
+		if got, ok := sd.DoorStateOf(id); !ok || got != want {
+			t.Fatalf("expected %s %v, got %v", id, want, got)
+		}
+	}
+	if _, ok := sd.DoorStateOf("garage"); ok {
+		t.Fatal("expected an unknown door reported")
+	}
+}
+
+func TestSecondaryDoorEventsTrackConnectivity(t *testing.T) {
+	inner := newFakeDoor()
+	sd, _, _, _ := newTestSmartDoor(Config{MinimalRateCameraProcess: time.Second}, &fakeClassifier{},
+		WithDoor("inner", inner, DoorRules{DetectionDog: ActionLock}))
+	if c := sd.Connectivity(); len(c.Doors) != 1 || c.Doors["inner"].Connected {
+		t.Fatalf("expected the inner door known but not connected, got %+v", c.Doors)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+	inner.events <- DoorEventConnected
+	waitFor(t, func() bool { return sd.Connectivity().Doors["inner"].Connected })
+	inner.events <- DoorEventDisconnected
+	waitFor(t, func() bool { return !sd.Connectivity().Doors["inner"].Connected })
+	if c := sd.Connectivity(); c.Door.Connected {
+		t.Fatalf("expected the primary door unaffected, got %+v", c.Door)
+	}
+	if err := sd.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
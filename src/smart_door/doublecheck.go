@@ -0,0 +1,45 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+)
+
+// confirmDetection is Config.ConfirmWithExtraCapture's double check: one
+// more capture from the trigger's camera, classified at once, must come
+// out as the same detection before it is acted on. A failed capture or
+// classification does not confirm.
+func (sd *SmartDoor) confirmDetection(ctx context.Context, detection Detection, trigger *Trigger) bool {
+	slot := sd.cameras[0]
+	for _, s := range sd.cameras {
+		if trigger != nil && s.id == trigger.Camera {
+			slot = s
+		}
+	}
+	deadline := sd.cycleDeadline()
+	ctx, cancel := sd.cycleContext(ctx, deadline)
+	defer cancel()
+
+	confirmed := DetectionNone
+	frames, err := sd.capture(ctx, slot)
+	if err == nil {
+		result := sd.classifyFrames(ctx, slot, frames, deadline)
+		err = result.Err
+		if result.Outcome == OutcomeDecided {
+			confirmed, _ = sd.toDetection(result.Classifications)
+		}
+	}
+	if confirmed == detection {
+		return true
+	}
+
+	sd.mu.Lock()
+	sd.stats.ConfirmRejected++
+	sd.mu.Unlock()
+	if err != nil {
+		sd.logger.Error(fmt.Sprintf("confirm %s on %s: %v", detection, slot.id, err))
+	} else {
+		sd.logger.Info(fmt.Sprintf("%s on %s not confirmed: saw %s", detection, slot.id, confirmed))
+	}
+	return false
+}
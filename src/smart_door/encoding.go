@@ -0,0 +1,71 @@
+package smartdoor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventEncoder renders an Event for one kind of consumer, so the event
+// model is defined once and each sink picks its wire format.
+type EventEncoder interface {
+	// ContentType is the MIME type of what Encode returns.
+	ContentType() string
+	Encode(event Event) ([]byte, error)
+}
+
+// JSONEventEncoder encodes events as JSON, the default for the event log
+// and webhooks.
+type JSONEventEncoder struct{}
+
+func (JSONEventEncoder) ContentType() string { return "application/json" }
+
+func (JSONEventEncoder) Encode(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// TextEventEncoder encodes an event as a single human-readable line, such
+// as "2024-01-01T12:00:00Z info action unlock dog 0.94 on cam0", for
+// small displays and plain-text logs.
+type TextEventEncoder struct{}
+
+func (TextEventEncoder) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (TextEventEncoder) Encode(event Event) ([]byte, error) {
+	fields := []string{event.Time.UTC().Format(time.RFC3339), event.Severity.String(), event.Kind.String()}
+	if event.Door != "" {
+		fields = append(fields, event.Door)
+	}
+	if event.Action != ActionNone {
+		fields = append(fields, event.Action.String())
+	}
+	if event.Message != "" {
+		fields = append(fields, strings.ReplaceAll(event.Message, "\n", " "))
+	}
+	return []byte(strings.Join(fields, " ")), nil
+}
+
+var kindNames = [...]string{
+	EventAction:              "action",
+	EventUnlockCapReached:    "unlock_cap_reached",
+	EventHeartbeat:           "heartbeat",
+	EventActionApplied:       "action_applied",
+	EventError:               "error",
+	EventFailSafe:            "fail_safe",
+	EventStarted:             "started",
+	EventManualUnlock:        "manual_unlock",
+	EventNoAction:            "no_action",
+	EventSummary:             "summary",
+	EventClassifierRecovered: "classifier_recovered",
+	EventDetection:           "detection",
+	EventPendingAction:       "pending_action",
+	EventConfigChanged:       "config_changed",
+}
+
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
@@ -0,0 +1,64 @@
+package smartdoor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventEncodersRenderTheSameEvent(t *testing.T) {
+	event := Event{
+		Kind:     EventAction,
+		Severity: SeverityInfo,
+		Time:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Action:   ActionUnlock,
+		Message:  "dog 0.94 on cam0",
+		Trigger:  &Trigger{Camera: "cam0", Classification: Classification{Label: "dog", Confidence: 0.94}},
+	}
+
+	data, err := JSONEventEncoder{}.Encode(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Event
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if decoded.Kind != event.Kind || decoded.Action != event.Action || decoded.Message != event.Message ||
+		!decoded.Time.Equal(event.Time) || decoded.Trigger == nil || *decoded.Trigger != *event.Trigger {
+		t.Fatalf("expected %+v back from JSON, got %+v", event, decoded)
+	}
+
+	data, err = TextEventEncoder{}.Encode(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-01-01T12:00:00Z info action unlock dog 0.94 on cam0"; string(data) != want {
+		t.Fatalf("expected text %q, got %q", want, data)
+	}
+}
+
+func TestEventLogWriterUsesItsEncoder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	w, err := NewEventLogWriter(path, EventLogRotation{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetEncoder(TextEventEncoder{})
+
+	events := make(chan Event, 1)
+	events <- Event{Kind: EventError, Severity: SeverityWarning, Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Message: "camera\nunplugged"}
+	close(events)
+	w.Run(context.Background(), events)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-01-01T12:00:00Z warning error camera unplugged\n"; string(data) != want {
+		t.Fatalf("expected %q, got %q", want, data)
+	}
+}
@@ -0,0 +1,205 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultEventLogFiles is how many rotated files an EventLogWriter keeps
+// when EventLogRotation.MaxFiles is zero.
+const defaultEventLogFiles = 5
+
+// EventLogRotation bounds the files an EventLogWriter writes. The current
+// file is rotated before an event would take it past MaxSize bytes, or once
+// its first event is MaxAge older than the next; zero disables either
+// limit. MaxFiles rotated files are kept, path.1 being the newest.
+type EventLogRotation struct {
+	MaxSize  int64
+	MaxAge   time.Duration
+	MaxFiles int
+}
+
+// EventLogWriter persists the event stream to path as JSON lines, or in the
+// format of SetEncoder, so the history survives a restart without a separate
+// log shipper. Writing to
+// disk never holds up the stream: events arriving while the writer is
+// behind are dropped and counted in Dropped.
+type EventLogWriter struct {
+	path     string
+	rotation EventLogRotation
+	logger   Logger
+	encoder  EventEncoder
+	queue    chan Event
+
+	mu      sync.Mutex
+	dropped int
+	file    *os.File
+	size    int64
+	// opened is the time of the first event in the current file.
+	opened time.Time
+}
+
+// NewEventLogWriter appends to path, creating it if needed. Failed writes
+// are logged to logger when it is not nil.
+func NewEventLogWriter(path string, rotation EventLogRotation, logger Logger) (*EventLogWriter, error) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	if rotation.MaxFiles <= 0 {
+		rotation.MaxFiles = defaultEventLogFiles
+	}
+	w := &EventLogWriter{
+		path:     path,
+		rotation: rotation,
+		logger:   logger,
+		encoder:  JSONEventEncoder{},
+		queue:    make(chan Event, eventBufferSize),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetEncoder writes events with encoder instead of as JSON. Each encoded
+// event must be a single line. Call it before Run.
+func (w *EventLogWriter) SetEncoder(encoder EventEncoder) {
+	w.encoder = encoder
+}
+
+// Run writes every event from events until it is closed or ctx is done,
+// then flushes what it has queued and closes the file. Pass a subscription
+// from SmartDoor.Events. Run may only be called once.
+func (w *EventLogWriter) Run(ctx context.Context, events <-chan Event) {
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for event := range w.queue {
+			w.write(event)
+		}
+	}()
+	defer func() {
+		close(w.queue)
+		<-written
+		w.close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			w.enqueue(event)
+		}
+	}
+}
+
+// Dropped is how many events arrived while the writer was too far behind
+// to queue them.
+func (w *EventLogWriter) Dropped() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+func (w *EventLogWriter) enqueue(event Event) {
+	select {
+	case w.queue <- event:
+	default:
+		w.mu.Lock()
+		w.dropped++
+		w.mu.Unlock()
+	}
+}
+
+func (w *EventLogWriter) write(event Event) {
+	line, err := w.encoder.Encode(event)
+	if err != nil {
+		w.logger.Error(fmt.Sprintf("event log: encode event: %v", err))
+		return
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.due(event.Time, len(line)) {
+		if err := w.rotate(); err != nil {
+			w.logger.Error(fmt.Sprintf("event log: rotate %s: %v", w.path, err))
+			return
+		}
+	}
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		w.logger.Error(fmt.Sprintf("event log: write %s: %v", w.path, err))
+	}
+	if w.opened.IsZero() {
+		w.opened = event.Time
+	}
+}
+
+// due reports whether the current file must be rotated before a line of
+// size bytes for an event at t is written to it.
+func (w *EventLogWriter) due(t time.Time, size int) bool {
+	if w.size == 0 {
+		return false
+	}
+	r := w.rotation
+	return r.MaxSize > 0 && w.size+int64(size) > r.MaxSize ||
+		r.MaxAge > 0 && !w.opened.IsZero() && t.Sub(w.opened) >= r.MaxAge
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest beyond MaxFiles,
+// moves the current file to path.1 and starts a new one. A new file is
+// opened even if shifting failed, so that writing can go on.
+func (w *EventLogWriter) rotate() error {
+	closeErr := w.file.Close()
+	shiftErr := w.shift()
+	return errors.Join(closeErr, shiftErr, w.open())
+}
+
+func (w *EventLogWriter) shift() error {
+	oldest := fmt.Sprintf("%s.%d", w.path, w.rotation.MaxFiles)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := w.rotation.MaxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(w.path, w.path+".1")
+}
+
+func (w *EventLogWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.opened = time.Time{}
+	return nil
+}
+
+func (w *EventLogWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Close(); err != nil {
+		w.logger.Error(fmt.Sprintf("event log: close %s: %v", w.path, err))
+	}
+}
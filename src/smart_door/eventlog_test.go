@@ -0,0 +1,102 @@
+package smartdoor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readEventLog(t *testing.T, path string) []Event {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("decode %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEventLogWriterRotatesAtSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	line, _ := json.Marshal(Event{Kind: EventAction, Time: start, Message: "event 0"})
+	// Two events fit in a file, a third rotates it.
+	w, err := NewEventLogWriter(path, EventLogRotation{MaxSize: int64(2*len(line) + 2), MaxFiles: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan Event, 8)
+	for i := range 7 {
+		events <- Event{Kind: EventAction, Time: start, Message: "event " + string(rune('0'+i))}
+	}
+	close(events)
+	w.Run(context.Background(), events)
+
+	for suffix, want := range map[string][]string{
+		"":   {"event 6"},
+		".1": {"event 4", "event 5"},
+		".2": {"event 2", "event 3"},
+	} {
+		got := readEventLog(t, path+suffix)
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %+v", path+suffix, want, got)
+		}
+		for i := range want {
+			if got[i].Message != want[i] || got[i].Kind != EventAction || !got[i].Time.Equal(start) {
+				t.Fatalf("%s: expected %v, got %+v", path+suffix, want, got)
+			}
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only MaxFiles rotated files, got %v", err)
+	}
+}
+
+func TestEventLogWriterRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	w, err := NewEventLogWriter(path, EventLogRotation{MaxAge: time.Hour}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := make(chan Event, 3)
+	events <- Event{Kind: EventHeartbeat, Time: start}
+	events <- Event{Kind: EventHeartbeat, Time: start.Add(59 * time.Minute)}
+	events <- Event{Kind: EventHeartbeat, Time: start.Add(time.Hour)}
+	close(events)
+	w.Run(context.Background(), events)
+
+	if got := readEventLog(t, path+".1"); len(got) != 2 {
+		t.Fatalf("expected the first hour in the rotated file, got %d events", len(got))
+	}
+	if got := readEventLog(t, path); len(got) != 1 {
+		t.Fatalf("expected the new hour in the current file, got %d events", len(got))
+	}
+}
+
+func TestEventLogWriterDropsWhenBehind(t *testing.T) {
+	w, err := NewEventLogWriter(filepath.Join(t.TempDir(), "events.jsonl"), EventLogRotation{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range eventBufferSize + 3 {
+		w.enqueue(Event{Kind: EventHeartbeat})
+	}
+	if n := w.Dropped(); n != 3 {
+		t.Fatalf("expected 3 dropped events, got %d", n)
+	}
+}
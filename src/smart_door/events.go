@@ -0,0 +1,306 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type EventKind int
+
+const (
+	// EventAction reports a door action decided by the controller.
+	EventAction EventKind = iota
+	// EventUnlockCapReached reports a lock forced by MaxContinuousUnlock.
+	EventUnlockCapReached
+	// EventHeartbeat is emitted every Config.HeartbeatInterval as a
+	// liveness signal.
+	EventHeartbeat
+	// EventActionApplied reports a door action the device completed.
+	EventActionApplied
+	// EventError reports a failure, described by Message.
+	EventError
+	// EventFailSafe reports a lock forced by Config.FailSafeAfterErrors,
+	// or the action Config.CameraLossPolicy takes on a lost camera.
+	EventFailSafe
+	// EventStarted is the first event of a Run, describing what it runs
+	// with.
+	EventStarted
+	// EventManualUnlock reports the door seen unlocked without the
+	// controller commanding it.
+	EventManualUnlock
+	// EventNoAction reports the NoActionReason of an idle cycle whenever
+	// it differs from the last cycle's.
+	EventNoAction
+	// EventSummary carries the Summary of a window of activity, every
+	// Config.SummaryInterval or on EmitSummary.
+	EventSummary
+	// EventClassifierRecovered reports classification succeeding again
+	// after an outage that engaged the fail-safe or breaker, described by
+	// Recovery.
+	EventClassifierRecovered
+	// EventDetection reports, while disarmed, each change in what the
+	// camera detects.
+	EventDetection
+	// EventPendingAction announces an action Config.ConfirmDelay before it
+	// is applied.
+	EventPendingAction
+	// EventConfigChanged reports the ConfigChanges of an UpdateConfig or
+	// a profile switch.
+	EventConfigChanged
+)
+
+type Severity int
+
+const (
+	// severityUnset is the zero Severity, which emit replaces with the
+	// usual severity for the event's kind, so SeverityDebug can still be
+	// set explicitly.
+	severityUnset Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	// SeverityCritical marks events that need someone's attention, such
+	// as a stuck door or a fail-safe lock.
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityDebug:
+		return "debug"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// kindSeverity is the severity of events that do not set one.
+func kindSeverity(kind EventKind) Severity {
+	switch kind {
+	case EventHeartbeat, EventNoAction:
+		return SeverityDebug
+	case EventUnlockCapReached, EventError:
+		return SeverityWarning
+	case EventFailSafe:
+		return SeverityCritical
+	}
+	return SeverityInfo
+}
+
+type Event struct {
+	Kind EventKind
+	// Severity defaults to the usual severity for Kind when left zero;
+	// emitters raise it for worse cases of the same kind, such as a door
+	// call timing out.
+	Severity Severity
+	Time     time.Time
+	Action   DoorAction
+	// Door is the ID of the secondary door an action event is for, empty
+	// for the primary door; see WithDoor.
+	Door string
+	// Reassert marks an EventAction that re-sends the intended action
+	// rather than deciding a new one.
+	Reassert bool
+	Message  string
+	Trigger  *Trigger
+	// Context is set on EventAction, EventActionApplied and door
+	// EventErrors to the context the action was decided with.
+	Context *ActionContext
+	// NoActionReason is set on EventNoAction.
+	NoActionReason NoActionReason
+	// Detection is set on EventDetection.
+	Detection Detection
+	// Quiet marks a non-critical event emitted during Config.QuietHours,
+	// which Notifications holds back.
+	Quiet     bool
+	Heartbeat *Heartbeat
+	Summary   *Summary
+	// ConfigChanges is set on EventConfigChanged.
+	ConfigChanges []ConfigChange
+	Recovery      *Recovery
+	Started       *Started
+}
+
+type Heartbeat struct {
+	DoorState       DoorState
+	Connectivity    Connectivity
+	FramesProcessed int
+	Version         BuildInfo
+}
+
+// Started describes the build, devices and effective config a Run began
+// with, so later events can be read against them.
+type Started struct {
+	Version BuildInfo
+	Cameras []string
+	Door    string
+	Config  Config
+}
+
+const eventBufferSize = 64
+
+type subscriber struct {
+	ch chan Event
+	// notifications limits delivery to what Notifications promises.
+	notifications bool
+}
+
+// wants reports whether the subscriber takes event.
+func (s subscriber) wants(event Event) bool {
+	return !s.notifications || (event.Severity >= SeverityInfo && !event.Quiet)
+}
+
+// Events returns a new subscription to the event stream. Delivery never
+// blocks the controller: events for a subscriber whose buffer is full are
+// dropped and counted in Stats.EventsDropped. The channel is closed when
+// Run shuts down.
+func (sd *SmartDoor) Events() <-chan Event {
+	return sd.subscribe(false)
+}
+
+// Notifications is like Events but only delivers events worth telling
+// someone about: those of SeverityInfo and above, less any non-critical
+// event during Config.QuietHours. Those are counted in
+// Stats.NotificationsSuppressed instead.
+func (sd *SmartDoor) Notifications() <-chan Event {
+	return sd.subscribe(true)
+}
+
+func (sd *SmartDoor) subscribe(notifications bool) <-chan Event {
+	ch := make(chan Event, eventBufferSize)
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.closed {
+		close(ch)
+		return ch
+	}
+	sd.subscribers = append(sd.subscribers, subscriber{ch: ch, notifications: notifications})
+	return ch
+}
+
+func (sd *SmartDoor) unsubscribe(ch <-chan Event) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	for i, sub := range sd.subscribers {
+		if sub.ch == ch {
+			sd.subscribers = append(sd.subscribers[:i], sd.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// WaitForState blocks until the door is in state, ctx is done, or Run shuts
+// down, which returns ErrNotRunning. It returns at once if the door is
+// already in state.
+func (sd *SmartDoor) WaitForState(ctx context.Context, state DoorState) error {
+	events := sd.Events()
+	defer sd.unsubscribe(events)
+
+	for {
+		if sd.DoorState() == state {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-events:
+			if !ok {
+				if sd.DoorState() == state {
+					return nil
+				}
+				return ErrNotRunning
+			}
+		}
+	}
+}
+
+func (sd *SmartDoor) closeSubscribers() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.closed = true
+	for _, sub := range sd.subscribers {
+		close(sub.ch)
+	}
+	sd.subscribers = nil
+	for _, tap := range sd.taps {
+		close(tap)
+	}
+	sd.taps = nil
+}
+
+func (sd *SmartDoor) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = sd.clock.Now()
+	}
+	if event.Severity == severityUnset {
+		event.Severity = kindSeverity(event.Kind)
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if event.Severity >= SeverityInfo && event.Severity < SeverityCritical &&
+		sd.effectiveConfigLocked().QuietHours.Contains(event.Time) {
+		event.Quiet = true
+	}
+	suppressed := false
+	for _, sub := range sd.subscribers {
+		if !sub.wants(event) {
+			if event.Quiet && sub.notifications {
+				suppressed = true
+			}
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sd.stats.EventsDropped++
+		}
+	}
+	if suppressed {
+		sd.stats.NotificationsSuppressed++
+	}
+}
+
+func (sd *SmartDoor) heartbeat(ctx context.Context) {
+	ticker := sd.clock.NewTicker(sd.currentConfig().HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			sd.trace(TraceEntry{Kind: TraceKindTick, Tick: tickHeartbeat})
+			sd.emitHeartbeat(now)
+		}
+	}
+}
+
+func (sd *SmartDoor) emitHeartbeat(now time.Time) {
+	sd.mu.Lock()
+	heartbeat := Heartbeat{
+		DoorState:       sd.doorState,
+		Connectivity:    sd.connectivity.clone(),
+		FramesProcessed: sd.stats.FramesProcessed,
+		Version:         Version(),
+	}
+	sd.mu.Unlock()
+	sd.emit(Event{Kind: EventHeartbeat, Time: now, Heartbeat: &heartbeat})
+}
+
+func (sd *SmartDoor) emitStarted() {
+	started := Started{
+		Version: Version(),
+		Door:    fmt.Sprintf("%T", sd.door),
+		Config:  sd.currentConfig().clone(),
+	}
+	for _, slot := range sd.cameras {
+		started.Cameras = append(started.Cameras, slot.id)
+	}
+	sd.emit(Event{Kind: EventStarted, Started: &started})
+}
@@ -0,0 +1,242 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatFiresOnSchedule(t *testing.T) {
+	config := Config{HeartbeatInterval: 30 * time.Second}
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+	events := sd.Events()
+
+	if sd.runCycle(context.Background()).Outcome != OutcomeDecided {
+		t.Fatal("expected cycle to succeed")
+	}
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.heartbeat(ctx)
+	clock.BlockUntil(1)
+
+	start := clock.Now()
+	clock.Advance(29 * time.Second)
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected early event %+v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	expectHeartbeat(t, events, start.Add(30*time.Second))
+
+	clock.Advance(30 * time.Second)
+	expectHeartbeat(t, events, start.Add(60*time.Second))
+}
+
+func expectHeartbeat(t *testing.T, events <-chan Event, at time.Time) {
+	t.Helper()
+	e := <-events
+	if e.Kind != EventHeartbeat || e.Heartbeat == nil {
+		t.Fatalf("expected heartbeat, got %+v", e)
+	}
+	if !e.Time.Equal(at) {
+		t.Fatalf("expected heartbeat at %v, got %v", at, e.Time)
+	}
+	if e.Heartbeat.FramesProcessed != 1 || e.Heartbeat.Version.Version == "" || !e.Heartbeat.Connectivity.Camera.Connected {
+		t.Fatalf("unexpected heartbeat payload %+v", e.Heartbeat)
+	}
+}
+
+func TestStartedIsFirstEventAndEmittedOnce(t *testing.T) {
+	config := DefaultDogDoorConfig()
+	config.CameraMode = CameraModePush
+	config.HeartbeatInterval = 0
+	sd := NewSmartDoor(config, newFakePushCamera(), newFakeDoor(), &fakeClassifier{}, WithClock(newFakeClock()))
+	events := sd.Events()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sd.Run(ctx)
+		close(done)
+	}()
+	first := <-events
+	cancel()
+	<-done
+
+	if first.Kind != EventStarted || first.Started == nil {
+		t.Fatalf("expected Started first, got %+v", first)
+	}
+	s := first.Started
+	if s.Version != Version() || len(s.Cameras) != 1 || s.Cameras[0] != defaultCameraID || s.Door != "*smartdoor.fakeDoor" {
+		t.Fatalf("unexpected identities %+v", s)
+	}
+	if s.Config.MinimalRateCameraProcess != time.Second || s.Config.ClassificationUnlockList[0].Label != "dog" ||
+		s.Config.ConflictPolicy != ConflictPreferLock {
+		t.Fatalf("unexpected config %+v", s.Config)
+	}
+	for e := range events {
+		if e.Kind == EventStarted {
+			t.Fatal("expected Started only once")
+		}
+	}
+}
+
+func TestEventSeverities(t *testing.T) {
+	config := dogDoorConfig()
+	config.FailSafeAfterErrors = 1
+	config.DoorCallTimeout = time.Second
+	door := &ctxBlockingDoor{fakeDoor: newFakeDoor(), started: make(chan struct{}, 1), errs: make(chan error, 1)}
+	clock := newFakeClock()
+	sd := NewSmartDoor(config, newFakeCamera(), door, &fakeClassifier{}, WithClock(clock))
+	events := sd.Events()
+
+	sd.handleClassifications(dogBatch())
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	sd.actions.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		sd.executeAction(context.Background(), ActionLock)
+		close(done)
+	}()
+	<-door.started
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+
+	want := []struct {
+		kind     EventKind
+		severity Severity
+	}{
+		{EventAction, SeverityInfo},
+		{EventAction, SeverityInfo},
+		{EventFailSafe, SeverityCritical},
+		{EventError, SeverityCritical},
+	}
+	for _, w := range want {
+		e := <-events
+		if e.Kind != w.kind || e.Severity != w.severity {
+			t.Fatalf("expected %v event at %v, got %v at %v", w.kind, w.severity, e.Kind, e.Severity)
+		}
+	}
+	if got := kindSeverity(EventHeartbeat); got != SeverityDebug {
+		t.Fatalf("expected heartbeats at debug, got %v", got)
+	}
+}
+
+func TestWaitForStateUnblocksOnTargetState(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	classifier := &fakeClassifier{results: []fakeResult{{classifications: dogBatch()}}}
+	sd, _, _, clock := newTestSmartDoor(config, classifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+
+	waited := make(chan error, 1)
+	go func() { waited <- sd.WaitForState(ctx, DoorStateUnlocked) }()
+	waitFor(t, func() bool {
+		sd.mu.Lock()
+		defer sd.mu.Unlock()
+		return len(sd.subscribers) == 1
+	})
+	select {
+	case err := <-waited:
+		t.Fatalf("expected to block while locked, got %v", err)
+	default:
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-waited; err != nil {
+		t.Fatalf("expected to unblock on unlock, got %v", err)
+	}
+	sd.mu.Lock()
+	subscribers := len(sd.subscribers)
+	sd.mu.Unlock()
+	if subscribers != 0 {
+		t.Fatalf("expected the wait to unsubscribe, got %d subscribers", subscribers)
+	}
+	if err := sd.WaitForState(ctx, DoorStateUnlocked); err != nil {
+		t.Fatalf("expected an immediate return in the target state, got %v", err)
+	}
+}
+
+func TestWaitForStateRespectsContext(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() { waited <- sd.WaitForState(ctx, DoorStateLocked) }()
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQuietHoursSuppressNonCriticalNotifications(t *testing.T) {
+	config := dogDoorConfig()
+	quiet, err := NewSchedule("UTC", "22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.QuietHours = quiet
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	notifications := sd.Notifications()
+	events := sd.Events()
+
+	night := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	sd.emit(Event{Kind: EventActionApplied, Action: ActionUnlock, Time: night})
+	sd.emit(Event{Kind: EventFailSafe, Action: ActionLock, Time: night})
+	sd.emit(Event{Kind: EventActionApplied, Action: ActionLock, Time: night.Add(4 * time.Hour)})
+
+	if got := (<-notifications).Kind; got != EventFailSafe {
+		t.Fatalf("expected the critical fail-safe first, got %v", got)
+	}
+	if got := <-notifications; got.Kind != EventActionApplied || got.Action != ActionLock {
+		t.Fatalf("expected the daytime lock, got %+v", got)
+	}
+	select {
+	case got := <-notifications:
+		t.Fatalf("expected nothing else, got %+v", got)
+	default:
+	}
+
+	if got := <-events; got.Kind != EventActionApplied || !got.Quiet {
+		t.Fatalf("expected Events to still see the quiet unlock, got %+v", got)
+	}
+	if n := sd.Stats().NotificationsSuppressed; n != 1 {
+		t.Fatalf("expected 1 suppressed notification, got %d", n)
+	}
+
+	unwatched, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	unwatched.emit(Event{Kind: EventActionApplied, Action: ActionUnlock, Time: night})
+	if n := unwatched.Stats().NotificationsSuppressed; n != 0 {
+		t.Fatalf("expected nothing counted without a Notifications subscriber, got %d", n)
+	}
+}
+
+func TestEmitKeepsExplicitDebugSeverity(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	events := sd.Events()
+	notifications := sd.Notifications()
+
+	sd.emit(Event{Kind: EventAction, Severity: SeverityDebug, Action: ActionUnlock})
+	sd.emit(Event{Kind: EventAction, Action: ActionLock})
+	if got := <-events; got.Severity != SeverityDebug {
+		t.Fatalf("expected the explicit debug severity kept, got %v", got.Severity)
+	}
+	if got := <-events; got.Severity != SeverityInfo {
+		t.Fatalf("expected the kind's severity for an unset one, got %v", got.Severity)
+	}
+	if got := <-notifications; got.Action != ActionLock {
+		t.Fatalf("expected the debug action held back from Notifications, got %+v", got)
+	}
+}
@@ -0,0 +1,33 @@
+package smartdoor
+
+import "context"
+
+// ActionExecutor applies a decided door action, for setups that need more
+// than a single Lock or Unlock call, such as several relays or a
+// confirmation step. A nil error means the door is now in the state the
+// action asks for.
+type ActionExecutor interface {
+	Execute(ctx context.Context, action DoorAction) error
+}
+
+// WithActionExecutor replaces the default executor, which calls the
+// DeviceDoor passed to NewSmartDoor.
+func WithActionExecutor(executor ActionExecutor) Option {
+	return func(sd *SmartDoor) {
+		sd.executor = executor
+	}
+}
+
+type doorExecutor struct {
+	door DeviceDoor
+}
+
+func (e doorExecutor) Execute(ctx context.Context, action DoorAction) error {
+	switch action {
+	case ActionLock:
+		return e.door.Lock(ctx)
+	case ActionUnlock:
+		return e.door.Unlock(ctx)
+	}
+	return nil
+}
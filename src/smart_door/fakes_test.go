@@ -0,0 +1,250 @@
+package smartdoor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).ch
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return &fakeTicker{clock: c, waiter: c.add(d, d)}
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	return &fakeTicker{clock: c, waiter: c.add(d, 0)}
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+func (c *fakeClock) remove(w *fakeWaiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(c.now) {
+				w.at = w.at.Add(w.period)
+			}
+			kept = append(kept, w)
+		}
+	}
+	c.waiters = kept
+}
+
+// BlockUntil waits until n timers or tickers are registered on the clock.
+func (c *fakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+type fakeTicker struct {
+	clock  *fakeClock
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.waiter)
+}
+
+type fakeCamera struct {
+	mu     sync.Mutex
+	events chan DeviceCameraEvent
+	frames []Frame
+	err    error
+	// changing gives every capture distinct frame data.
+	changing bool
+	captures int
+}
+
+func newFakeCamera() *fakeCamera {
+	return &fakeCamera{
+		events: make(chan DeviceCameraEvent, 16),
+		frames: []Frame{{}},
+	}
+}
+
+func (c *fakeCamera) Subscribe() <-chan DeviceCameraEvent {
+	return c.events
+}
+
+func (c *fakeCamera) CaptureFrames(ctx context.Context) ([]Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.captures++
+	if c.changing {
+		return []Frame{{Data: []byte{byte(c.captures)}}}, c.err
+	}
+	return c.frames, c.err
+}
+
+type fakeDoor struct {
+	mu      sync.Mutex
+	events  chan DeviceDoorEvent
+	actions []DoorAction
+}
+
+func newFakeDoor() *fakeDoor {
+	return &fakeDoor{events: make(chan DeviceDoorEvent, 16)}
+}
+
+func (d *fakeDoor) Subscribe() <-chan DeviceDoorEvent {
+	return d.events
+}
+
+func (d *fakeDoor) Lock(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = append(d.actions, ActionLock)
+	return nil
+}
+
+func (d *fakeDoor) Unlock(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = append(d.actions, ActionUnlock)
+	return nil
+}
+
+func (d *fakeDoor) Actions() []DoorAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DoorAction(nil), d.actions...)
+}
+
+// fakeClassifier replays results in order, repeating the last one once
+// the script runs out.
+type fakeClassifier struct {
+	mu      sync.Mutex
+	results []fakeResult
+	calls   int
+	// frames is the batch of the latest call.
+	frames []Frame
+}
+
+type fakeResult struct {
+	classifications [][]Classification
+	err             error
+}
+
+func (c *fakeClassifier) ClassifyFrames(ctx context.Context, frames []Frame) ([][]Classification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	c.frames = frames
+	if len(c.results) == 0 {
+		return nil, nil
+	}
+	r := c.results[0]
+	if len(c.results) > 1 {
+		c.results = c.results[1:]
+	}
+	return r.classifications, r.err
+}
+
+func (c *fakeClassifier) lastFrames() []Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames
+}
+
+func (c *fakeClassifier) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func newTestSmartDoor(config Config, classifier ImageClassifier, opts ...Option) (*SmartDoor, *fakeCamera, *fakeDoor, *fakeClock) {
+	camera := newFakeCamera()
+	door := newFakeDoor()
+	clock := newFakeClock()
+	opts = append([]Option{WithClock(clock)}, opts...)
+	return NewSmartDoor(config, camera, door, classifier, opts...), camera, door, clock
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type fakePushCamera struct {
+	*fakeCamera
+	pushed chan []Frame
+}
+
+func newFakePushCamera() *fakePushCamera {
+	return &fakePushCamera{fakeCamera: newFakeCamera(), pushed: make(chan []Frame)}
+}
+
+func (c *fakePushCamera) Frames() <-chan []Frame {
+	return c.pushed
+}
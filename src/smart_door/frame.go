@@ -0,0 +1,45 @@
+package smartdoor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+type FrameFormat int
+
+const (
+	// FrameFormatRaw is device-specific bytes only the classifier
+	// understands.
+	FrameFormatRaw FrameFormat = iota
+	FrameFormatJPEG
+	FrameFormatPNG
+)
+
+var ErrUndecodableFrame = errors.New("smartdoor: frame format cannot be decoded")
+
+// FrameDecoder turns a frame into an image for preprocessing such as
+// snapshots or cropping. Classifiers keep receiving the encoded bytes.
+type FrameDecoder interface {
+	DecodeFrame(frame Frame) (image.Image, error)
+}
+
+// StandardFrameDecoder decodes JPEG and PNG frames with the standard
+// library. Raw frames report ErrUndecodableFrame.
+type StandardFrameDecoder struct{}
+
+func (StandardFrameDecoder) DecodeFrame(frame Frame) (image.Image, error) {
+	r := bytes.NewReader(frame.Data)
+	switch frame.Format {
+	case FrameFormatJPEG:
+		return jpeg.Decode(r)
+	case FrameFormatPNG:
+		return png.Decode(r)
+	case FrameFormatRaw:
+		return nil, ErrUndecodableFrame
+	}
+	return nil, fmt.Errorf("%w: unknown format %d", ErrUndecodableFrame, frame.Format)
+}
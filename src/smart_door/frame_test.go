@@ -0,0 +1,64 @@
+package smartdoor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestStandardFrameDecoderDecodesJPEG(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 4, 2))
+	for i := range src.Pix {
+		src.Pix[i] = 200
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := StandardFrameDecoder{}.DecodeFrame(Frame{Data: buf.Bytes(), Format: FrameFormatJPEG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got != src.Bounds() {
+		t.Fatalf("expected bounds %v, got %v", src.Bounds(), got)
+	}
+	if y := color.GrayModel.Convert(img.At(1, 1)).(color.Gray).Y; y < 190 || y > 210 {
+		t.Fatalf("expected a pixel near 200, got %d", y)
+	}
+}
+
+func TestStandardFrameResizerKeepsFormat(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 8, 4))
+	for i := range src.Pix {
+		src.Pix[i] = 200
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	frame, err := StandardFrameResizer{}.ResizeFrame(Frame{Data: buf.Bytes(), Format: FrameFormatJPEG}, Resolution{Width: 2, Height: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(frame.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 2, 2) || frame.Width != 2 || frame.Height != 2 {
+		t.Fatalf("expected a 2x2 frame, got %v (%dx%d)", got, frame.Width, frame.Height)
+	}
+	if y := color.GrayModel.Convert(img.At(1, 1)).(color.Gray).Y; y < 190 || y > 210 {
+		t.Fatalf("expected a pixel near 200, got %d", y)
+	}
+}
+
+func TestStandardFrameDecoderRejectsRaw(t *testing.T) {
+	if _, err := (StandardFrameDecoder{}).DecodeFrame(Frame{Data: []byte{1, 2, 3}}); !errors.Is(err, ErrUndecodableFrame) {
+		t.Fatalf("expected ErrUndecodableFrame, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build smartdoor_debug
+
+package smartdoor
+
+import "time"
+
+// FrozenState is the controller's state as of a single instant, for
+// debugging scenarios where separate getters, each taking the lock on its
+// own, could interleave with an update.
+type FrozenState struct {
+	IntendedState DoorState
+	DoorState     DoorState
+	// LastDetection and LastNoActionReason are from the latest cycle.
+	LastDetection      Detection
+	LastNoActionReason NoActionReason
+	CooldownRemaining  time.Duration
+	// Override is the active ForceLock or ForceUnlock, ActionNone if there
+	// is none, held until OverrideUntil.
+	Override      DoorAction
+	OverrideUntil time.Time
+	Connectivity  Connectivity
+	// Profile is the name of the profile in effect, empty for the base
+	// config.
+	Profile string
+}
+
+// FreezeState captures FrozenState under one lock. It is only built with
+// the smartdoor_debug tag.
+func (sd *SmartDoor) FreezeState() FrozenState {
+	now := sd.clock.Now()
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	state := FrozenState{
+		IntendedState:      sd.intendedStateLocked(),
+		DoorState:          sd.doorState,
+		LastDetection:      sd.lastDecision.Detection,
+		LastNoActionReason: sd.noActionReason,
+		CooldownRemaining:  sd.cooldownRemainingLocked(now, false),
+		Connectivity:       sd.connectivity.clone(),
+	}
+	if sd.override != nil {
+		state.Override = sd.override.action
+		state.OverrideUntil = sd.override.until
+	}
+	if p := sd.activeProfileLocked(); p != nil {
+		state.Profile = p.Name
+	}
+	return state
+}
@@ -0,0 +1,86 @@
+//go:build smartdoor_debug
+
+package smartdoor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFreezeStateIsConsistentUnderConcurrentUpdates(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{}, WithCamera("cam1", newFakeCamera(), nil))
+	always, err := NewSchedule("UTC", "00:00-00:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	run := func(step func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					step(i)
+				}
+			}
+		}()
+	}
+	run(func(i int) {
+		batch := dogBatch()
+		if i%2 == 1 {
+			batch = cat
+		}
+		sd.handleCycle(context.Background(), CycleResult{Classifications: batch, Outcome: OutcomeDecided})
+		applyActions(sd)
+	})
+	run(func(i int) {
+		switch i % 3 {
+		case 0:
+			sd.ForceLock(clock.Now().Add(time.Hour))
+		case 1:
+			sd.ForceUnlock(clock.Now().Add(time.Hour))
+		default:
+			sd.ClearOverride()
+		}
+	})
+	run(func(i int) {
+		event := CameraEventConnected
+		if i%2 == 1 {
+			event = CameraEventDisconnected
+		}
+		sd.handleCameraEvent("cam1", event)
+		sd.handleDoorEvent(DeviceDoorEvent(i % 2))
+		if i%50 == 0 {
+			sd.SetProfiles(Profile{Name: "always", Schedule: always, Config: config})
+		}
+	})
+
+	for range 2000 {
+		state := sd.FreezeState()
+		if state.Override != ActionNone && state.IntendedState != actionState(state.Override) {
+			t.Fatalf("intended state %v disagrees with override %v", state.IntendedState, state.Override)
+		}
+		all := true
+		for _, camera := range state.Connectivity.Cameras {
+			all = all && camera.Connected
+		}
+		if len(state.Connectivity.Cameras) > 0 && state.Connectivity.Camera.Connected != all {
+			t.Fatalf("aggregate camera connectivity disagrees with per-camera state: %+v", state.Connectivity)
+		}
+		if state.Profile != "" && state.Profile != "always" {
+			t.Fatalf("unexpected profile %q", state.Profile)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
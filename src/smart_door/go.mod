@@ -0,0 +1,3 @@
+module smart_door
+
+go 1.22
@@ -0,0 +1,83 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HealthChecker is implemented by cameras, doors and classifiers that can
+// report their health on demand, as opposed to connectivity events, which
+// only report changes. Devices without it are assumed healthy.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthReport is the result of CheckHealth. A nil error means healthy.
+type HealthReport struct {
+	// Cameras and Classifiers are keyed by camera ID, the classifier being
+	// the one assigned to that camera.
+	Cameras     map[string]error
+	Classifiers map[string]error
+	Door        error
+}
+
+// Healthy reports whether every check passed.
+func (r HealthReport) Healthy() bool {
+	return r.Err() == nil
+}
+
+// Err combines every failed check into one error, nil when all passed.
+func (r HealthReport) Err() error {
+	var failures []string
+	for id, err := range r.Cameras {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("camera %s: %v", id, err))
+		}
+	}
+	for id, err := range r.Classifiers {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("classifier for %s: %v", id, err))
+		}
+	}
+	if r.Door != nil {
+		failures = append(failures, fmt.Sprintf("door: %v", r.Door))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	return fmt.Errorf("smartdoor: unhealthy: %s", strings.Join(failures, "; "))
+}
+
+// CheckHealth probes every camera, its classifier and the door through
+// HealthChecker, for a /healthz endpoint. A classifier that cannot check
+// itself is unhealthy only while the classifier breaker is open.
+func (sd *SmartDoor) CheckHealth(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Cameras:     make(map[string]error),
+		Classifiers: make(map[string]error),
+		Door:        checkHealth(ctx, sd.door),
+	}
+	sd.mu.Lock()
+	breakerOpen := sd.clock.Now().Before(sd.breakerOpenUntil)
+	sd.mu.Unlock()
+
+	for _, slot := range sd.cameras {
+		report.Cameras[slot.id] = checkHealth(ctx, slot.camera)
+		if _, ok := slot.classifier.(HealthChecker); ok || !breakerOpen {
+			report.Classifiers[slot.id] = checkHealth(ctx, slot.classifier)
+		} else {
+			report.Classifiers[slot.id] = ErrBreakerOpen
+		}
+	}
+	return report
+}
+
+func checkHealth(ctx context.Context, device any) error {
+	if checker, ok := device.(HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type checkedCamera struct {
+	*fakeCamera
+	err error
+}
+
+func (c *checkedCamera) HealthCheck(ctx context.Context) error {
+	return c.err
+}
+
+type checkedDoor struct {
+	*fakeDoor
+	err error
+}
+
+func (d *checkedDoor) HealthCheck(ctx context.Context) error {
+	return d.err
+}
+
+func TestCheckHealthAggregatesMixedHealth(t *testing.T) {
+	door := &checkedDoor{fakeDoor: newFakeDoor(), err: errors.New("relay not responding")}
+	sd := NewSmartDoor(dogDoorConfig(), &checkedCamera{fakeCamera: newFakeCamera()}, door, &fakeClassifier{},
+		WithClock(newFakeClock()),
+		WithCamera("garage", &checkedCamera{fakeCamera: newFakeCamera(), err: errors.New("no signal")}, nil),
+		WithCamera("porch", newFakeCamera(), nil),
+	)
+
+	report := sd.CheckHealth(context.Background())
+	if report.Healthy() {
+		t.Fatal("expected an unhealthy report")
+	}
+	if report.Cameras[defaultCameraID] != nil || report.Cameras["porch"] != nil {
+		t.Fatalf("expected the primary and unchecked cameras healthy, got %v", report.Cameras)
+	}
+	if report.Cameras["garage"] == nil || report.Door == nil {
+		t.Fatalf("expected the garage camera and door unhealthy, got %+v", report)
+	}
+	for id, err := range report.Classifiers {
+		if err != nil {
+			t.Fatalf("expected classifier for %s healthy, got %v", id, err)
+		}
+	}
+	msg := report.Err().Error()
+	if !strings.Contains(msg, "camera garage: no signal") || !strings.Contains(msg, "door: relay not responding") {
+		t.Fatalf("expected both failures in %q", msg)
+	}
+
+	door.err = nil
+	sd.mu.Lock()
+	sd.breakerOpenUntil = sd.clock.Now().Add(time.Minute)
+	sd.mu.Unlock()
+	report = sd.CheckHealth(context.Background())
+	if !errors.Is(report.Classifiers["porch"], ErrBreakerOpen) || report.Door != nil {
+		t.Fatalf("expected the open breaker to fail the classifier probe, got %+v", report)
+	}
+}
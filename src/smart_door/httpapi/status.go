@@ -0,0 +1,150 @@
+// Package httpapi serves a SmartDoor over HTTP.
+package httpapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	smartdoor "smart_door"
+)
+
+//go:embed status.html
+var statusPage string
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"connected": func(c smartdoor.DeviceConnectivity) string {
+		if c.Connected {
+			return "connected"
+		}
+		return "disconnected"
+	},
+}).Parse(statusPage))
+
+type statusView struct {
+	State           smartdoor.DoorState
+	Reason          string
+	Detection       smartdoor.Detection
+	Classifications []smartdoor.Classification
+	Snapshot        bool
+	Connectivity    smartdoor.Connectivity
+	Token           string
+}
+
+// StatusHandler serves a small status page for the household: the door
+// state and why, the last detection with its snapshot, connectivity, and
+// lock and unlock buttons. The buttons POST to lock and unlock, which
+// ForceLock or ForceUnlock the door for override; ClearOverride hands it
+// back to detection sooner. Mount it with http.StripPrefix under a path
+// of its own.
+//
+// The buttons carry a token drawn when the handler is built, and lock and
+// unlock refuse a POST without it or from another origin, so a page on
+// another site cannot work the door through a visitor's browser.
+func StatusHandler(sd *smartdoor.SmartDoor, override time.Duration) http.Handler {
+	token := newToken()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		detection, _, _ := sd.LastDecision()
+		_, hasSnapshot := sd.LastSnapshot()
+		var classifications []smartdoor.Classification
+		for _, frame := range sd.LastClassifications() {
+			classifications = append(classifications, frame...)
+		}
+		view := statusView{
+			State:           sd.DoorState(),
+			Reason:          sd.StateReason(),
+			Detection:       detection,
+			Classifications: classifications,
+			Snapshot:        hasSnapshot,
+			Connectivity:    sd.Connectivity(),
+			Token:           token,
+		}
+		var page bytes.Buffer
+		if err := statusTemplate.Execute(&page, view); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		frame, ok := sd.LastSnapshot()
+		contentType := map[smartdoor.FrameFormat]string{
+			smartdoor.FrameFormatJPEG: "image/jpeg",
+			smartdoor.FrameFormatPNG:  "image/png",
+		}[frame.Format]
+		if !ok || contentType == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(frame.Data)
+	})
+	control := func(force func(time.Time)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !allow(w, r, http.MethodPost) {
+				return
+			}
+			if !sameOrigin(r) || subtle.ConstantTimeCompare([]byte(r.PostFormValue("token")), []byte(token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			force(sd.Now().Add(override))
+			http.Redirect(w, r, "./", http.StatusSeeOther)
+		}
+	}
+	mux.HandleFunc("/lock", control(sd.ForceLock))
+	mux.HandleFunc("/unlock", control(sd.ForceUnlock))
+	return mux
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("httpapi: read random token: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// sameOrigin reports whether r came from a page of this host, judged by
+// its Origin header, or Referer when a browser sends no Origin. Requests
+// with neither, such as from curl, are left to the token.
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+	u, err := url.Parse(source)
+	return err == nil && u.Host == r.Host
+}
+
+// allow reports whether r uses method, answering 405 when it does not.
+// GET also admits HEAD.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method || (method == http.MethodGet && r.Method == http.MethodHead) {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
@@ -0,0 +1,122 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	smartdoor "smart_door"
+	"smart_door/smartdoortest"
+)
+
+func TestStatusPageRendersStateAndControls(t *testing.T) {
+	h := smartdoortest.NewHarness(t, smartdoor.DefaultDogDoorConfig())
+	h.Camera.SetFrames([]smartdoor.Frame{{Data: []byte("jpeg"), Format: smartdoor.FrameFormatJPEG}}, nil)
+	h.DoorEvent(smartdoor.DoorEventConnected)
+	dog := [][]smartdoor.Classification{{{Label: "dog", Confidence: 0.94}}}
+	h.Cycle(dog)
+	sd := h.SmartDoor
+	server := httptest.NewServer(StatusHandler(sd, time.Hour))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	for _, want := range []string{
+		"Door unlocked",
+		"unlocked: dog 0.94 on cam0",
+		"dog: dog 0.94",
+		`<img src="snapshot"`,
+		"Door: connected",
+		`<form method="post" action="lock">`,
+		`<form method="post" action="unlock">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected %q in the page:\n%s", want, page)
+		}
+	}
+
+	resp, err = http.Get(server.URL + "/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(snapshot) != "jpeg" || resp.Header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected the trigger frame, got %q (%s)", snapshot, resp.Header.Get("Content-Type"))
+	}
+
+	match := regexp.MustCompile(`name="token" value="([0-9a-f]+)"`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatalf("expected a token in the page:\n%s", page)
+	}
+	token := match[1]
+	for name, post := range map[string]struct {
+		form   url.Values
+		origin string
+	}{
+		"no token":     {url.Values{}, ""},
+		"wrong token":  {url.Values{"token": {"wrong"}}, ""},
+		"cross origin": {url.Values{"token": {token}}, "http://attacker.example"},
+	} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/unlock", strings.NewReader(post.form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if post.origin != "" {
+			req.Header.Set("Origin", post.origin)
+		}
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: expected the unlock refused, got %s", name, resp.Status)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/lock", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", server.URL)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the redirect back to the page, got %s", resp.Status)
+	}
+	h.Cycle(dog)
+	if _, _, reason := sd.LastDecision(); reason != smartdoor.ReasonOverridden {
+		t.Fatalf("expected the lock button to override detection, got %v", reason)
+	}
+	if got := h.DoorActions(); !reflect.DeepEqual(got, []smartdoor.DoorAction{smartdoor.ActionUnlock, smartdoor.ActionLock}) {
+		t.Fatalf("expected the door unlocked then locked, got %v", got)
+	}
+
+	for _, path := range []string{"/unlock", "/missing"} {
+		resp, err = http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want := map[string]int{"/unlock": http.StatusMethodNotAllowed, "/missing": http.StatusNotFound}[path]; resp.StatusCode != want {
+			t.Fatalf("%s: expected %d, got %s", path, want, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package smartdoor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInjectionDisabled is returned by InjectCameraEvent and InjectDoorEvent
+// unless Config.AllowEventInjection is set.
+var ErrInjectionDisabled = errors.New("smartdoor: event injection is disabled")
+
+// InjectCameraEvent handles event as if camera had sent it, so alerting and
+// automation can be drilled without unplugging anything. The event goes
+// through the same handling as a real one; the camera's own next event
+// overrides it.
+func (sd *SmartDoor) InjectCameraEvent(camera string, event DeviceCameraEvent) error {
+	if !sd.currentConfig().AllowEventInjection {
+		return ErrInjectionDisabled
+	}
+	if !sd.hasCamera(camera) {
+		return fmt.Errorf("smartdoor: no camera %q", camera)
+	}
+	sd.logger.Info(fmt.Sprintf("camera %s: injecting %s event", camera, connectedName(event == CameraEventConnected)))
+	sd.handleCameraEvent(camera, event)
+	return nil
+}
+
+// InjectDoorEvent handles event as if the door had sent it, like
+// InjectCameraEvent.
+func (sd *SmartDoor) InjectDoorEvent(event DeviceDoorEvent) error {
+	if !sd.currentConfig().AllowEventInjection {
+		return ErrInjectionDisabled
+	}
+	sd.logger.Info(fmt.Sprintf("door: injecting %s event", connectedName(event == DoorEventConnected)))
+	sd.handleDoorEvent(event)
+	return nil
+}
+
+func (sd *SmartDoor) hasCamera(id string) bool {
+	for _, slot := range sd.cameras {
+		if slot.id == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package smartdoor
+
+import (
+	"math/rand"
+	"time"
+)
+
+// WithRandSource sets the source every randomized behavior, such as
+// Config.PollJitter, draws from. The default is seeded from the time the
+// SmartDoor is created; pass a fixed seed for reproducible runs.
+func WithRandSource(source rand.Source) Option {
+	return func(sd *SmartDoor) {
+		sd.rand = rand.New(source)
+	}
+}
+
+// jitter returns a random duration in [0, limit).
+func (sd *SmartDoor) jitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return time.Duration(sd.rand.Int63n(int64(limit)))
+}
@@ -0,0 +1,55 @@
+package smartdoor
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSameSeedGivesSameJitter(t *testing.T) {
+	jitters := func(seed int64) []time.Duration {
+		sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithRandSource(rand.NewSource(seed)))
+		var got []time.Duration
+		for i := 0; i < 8; i++ {
+			j := sd.jitter(time.Second)
+			if j < 0 || j >= time.Second {
+				t.Fatalf("jitter %s outside [0, 1s)", j)
+			}
+			got = append(got, j)
+		}
+		return got
+	}
+
+	a, b, other := jitters(42), jitters(42), jitters(7)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("jitter %d differs for the same seed: %s vs %s", i, a[i], b[i])
+		}
+	}
+	same := true
+	for i := range a {
+		same = same && a[i] == other[i]
+	}
+	if same {
+		t.Fatal("expected a different seed to give a different sequence")
+	}
+}
+
+func TestPollWaitIncludesJitter(t *testing.T) {
+	config := Config{MinimalRateCameraProcess: time.Second, PollJitter: time.Second}
+	want := time.Second + time.Duration(rand.New(rand.NewSource(1)).Int63n(int64(time.Second)))
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{}, WithRandSource(rand.NewSource(1)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.pollCamera(ctx)
+	clock.BlockUntil(1)
+
+	clock.mu.Lock()
+	got := clock.waiters[0].at.Sub(clock.now)
+	clock.mu.Unlock()
+	if got != want {
+		t.Fatalf("expected first wait %s, got %s", want, got)
+	}
+}
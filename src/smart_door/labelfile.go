@@ -0,0 +1,244 @@
+package smartdoor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Labels are the classification lists of a labels file, which
+// Config.LabelSource points at so a retrained model's label set can be
+// swapped without touching the rest of the config.
+//
+// A .json file holds an object with "unlock", "lock" and "ignore" arrays
+// of {"label", "min_confidence", "priority", "hold_duration",
+// "min_present_duration", "adaptive": {"percentile", "min", "max"}}
+// entries, durations written as "30s"; unknown fields are rejected. A .csv
+// file holds one entry per row as
+// list,label,min_confidence[,priority,hold_duration,min_present_duration,
+// adaptive_percentile,adaptive_min,adaptive_max], where list is unlock,
+// lock or ignore and empty trailing cells are zero; a first row starting
+// with "list" is a header. Every field maps onto ClassificationConfig.
+type Labels struct {
+	Unlock []ClassificationConfig
+	Lock   []ClassificationConfig
+	Ignore []ClassificationConfig
+}
+
+type labelEntry struct {
+	Label              string        `json:"label"`
+	MinConfidence      float64       `json:"min_confidence"`
+	Priority           int           `json:"priority"`
+	HoldDuration       labelDuration `json:"hold_duration"`
+	MinPresentDuration labelDuration `json:"min_present_duration"`
+	Adaptive           struct {
+		Percentile float64 `json:"percentile"`
+		Min        float64 `json:"min"`
+		Max        float64 `json:"max"`
+	} `json:"adaptive"`
+}
+
+// labelDuration reads a duration written as time.ParseDuration takes it.
+type labelDuration time.Duration
+
+func (d *labelDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = labelDuration(parsed)
+	return nil
+}
+
+func (e labelEntry) config() ClassificationConfig {
+	return ClassificationConfig{
+		Label:              e.Label,
+		MinConfidence:      e.MinConfidence,
+		Priority:           e.Priority,
+		HoldDuration:       time.Duration(e.HoldDuration),
+		MinPresentDuration: time.Duration(e.MinPresentDuration),
+		Adaptive: AdaptiveThreshold{
+			Percentile: e.Adaptive.Percentile,
+			Min:        e.Adaptive.Min,
+			Max:        e.Adaptive.Max,
+		},
+	}
+}
+
+// LoadLabels reads and validates the labels file at path, reporting every
+// bad entry at once.
+func LoadLabels(path string) (Labels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Labels{}, fmt.Errorf("smartdoor: labels: %w", err)
+	}
+	defer file.Close()
+
+	var entries map[string][]labelEntry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		entries, err = decodeJSONLabels(file)
+	case ".csv":
+		entries, err = decodeCSVLabels(file)
+	default:
+		err = fmt.Errorf("unknown labels file type %q", ext)
+	}
+	if err != nil {
+		return Labels{}, fmt.Errorf("smartdoor: labels %s: %w", path, err)
+	}
+
+	var labels Labels
+	var errs []error
+	for name, list := range map[string]*[]ClassificationConfig{
+		"unlock": &labels.Unlock,
+		"lock":   &labels.Lock,
+		"ignore": &labels.Ignore,
+	} {
+		for i, entry := range entries[name] {
+			if strings.TrimSpace(entry.Label) == "" {
+				errs = append(errs, fmt.Errorf("%s[%d]: label must not be empty", name, i))
+			}
+			if entry.MinConfidence < 0 || entry.MinConfidence > 1 {
+				errs = append(errs, fmt.Errorf("%s[%d]: min_confidence %v must be within [0, 1]", name, i, entry.MinConfidence))
+			}
+			*list = append(*list, entry.config())
+		}
+		delete(entries, name)
+	}
+	for name := range entries {
+		errs = append(errs, fmt.Errorf("unknown list %q", name))
+	}
+	if len(labels.Unlock) == 0 {
+		errs = append(errs, errors.New("no unlock labels"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Labels{}, fmt.Errorf("smartdoor: labels %s: %w", path, err)
+	}
+	return labels, nil
+}
+
+func decodeJSONLabels(r io.Reader) (map[string][]labelEntry, error) {
+	var entries map[string][]labelEntry
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func decodeCSVLabels(r io.Reader) (map[string][]labelEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && strings.EqualFold(rows[0][0], "list") {
+		rows = rows[1:]
+	}
+
+	entries := make(map[string][]labelEntry)
+	for i, row := range rows {
+		if len(row) < 3 || len(row) > len(csvLabelColumns) {
+			return nil, fmt.Errorf("row %d: want list,label,min_confidence and up to %d more fields, got %d", i+1, len(csvLabelColumns)-3, len(row))
+		}
+		entry := labelEntry{Label: row[1]}
+		var errs []error
+		for j, cell := range row[2:] {
+			column := csvLabelColumns[j+2]
+			if err := parseLabelCell(&entry, column, cell); err != nil {
+				errs = append(errs, fmt.Errorf("row %d: %s: %w", i+1, column, err))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(row[0])
+		entries[name] = append(entries[name], entry)
+	}
+	return entries, nil
+}
+
+var csvLabelColumns = []string{
+	"list", "label", "min_confidence", "priority", "hold_duration",
+	"min_present_duration", "adaptive_percentile", "adaptive_min", "adaptive_max",
+}
+
+// parseLabelCell sets the field of entry for column from cell. An empty
+// cell leaves it zero, except min_confidence, which is required.
+func parseLabelCell(entry *labelEntry, column, cell string) error {
+	if cell == "" && column != "min_confidence" {
+		return nil
+	}
+	var err error
+	switch column {
+	case "min_confidence":
+		entry.MinConfidence, err = strconv.ParseFloat(cell, 64)
+	case "priority":
+		entry.Priority, err = strconv.Atoi(cell)
+	case "hold_duration", "min_present_duration":
+		var d time.Duration
+		d, err = time.ParseDuration(cell)
+		if column == "hold_duration" {
+			entry.HoldDuration = labelDuration(d)
+		} else {
+			entry.MinPresentDuration = labelDuration(d)
+		}
+	case "adaptive_percentile":
+		entry.Adaptive.Percentile, err = strconv.ParseFloat(cell, 64)
+	case "adaptive_min":
+		entry.Adaptive.Min, err = strconv.ParseFloat(cell, 64)
+	case "adaptive_max":
+		entry.Adaptive.Max, err = strconv.ParseFloat(cell, 64)
+	}
+	return err
+}
+
+// ResolveLabels returns the config with its classification lists replaced
+// by those of the LabelSource file, or unchanged if it has none.
+// UpdateConfig and Run resolve labels themselves.
+func (c Config) ResolveLabels() (Config, error) {
+	if c.LabelSource == "" {
+		return c, nil
+	}
+	labels, err := LoadLabels(c.LabelSource)
+	if err != nil {
+		return c, err
+	}
+	c.ClassificationUnlockList = labels.Unlock
+	c.ClassificationLockList = labels.Lock
+	c.IgnoreList = labels.Ignore
+	return c, nil
+}
+
+// resolveLabelsLocked reloads the LabelSource of the base config. The file
+// is read under sd.mu so a concurrent UpdateConfig cannot be overwritten by
+// the labels resolved from the config it replaced.
+func (sd *SmartDoor) resolveLabelsLocked() error {
+	if sd.config.LabelSource == "" {
+		return nil
+	}
+
+	resolved, err := sd.config.ResolveLabels()
+	if err == nil {
+		err = resolved.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	sd.config = resolved
+	return nil
+}
@@ -0,0 +1,139 @@
+package smartdoor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLabels(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLabelSourcePopulatesEffectiveConfig(t *testing.T) {
+	want := Labels{
+		Unlock: []ClassificationConfig{{Label: "golden retriever", MinConfidence: 0.7, Priority: 1}},
+		Lock:   []ClassificationConfig{{Label: "tabby", MinConfidence: 0.4}},
+		Ignore: []ClassificationConfig{{Label: "person", MinConfidence: 0.5}},
+	}
+	for name, content := range map[string]string{
+		"labels.json": `{
+			"unlock": [{"label": "golden retriever", "min_confidence": 0.7, "priority": 1}],
+			"lock": [{"label": "tabby", "min_confidence": 0.4}],
+			"ignore": [{"label": "person", "min_confidence": 0.5}]
+		}`,
+		"labels.csv": "list,label,min_confidence,priority\nunlock,golden retriever,0.7,1\nlock,tabby,0.4\nignore,person,0.5\n",
+	} {
+		config := DefaultDogDoorConfig()
+		config.LabelSource = writeLabels(t, name, content)
+		sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+		if err := sd.UpdateConfig(config); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		effective := sd.EffectiveConfig()
+		got := Labels{effective.ClassificationUnlockList, effective.ClassificationLockList, effective.IgnoreList}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, got)
+		}
+		sd.handleClassifications([][]Classification{{{Label: "golden retriever", Confidence: 0.8}}})
+		expectActions(t, sd, ActionUnlock)
+	}
+}
+
+func TestLoadLabelsReportsBadEntries(t *testing.T) {
+	path := writeLabels(t, "labels.json", `{
+		"unlock": [{"label": " ", "min_confidence": 0.7}],
+		"lock": [{"label": "cat", "min_confidence": 1.5}],
+		"other": []
+	}`)
+	_, err := LoadLabels(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"unlock[0]: label must not be empty", "lock[0]: min_confidence 1.5", `unknown list "other"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestRunningInstanceIgnoresLabelSourceOnSecondRun(t *testing.T) {
+	config := DefaultDogDoorConfig()
+	config.LabelSource = writeLabels(t, "labels.csv", "list,label,min_confidence\nunlock,dog,0.7\nlock,cat,0.4\n")
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.Run(ctx)
+	clock.BlockUntil(1)
+	if err := os.WriteFile(config.LabelSource, []byte("list,label\nother,dog\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sd.Run(ctx); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning before the label file is read, got %v", err)
+	}
+	if got := sd.EffectiveConfig().ClassificationUnlockList; len(got) != 1 || got[0].Label != "dog" {
+		t.Fatalf("expected the labels resolved at start kept, got %+v", got)
+	}
+	if err := sd.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLabelSourceKeepsPerLabelRules(t *testing.T) {
+	want := ClassificationConfig{
+		Label:              "dog",
+		MinConfidence:      0.6,
+		Priority:           2,
+		HoldDuration:       30 * time.Second,
+		MinPresentDuration: 2 * time.Second,
+		Adaptive:           AdaptiveThreshold{Percentile: 20, Min: 0.5, Max: 0.9},
+	}
+	for name, content := range map[string]string{
+		"labels.json": `{"unlock": [{"label": "dog", "min_confidence": 0.6, "priority": 2,
+			"hold_duration": "30s", "min_present_duration": "2s",
+			"adaptive": {"percentile": 20, "min": 0.5, "max": 0.9}}]}`,
+		"labels.csv": "unlock,dog,0.6,2,30s,2s,20,0.5,0.9\n",
+	} {
+		labels, err := LoadLabels(writeLabels(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(labels.Unlock) != 1 || labels.Unlock[0] != want {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, labels.Unlock)
+		}
+	}
+
+	if _, err := LoadLabels(writeLabels(t, "labels.json", `{"unlock": [{"label": "dog", "min_confidence": 0.6, "hold": "30s"}]}`)); err == nil {
+		t.Fatal("expected an unknown field rejected")
+	}
+}
+
+func TestSetProfilesResolvesLabelSource(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(DefaultDogDoorConfig(), &fakeClassifier{})
+	always, err := NewSchedule("UTC", "00:00-23:59")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := StrictSecurityConfig()
+	config.ClassificationUnlockList = nil
+	config.LabelSource = writeLabels(t, "labels.csv", "unlock,golden retriever,0.8\nlock,cat,0.3\n")
+	if err := sd.SetProfiles(Profile{Name: "always", Schedule: always, Config: config}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sd.EffectiveConfig().ClassificationUnlockList; len(got) != 1 || got[0].Label != "golden retriever" {
+		t.Fatalf("expected the profile's labels file applied, got %+v", got)
+	}
+}
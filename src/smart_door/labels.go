@@ -0,0 +1,59 @@
+package smartdoor
+
+import (
+	"strings"
+	"time"
+)
+
+// DisableLabel takes label out of consideration for d: entries for it are
+// dropped from the unlock, lock and ignore lists of the effective config,
+// so it triggers nothing until d passes or EnableLabel is called. Disabling
+// a label again restarts the timer.
+func (sd *SmartDoor) DisableLabel(label string, d time.Duration) {
+	until := sd.clock.Now().Add(d)
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.disabledLabels == nil {
+		sd.disabledLabels = make(map[string]time.Time)
+	}
+	sd.disabledLabels[strings.ToLower(label)] = until
+}
+
+// EnableLabel ends a DisableLabel early.
+func (sd *SmartDoor) EnableLabel(label string) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	delete(sd.disabledLabels, strings.ToLower(label))
+}
+
+// withoutDisabledLabelsLocked returns config with the entries of every
+// label still disabled removed, forgetting those that have expired. It must
+// be called with sd.mu held.
+func (sd *SmartDoor) withoutDisabledLabelsLocked(config Config) Config {
+	if len(sd.disabledLabels) == 0 {
+		return config
+	}
+	now := sd.clock.Now()
+	for label, until := range sd.disabledLabels {
+		if !now.Before(until) {
+			delete(sd.disabledLabels, label)
+		}
+	}
+	if len(sd.disabledLabels) == 0 {
+		return config
+	}
+	config.ClassificationUnlockList = sd.enabledEntriesLocked(config.ClassificationUnlockList)
+	config.ClassificationLockList = sd.enabledEntriesLocked(config.ClassificationLockList)
+	config.IgnoreList = sd.enabledEntriesLocked(config.IgnoreList)
+	return config
+}
+
+func (sd *SmartDoor) enabledEntriesLocked(list []ClassificationConfig) []ClassificationConfig {
+	var kept []ClassificationConfig
+	for _, entry := range list {
+		if _, disabled := sd.disabledLabels[strings.ToLower(entry.Label)]; !disabled {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
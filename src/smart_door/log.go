@@ -0,0 +1,55 @@
+package smartdoor
+
+import "fmt"
+
+type Logger interface {
+	Info(message string)
+	Error(message string)
+}
+
+func WithLogger(logger Logger) Option {
+	return func(sd *SmartDoor) {
+		sd.logger = logger
+	}
+}
+
+// DebugLogger is implemented by loggers that take debug-level messages,
+// such as redundant device events. Others do not receive them.
+type DebugLogger interface {
+	Debug(message string)
+}
+
+func (sd *SmartDoor) debug(message string) {
+	if logger, ok := sd.logger.(DebugLogger); ok {
+		logger.Debug(message)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+// logCycle logs the outcome of one controller cycle. Cycles that decided
+// an action or failed are always logged; routine cycles are logged one in
+// every Config.LogSampleEvery (every cycle when below two) so a fast
+// capture rate does not flood constrained hardware with identical lines.
+func (sd *SmartDoor) logCycle(result CycleResult, decision decision) {
+	sd.logCycles++
+
+	switch {
+	case result.Outcome == OutcomeError:
+		sd.logger.Error(fmt.Sprintf("cycle %d failed", sd.logCycles))
+	case decision.Action != ActionNone:
+		sd.logger.Info(fmt.Sprintf("cycle %d: %s detected, %s", sd.logCycles, decision.Detection, decision.Action))
+	default:
+		every := sd.currentConfig().LogSampleEvery
+		if every < 1 {
+			every = 1
+		}
+		if (sd.logCycles-1)%every != 0 {
+			return
+		}
+		sd.logger.Info(fmt.Sprintf("cycle %d: %s detected", sd.logCycles, decision.Detection))
+	}
+}
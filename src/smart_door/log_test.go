@@ -0,0 +1,89 @@
+package smartdoor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	infos  []string
+	errs   []string
+	debugs []string
+}
+
+func (l *recordingLogger) Debug(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugs = append(l.debugs, message)
+}
+
+func (l *recordingLogger) Info(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, message)
+}
+
+func (l *recordingLogger) Error(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errs = append(l.errs, message)
+}
+
+func TestRoutineCycleLogsAreSampled(t *testing.T) {
+	config := dogDoorConfig()
+	config.LogSampleEvery = 5
+	logger := &recordingLogger{}
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{}, WithLogger(logger))
+
+	cycle := func(result CycleResult) {
+		sd.logCycle(result, sd.handleCycle(context.Background(), result))
+	}
+
+	for i := 0; i < 10; i++ {
+		cycle(CycleResult{Classifications: noneBatch(), Outcome: OutcomeDecided})
+	}
+	if len(logger.infos) != 2 {
+		t.Fatalf("expected 2 of 10 routine cycles logged, got %v", logger.infos)
+	}
+
+	cycle(CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided})
+	cycle(CycleResult{Outcome: OutcomeError})
+	cycle(CycleResult{Outcome: OutcomeError})
+
+	if len(logger.infos) != 3 || !strings.Contains(logger.infos[2], "unlock") {
+		t.Fatalf("expected the unlock to be logged, got %v", logger.infos)
+	}
+	if len(logger.errs) != 2 {
+		t.Fatalf("expected every error cycle logged, got %v", logger.errs)
+	}
+}
+
+func TestRepeatedDeviceEventsChangeStateOnce(t *testing.T) {
+	logger := &recordingLogger{}
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithLogger(logger))
+
+	start := clock.Now()
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+	sd.handleDoorEvent(DoorEventConnected)
+	clock.Advance(time.Second)
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+	sd.handleDoorEvent(DoorEventConnected)
+
+	c := sd.Connectivity()
+	for name, device := range map[string]DeviceConnectivity{
+		"camera": c.Camera, "cam0": c.Cameras[defaultCameraID], "door": c.Door,
+	} {
+		if !device.Connected || !device.ChangedAt.Equal(start) {
+			t.Fatalf("%s: expected one transition at %v, got %+v", name, start, device)
+		}
+	}
+	want := []string{"camera cam0: ignoring repeated connected event", "door: ignoring repeated connected event"}
+	if !reflect.DeepEqual(logger.debugs, want) {
+		t.Fatalf("expected debug logs %q, got %q", want, logger.debugs)
+	}
+}
@@ -0,0 +1,80 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DoorStateReader is implemented by doors that can report their physical
+// state, so the controller notices a person unlocking the door by hand.
+type DoorStateReader interface {
+	ReadState(ctx context.Context) (DoorState, error)
+}
+
+// watchDoorState reads the door's state every MinimalRateCameraProcess.
+func (sd *SmartDoor) watchDoorState(ctx context.Context) {
+	reader, ok := sd.door.(DoorStateReader)
+	if !ok {
+		return
+	}
+	ticker := sd.clock.NewTicker(sd.currentConfig().MinimalRateCameraProcess)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			sd.observeDoorState(ctx, reader)
+		}
+	}
+}
+
+// observeDoorState records an unlock the controller did not command.
+func (sd *SmartDoor) observeDoorState(ctx context.Context, reader DoorStateReader) {
+	state, err := reader.ReadState(ctx)
+	if err != nil {
+		sd.logger.Error(fmt.Sprintf("read door state: %v", err))
+		return
+	}
+	sd.handleDoorState(state)
+}
+
+func (sd *SmartDoor) handleDoorState(state DoorState) {
+	sd.trace(TraceEntry{Kind: TraceKindDoorState, DoorState: state})
+	now := sd.clock.Now()
+	sd.mu.Lock()
+	manual := state == DoorStateUnlocked && sd.doorState == DoorStateLocked && sd.inFlight == ActionNone
+	if manual {
+		sd.doorState = DoorStateUnlocked
+		sd.stateReason = "unlocked: manual"
+		sd.manualUnlockAt = now
+	}
+	sd.mu.Unlock()
+
+	if manual {
+		sd.emit(Event{Kind: EventManualUnlock, Time: now, Message: "door unlocked by hand"})
+	}
+}
+
+// adoptManualUnlock takes over a manual unlock as if the controller had
+// decided it, except that no automatic relock happens until
+// Config.ManualGracePeriod has passed.
+func (sd *SmartDoor) adoptManualUnlock(now time.Time) {
+	sd.mu.Lock()
+	at := sd.manualUnlockAt
+	sd.manualUnlockAt = time.Time{}
+	if !at.IsZero() {
+		sd.intended = ActionUnlock
+	}
+	sd.mu.Unlock()
+	if at.IsZero() {
+		return
+	}
+
+	sd.unlockedSince = at
+	sd.graceUntil = at.Add(sd.currentConfig().ManualGracePeriod)
+	sd.absentSince = time.Time{}
+	sd.lastAssertTime = now
+}
@@ -0,0 +1,78 @@
+package smartdoor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readableDoor reports whatever state a test sets, as a door with a
+// position sensor would.
+type readableDoor struct {
+	*fakeDoor
+	mu    sync.Mutex
+	state DoorState
+}
+
+func (d *readableDoor) ReadState(ctx context.Context) (DoorState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state, nil
+}
+
+func TestManualUnlockSuppressesRelockForGracePeriod(t *testing.T) {
+	config := dogDoorConfig()
+	config.ManualGracePeriod = 30 * time.Second
+	door := &readableDoor{fakeDoor: newFakeDoor()}
+	clock := newFakeClock()
+	sd := NewSmartDoor(config, newFakeCamera(), door, &fakeClassifier{}, WithClock(clock))
+	events := sd.Events()
+	ctx := context.Background()
+
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+	sd.handleClassifications(cat)
+	applyActions(sd)
+	if sd.DoorState() != DoorStateLocked {
+		t.Fatalf("expected locked, got %v", sd.DoorState())
+	}
+
+	door.state = DoorStateUnlocked
+	sd.observeDoorState(ctx, door)
+	if sd.DoorState() != DoorStateUnlocked || sd.StateReason() != "unlocked: manual" {
+		t.Fatalf("expected a manual unlock, got %v (%s)", sd.DoorState(), sd.StateReason())
+	}
+
+	for _, advance := range []time.Duration{0, 10 * time.Second, 19 * time.Second} {
+		clock.Advance(advance)
+		if d := sd.handleClassifications(noneBatch()); d.Action != ActionNone {
+			t.Fatalf("expected no relock %s into the grace period, got %v", advance, d.Action)
+		}
+	}
+	clock.Advance(time.Second)
+	if d := sd.handleClassifications(noneBatch()); d.Action != ActionLock {
+		t.Fatalf("expected the relock once the grace period ended, got %v", d.Action)
+	}
+
+	var manual bool
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventManualUnlock {
+			manual = true
+		}
+	}
+	if !manual {
+		t.Fatal("expected EventManualUnlock")
+	}
+}
+
+func TestCommandedUnlockIsNotManual(t *testing.T) {
+	door := &readableDoor{fakeDoor: newFakeDoor(), state: DoorStateUnlocked}
+	sd := NewSmartDoor(dogDoorConfig(), newFakeCamera(), door, &fakeClassifier{}, WithClock(newFakeClock()))
+
+	sd.handleClassifications(dogBatch())
+	applyActions(sd)
+	sd.observeDoorState(context.Background(), door)
+	if reason := sd.StateReason(); reason != "unlocked: dog 0.90 on cam0" {
+		t.Fatalf("expected the controller's own reason, got %q", reason)
+	}
+}
@@ -0,0 +1,47 @@
+package smartdoor
+
+import "time"
+
+// Names of the histograms recorded through Metrics, in seconds. The
+// capture stages are only recorded for actions a detection triggered
+// from a frame with a Frame.CapturedAt.
+const (
+	// MetricCaptureToDecision runs from the trigger frame's capture to the
+	// decision to act on it.
+	MetricCaptureToDecision = "capture_to_decision_seconds"
+	// MetricDecisionToApplied runs from the decision to the door
+	// completing the action.
+	MetricDecisionToApplied = "decision_to_applied_seconds"
+	// MetricCaptureToApplied is the end-to-end latency from capture to
+	// the door completing the action.
+	MetricCaptureToApplied = "capture_to_applied_seconds"
+)
+
+// Metrics receives measurements for an external metrics system, such as
+// a Prometheus histogram per name. Observe is called from the executor
+// goroutine and must not block.
+type Metrics interface {
+	Observe(name string, value float64)
+}
+
+// WithMetrics records the detection-to-action latency histograms through
+// metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(sd *SmartDoor) {
+		sd.metrics = metrics
+	}
+}
+
+// observeLatency records the stages between the capture behind ac and
+// the door completing its action at applied.
+func (sd *SmartDoor) observeLatency(ac ActionContext, applied time.Time) {
+	if sd.metrics == nil {
+		return
+	}
+	sd.metrics.Observe(MetricDecisionToApplied, applied.Sub(ac.Time).Seconds())
+	if ac.capturedAt.IsZero() {
+		return
+	}
+	sd.metrics.Observe(MetricCaptureToDecision, ac.Time.Sub(ac.capturedAt).Seconds())
+	sd.metrics.Observe(MetricCaptureToApplied, applied.Sub(ac.capturedAt).Seconds())
+}
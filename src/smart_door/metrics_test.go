@@ -0,0 +1,61 @@
+package smartdoor
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeMetrics struct {
+	mu       sync.Mutex
+	observed map[string][]float64
+}
+
+func (m *fakeMetrics) Observe(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.observed == nil {
+		m.observed = make(map[string][]float64)
+	}
+	m.observed[name] = append(m.observed[name], value)
+}
+
+// slowDoor takes delay of the injected clock to apply each action.
+type slowDoor struct {
+	*fakeDoor
+	clock *fakeClock
+	delay time.Duration
+}
+
+func (d *slowDoor) Unlock(ctx context.Context) error {
+	d.clock.Advance(d.delay)
+	return d.fakeDoor.Unlock(ctx)
+}
+
+func TestMetricsRecordDetectionToActionLatency(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &fakeMetrics{}
+	door := &slowDoor{fakeDoor: newFakeDoor(), clock: clock, delay: 500 * time.Millisecond}
+	sd := NewSmartDoor(dogDoorConfig(), newFakeCamera(), door, &fakeClassifier{},
+		WithClock(clock), WithMetrics(metrics))
+
+	frame := Frame{Data: []byte{1}, CapturedAt: clock.Now()}
+	clock.Advance(2 * time.Second)
+	sd.handleCycle(context.Background(), CycleResult{
+		Frames:          []Frame{frame},
+		Classifications: dogBatch(),
+		Outcome:         OutcomeDecided,
+	})
+	applyActions(sd)
+
+	want := map[string][]float64{
+		MetricCaptureToDecision: {2},
+		MetricDecisionToApplied: {0.5},
+		MetricCaptureToApplied:  {2.5},
+	}
+	if !reflect.DeepEqual(metrics.observed, want) {
+		t.Fatalf("expected %v, got %v", want, metrics.observed)
+	}
+}
@@ -0,0 +1,113 @@
+package smartdoor
+
+import (
+	"fmt"
+	"time"
+)
+
+// override is a manual ForceLock or ForceUnlock that holds the door until
+// it expires or is cleared.
+type override struct {
+	action DoorAction
+	until  time.Time
+}
+
+func (o override) String() string {
+	name := "ForceUnlock"
+	if o.action == ActionLock {
+		name = "ForceLock"
+	}
+	return fmt.Sprintf("%s until %s", name, o.until.Format("15:04"))
+}
+
+// ForceLock locks the door and keeps it locked until until, whatever the
+// camera sees. Detection resumes on the first cycle after it expires.
+func (sd *SmartDoor) ForceLock(until time.Time) {
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callForceLock, Until: until})
+	sd.force(ActionLock, until)
+}
+
+// ForceUnlock unlocks the door and keeps it unlocked until until.
+func (sd *SmartDoor) ForceUnlock(until time.Time) {
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callForceUnlock, Until: until})
+	sd.force(ActionUnlock, until)
+}
+
+// ClearOverride ends a ForceLock or ForceUnlock early.
+func (sd *SmartDoor) ClearOverride() {
+	sd.trace(TraceEntry{Kind: TraceKindCall, Call: callClearOverride})
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.override != nil {
+		sd.override.until = time.Time{}
+	}
+}
+
+func (sd *SmartDoor) force(action DoorAction, until time.Time) {
+	o := override{action: action, until: until}
+	ac := newActionContext(action, sd.clock.Now(), nil, "override: "+o.String())
+	sd.mu.Lock()
+	sd.override = &o
+	sd.pendingContexts[action] = ac
+	sd.mu.Unlock()
+
+	sd.enqueue(action)
+	sd.emit(Event{Kind: EventAction, Action: action, Message: o.String(), Context: &ac})
+}
+
+// heldByOverride reports whether a manual override still holds the door.
+// When one has expired the controller resumes from the overridden state,
+// as if a detection had decided it, with a fresh cooldown.
+func (sd *SmartDoor) heldByOverride(now time.Time) bool {
+	sd.mu.Lock()
+	o := sd.override
+	if o == nil {
+		sd.mu.Unlock()
+		return false
+	}
+	if now.Before(o.until) {
+		sd.mu.Unlock()
+		return true
+	}
+	sd.override = nil
+	sd.cooldownResetAt = now
+	sd.intended = o.action
+	sd.mu.Unlock()
+
+	sd.unlockCapLatched = false
+	sd.hasPrevious = false
+	sd.lastAssertTime = now
+	if o.action == ActionUnlock {
+		sd.lastDetection = DetectionDog
+		sd.unlockedSince = now
+	} else {
+		sd.lastDetection = DetectionCat
+		sd.unlockedSince = time.Time{}
+	}
+	return false
+}
+
+// StateReason explains the current door state, such as
+// "unlocked: dog 0.94 on cam0" or "override: ForceLock until 14:05". It
+// changes when the door applies an action or its state becomes unknown.
+func (sd *SmartDoor) StateReason() string {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.stateReason == "" {
+		return "unknown"
+	}
+	return sd.stateReason
+}
+
+func actionReason(action DoorAction, cause string) string {
+	state := "locked"
+	if action == ActionUnlock {
+		state = "unlocked"
+	}
+	return state + ": " + cause
+}
+
+func triggerCause(trigger *Trigger) string {
+	c := trigger.Classification
+	return fmt.Sprintf("%s %.2f on %s", c.Label, c.Confidence, trigger.Camera)
+}
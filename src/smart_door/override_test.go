@@ -0,0 +1,149 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// applyActions runs the queued actions through the executor synchronously.
+func applyActions(sd *SmartDoor) {
+	for _, action := range sd.actions.Drain() {
+		sd.executeAction(context.Background(), action)
+	}
+}
+
+func TestStateReasonFollowsTrigger(t *testing.T) {
+	config := dogDoorConfig()
+	config.FailSafeAfterErrors = 2
+	config.MaxContinuousUnlock = 3 * time.Minute
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+	dog := [][]Classification{{{Label: "dog", Confidence: 0.94}}}
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.8}}}
+
+	if got := sd.StateReason(); got != "unknown" {
+		t.Fatalf("expected unknown before any action, got %q", got)
+	}
+
+	steps := []struct {
+		name   string
+		cycles []CycleResult
+		want   string
+	}{
+		{"dog", []CycleResult{{Classifications: dog, Outcome: OutcomeDecided}}, "unlocked: dog 0.94 on cam0"},
+		{"cat", []CycleResult{{Classifications: cat, Outcome: OutcomeDecided}}, "locked: cat 0.80 on cam0"},
+		{"dog again", []CycleResult{{Classifications: dog, Outcome: OutcomeDecided}}, "unlocked: dog 0.94 on cam0"},
+		{"absence", []CycleResult{{Classifications: noneBatch(), Outcome: OutcomeDecided}}, "locked: no detection"},
+		{"errors", []CycleResult{
+			{Classifications: dog, Outcome: OutcomeDecided},
+			{Outcome: OutcomeError}, {Outcome: OutcomeError},
+		}, "locked: fail-safe after 2 classifier errors"},
+		{"dog for too long", []CycleResult{
+			{Classifications: dog, Outcome: OutcomeDecided},
+			{Classifications: dog, Outcome: OutcomeDecided},
+			{Classifications: dog, Outcome: OutcomeDecided},
+			{Classifications: dog, Outcome: OutcomeDecided},
+		}, "locked: unlock cap after 3m0s"},
+	}
+	for _, step := range steps {
+		for _, cycle := range step.cycles {
+			sd.handleCycle(context.Background(), cycle)
+			applyActions(sd)
+			clock.Advance(time.Minute)
+		}
+		if got := sd.StateReason(); got != step.want {
+			t.Fatalf("%s: expected reason %q, got %q", step.name, step.want, got)
+		}
+	}
+}
+
+func TestForceLockHoldsUntilExpiry(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+
+	until := time.Date(2024, 1, 1, 14, 5, 0, 0, time.UTC)
+	sd.ForceLock(until)
+	applyActions(sd)
+	if got := sd.StateReason(); got != "override: ForceLock until 14:05" {
+		t.Fatalf("unexpected reason %q", got)
+	}
+
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd)
+
+	clock.Advance(2*time.Hour + 5*time.Minute)
+	sd.handleClassifications(dogBatch())
+	applyActions(sd)
+	if got := sd.StateReason(); got != "unlocked: dog 0.90 on cam0" {
+		t.Fatalf("expected detection to resume after the override, got %q", got)
+	}
+}
+
+func TestMaxConsecutiveSameActionStopsRepeats(t *testing.T) {
+	config := dogDoorConfig()
+	config.MaxConsecutiveSameAction = 2
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+	until := clock.Now().Add(time.Hour)
+
+	for range 4 {
+		sd.ForceLock(until)
+		applyActions(sd)
+	}
+	if got := door.Actions(); len(got) != 2 {
+		t.Fatalf("expected the door locked only twice, got %v", got)
+	}
+	if n := sd.Stats().ActionsCapped; n != 2 {
+		t.Fatalf("expected 2 capped actions, got %d", n)
+	}
+	warnings := 0
+	for len(events) > 0 {
+		if event := <-events; event.Kind == EventError && event.Severity == SeverityWarning {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Fatalf("expected one warning for the streak, got %d", warnings)
+	}
+
+	sd.ForceUnlock(until)
+	applyActions(sd)
+	if got := door.Actions(); len(got) != 3 || got[2] != ActionUnlock {
+		t.Fatalf("expected a different action to go through, got %v", got)
+	}
+}
+
+func TestDisarmSuppressesActionsButReportsDetections(t *testing.T) {
+	config := dogDoorConfig()
+	config.StartArmed = false
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	if sd.Armed() {
+		t.Fatal("expected a SmartDoor without StartArmed to start disarmed")
+	}
+	for _, batch := range [][][]Classification{dogBatch(), dogBatch(), cat} {
+		if d := sd.handleClassifications(batch); d.Action != ActionNone || d.Reason != ReasonDisarmed {
+			t.Fatalf("expected no action while disarmed, got %v (%v)", d.Action, d.Reason)
+		}
+	}
+	expectActions(t, sd)
+
+	var detections []Detection
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventDetection {
+			detections = append(detections, e.Detection)
+		}
+	}
+	if want := []Detection{DetectionDog, DetectionCat}; len(detections) != len(want) ||
+		detections[0] != want[0] || detections[1] != want[1] {
+		t.Fatalf("expected detections %v, got %v", want, detections)
+	}
+
+	sd.Arm()
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	sd.Disarm()
+	sd.handleClassifications(cat)
+	expectActions(t, sd)
+}
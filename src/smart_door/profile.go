@@ -0,0 +1,75 @@
+package smartdoor
+
+import "fmt"
+
+// Profile replaces the base config while its schedule contains the current
+// time, e.g. a stricter config overnight.
+type Profile struct {
+	Name     string
+	Schedule Schedule
+	Config   Config
+}
+
+// SetProfiles validates and installs scheduled profiles, loading the
+// LabelSource of each profile's config as UpdateConfig does. When several
+// schedules overlap the first listed profile wins; outside every schedule
+// the config passed to NewSmartDoor or UpdateConfig applies.
+func (sd *SmartDoor) SetProfiles(profiles ...Profile) error {
+	installed := make([]Profile, len(profiles))
+	for i, p := range profiles {
+		config, err := p.Config.ResolveLabels()
+		if err == nil {
+			err = config.Validate()
+		}
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		p.Config = config.clone()
+		installed[i] = p
+	}
+	if sd.tracer != nil {
+		traced := make([]Profile, len(installed))
+		for i, p := range installed {
+			p.Config = p.Config.clone()
+			p.Config.LabelSource = ""
+			traced[i] = p
+		}
+		sd.trace(TraceEntry{Kind: TraceKindCall, Call: callSetProfiles, Profiles: traced})
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.profiles = installed
+	return nil
+}
+
+// EffectiveConfig returns the config the loop is applying right now, after
+// profile selection and without the labels DisableLabel turned off. The
+// returned config is a copy the caller may modify.
+func (sd *SmartDoor) EffectiveConfig() Config {
+	return sd.currentConfig().clone()
+}
+
+// effectiveConfigLocked must be called with sd.mu held.
+func (sd *SmartDoor) effectiveConfigLocked() Config {
+	config := sd.config
+	if p := sd.activeProfileLocked(); p != nil {
+		config = p.Config
+	}
+	return sd.withoutDisabledLabelsLocked(config)
+}
+
+// activeProfileLocked returns the profile in effect, nil outside every
+// schedule. It must be called with sd.mu held.
+func (sd *SmartDoor) activeProfileLocked() *Profile {
+	if len(sd.profiles) == 0 {
+		return nil
+	}
+	now := sd.clock.Now()
+	for i := range sd.profiles {
+		if sd.profiles[i].Schedule.Contains(now) {
+			return &sd.profiles[i]
+		}
+	}
+	return nil
+}
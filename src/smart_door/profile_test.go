@@ -0,0 +1,60 @@
+package smartdoor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectiveConfigFollowsActiveProfile(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(DefaultDogDoorConfig(), &fakeClassifier{})
+
+	night, err := NewSchedule("UTC", "22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sd.SetProfiles(Profile{Name: "night", Schedule: night, Config: StrictSecurityConfig()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sd.EffectiveConfig().MaxContinuousUnlock; got != DefaultDogDoorConfig().MaxContinuousUnlock {
+		t.Fatalf("expected base config at noon, got MaxContinuousUnlock %v", got)
+	}
+	clock.Advance(11 * time.Hour)
+	if got := sd.EffectiveConfig().MaxContinuousUnlock; got != StrictSecurityConfig().MaxContinuousUnlock {
+		t.Fatalf("expected night profile at 23:00, got MaxContinuousUnlock %v", got)
+	}
+	clock.Advance(8 * time.Hour)
+	if got := sd.EffectiveConfig().MaxContinuousUnlock; got != DefaultDogDoorConfig().MaxContinuousUnlock {
+		t.Fatalf("expected base config at 07:00, got MaxContinuousUnlock %v", got)
+	}
+}
+
+func TestSetProfilesRejectsInvalidConfig(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(DefaultDogDoorConfig(), &fakeClassifier{})
+	if err := sd.SetProfiles(Profile{Name: "broken"}); err == nil {
+		t.Fatal("expected invalid profile config to be rejected")
+	}
+}
+
+func TestProfilesAndEffectiveConfigAreCopies(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(DefaultDogDoorConfig(), &fakeClassifier{})
+	always, err := NewSchedule("UTC", "00:00-23:59")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := StrictSecurityConfig()
+	if err := sd.SetProfiles(Profile{Name: "always", Schedule: always, Config: config}); err != nil {
+		t.Fatal(err)
+	}
+	label := config.ClassificationUnlockList[0].Label
+
+	config.ClassificationUnlockList[0].Label = "changed by caller"
+	if got := sd.EffectiveConfig().ClassificationUnlockList[0].Label; got != label {
+		t.Fatalf("expected the installed profile unaffected by the caller's slice, got %q", got)
+	}
+	effective := sd.EffectiveConfig()
+	effective.ClassificationUnlockList[0].Label = "changed by reader"
+	if got := sd.EffectiveConfig().ClassificationUnlockList[0].Label; got != label {
+		t.Fatalf("expected EffectiveConfig to return a copy, got %q", got)
+	}
+}
@@ -0,0 +1,85 @@
+package smartdoor
+
+import (
+	"context"
+	"sync"
+)
+
+// actionQueue hands door actions from the decision loop to the executor in
+// FIFO order. Only actions that have not started executing are coalesced:
+// an action equal to the pending tail is dropped, and an action opposite to
+// the pending tail replaces it, since the newer decision supersedes one the
+// door has not acted on yet. A burst of Lock, Unlock, Lock behind a busy
+// executor therefore leaves a single pending Lock.
+type actionQueue struct {
+	mu      sync.Mutex
+	pending []DoorAction
+	notify  chan struct{}
+}
+
+func newActionQueue() *actionQueue {
+	return &actionQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *actionQueue) Enqueue(action DoorAction) {
+	if action == ActionNone {
+		return
+	}
+
+	q.mu.Lock()
+	for len(q.pending) > 0 {
+		tail := q.pending[len(q.pending)-1]
+		if tail == action {
+			q.mu.Unlock()
+			return
+		}
+		q.pending = q.pending[:len(q.pending)-1]
+	}
+	q.pending = append(q.pending, action)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *actionQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+func (q *actionQueue) pop() (DoorAction, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return ActionNone, false
+	}
+	action := q.pending[0]
+	q.pending = q.pending[1:]
+	return action, true
+}
+
+// Next blocks until an action is pending or ctx is done.
+func (q *actionQueue) Next(ctx context.Context) (DoorAction, bool) {
+	for {
+		if action, ok := q.pop(); ok {
+			return action, true
+		}
+		select {
+		case <-ctx.Done():
+			return ActionNone, false
+		case <-q.notify:
+		}
+	}
+}
+
+// Drain removes and returns every pending action in order.
+func (q *actionQueue) Drain() []DoorAction {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	drained := q.pending
+	q.pending = nil
+	return drained
+}
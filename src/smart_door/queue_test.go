@@ -0,0 +1,307 @@
+package smartdoor
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestActionQueuePreservesOrder(t *testing.T) {
+	q := newActionQueue()
+	ctx := context.Background()
+
+	q.Enqueue(ActionLock)
+	if action, _ := q.Next(ctx); action != ActionLock {
+		t.Fatalf("expected Lock, got %v", action)
+	}
+	q.Enqueue(ActionUnlock)
+	if action, _ := q.Next(ctx); action != ActionUnlock {
+		t.Fatalf("expected Unlock, got %v", action)
+	}
+}
+
+func TestActionQueueCoalescesPendingActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		enqueued []DoorAction
+		want     []DoorAction
+	}{
+		{"duplicate", []DoorAction{ActionLock, ActionLock}, []DoorAction{ActionLock}},
+		{"superseded", []DoorAction{ActionLock, ActionUnlock}, []DoorAction{ActionUnlock}},
+		{"lock unlock lock", []DoorAction{ActionLock, ActionUnlock, ActionLock}, []DoorAction{ActionLock}},
+		{"none ignored", []DoorAction{ActionNone, ActionUnlock, ActionNone}, []DoorAction{ActionUnlock}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newActionQueue()
+			for _, action := range tt.enqueued {
+				q.Enqueue(action)
+			}
+			if got := q.Drain(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestActionQueueNextRespectsCancel(t *testing.T) {
+	q := newActionQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, ok := q.Next(ctx); ok {
+		t.Fatal("expected Next to return on cancelled context")
+	}
+}
+
+type blockingDoor struct {
+	*fakeDoor
+	started chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDoor) Lock(ctx context.Context) error {
+	d.started <- struct{}{}
+	<-d.release
+	return d.fakeDoor.Lock(ctx)
+}
+
+func (d *blockingDoor) Unlock(ctx context.Context) error {
+	d.started <- struct{}{}
+	<-d.release
+	return d.fakeDoor.Unlock(ctx)
+}
+
+func TestExecutorCoalescesRapidEnqueuesInOrder(t *testing.T) {
+	door := &blockingDoor{
+		fakeDoor: newFakeDoor(),
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	sd := NewSmartDoor(Config{}, newFakeCamera(), door, &fakeClassifier{}, WithClock(newFakeClock()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.executeActions(ctx, context.Background())
+
+	sd.actions.Enqueue(ActionLock)
+	<-door.started
+	sd.actions.Enqueue(ActionUnlock)
+	sd.actions.Enqueue(ActionLock)
+	sd.actions.Enqueue(ActionUnlock)
+	close(door.release)
+	<-door.started
+
+	waitFor(t, func() bool { return len(door.Actions()) == 2 })
+	want := []DoorAction{ActionLock, ActionUnlock}
+	if got := door.Actions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if state := sd.DoorState(); state != DoorStateUnlocked {
+		t.Fatalf("expected unlocked, got %v", state)
+	}
+}
+
+func TestExecutorDrainsOnShutdown(t *testing.T) {
+	sd, _, door, _ := newTestSmartDoor(Config{}, &fakeClassifier{})
+	sd.actions.Enqueue(ActionUnlock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sd.executeActions(ctx, context.Background())
+
+	if got := door.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionUnlock}) {
+		t.Fatalf("expected pending unlock to be applied, got %v", got)
+	}
+}
+
+func TestDoorCallTimeoutAbandonsBlockedLock(t *testing.T) {
+	door := &blockingDoor{
+		fakeDoor: newFakeDoor(),
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	defer close(door.release)
+	clock := newFakeClock()
+	config := dogDoorConfig()
+	config.DoorCallTimeout = time.Second
+	sd := NewSmartDoor(config, newFakeCamera(), door, &fakeClassifier{}, WithClock(clock))
+	events := sd.Events()
+
+	sd.mu.Lock()
+	sd.doorState = DoorStateUnlocked
+	sd.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		sd.executeAction(context.Background(), ActionLock)
+		close(done)
+	}()
+	<-door.started
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+
+	if got := sd.DoorState(); got != DoorStateUnknown {
+		t.Fatalf("expected unknown door state after timeout, got %v", got)
+	}
+	if got := sd.Stats().DoorFailures; got != 1 {
+		t.Fatalf("expected one door failure, got %d", got)
+	}
+	if e := <-events; e.Kind != EventError || e.Message != ErrDoorCallTimeout.Error() {
+		t.Fatalf("expected a timeout error event, got %+v", e)
+	}
+}
+
+type recordingExecutor struct {
+	mu       sync.Mutex
+	actions  []DoorAction
+	contexts []ActionContext
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, action DoorAction) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.actions = append(e.actions, action)
+	if ac, ok := ActionContextFrom(ctx); ok {
+		e.contexts = append(e.contexts, ac)
+	}
+	return nil
+}
+
+func (e *recordingExecutor) lastContext() (ActionContext, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.contexts) == 0 {
+		return ActionContext{}, false
+	}
+	return e.contexts[len(e.contexts)-1], true
+}
+
+func (e *recordingExecutor) Actions() []DoorAction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]DoorAction(nil), e.actions...)
+}
+
+func TestCustomExecutorReceivesDecidedActions(t *testing.T) {
+	executor := &recordingExecutor{}
+	sd, _, door, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithActionExecutor(executor))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sd.executeActions(ctx, context.Background())
+		close(done)
+	}()
+
+	sd.handleClassifications(dogBatch())
+	waitFor(t, func() bool { return len(executor.Actions()) == 1 })
+	sd.handleClassifications([][]Classification{{{Label: "cat", Confidence: 0.9}}})
+	waitFor(t, func() bool { return len(executor.Actions()) == 2 })
+	cancel()
+	<-done
+
+	if got := executor.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionUnlock, ActionLock}) {
+		t.Fatalf("expected unlock then lock, got %v", got)
+	}
+	if got := door.Actions(); len(got) != 0 {
+		t.Fatalf("expected the door to be bypassed, got %v", got)
+	}
+	if got := sd.DoorState(); got != DoorStateLocked {
+		t.Fatalf("expected locked state, got %v", got)
+	}
+}
+
+func TestConfirmDelayAppliesUnlessCancelled(t *testing.T) {
+	config := Config{ConfirmDelay: 5 * time.Second}
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+	pending := func(action DoorAction) {
+		t.Helper()
+		sd.actions.Enqueue(action)
+		for e := range events {
+			if e.Kind == EventPendingAction {
+				if e.Action != action {
+					t.Fatalf("expected %v pending, got %v", action, e.Action)
+				}
+				break
+			}
+		}
+		clock.BlockUntil(1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.executeActions(ctx, context.Background())
+
+	pending(ActionUnlock)
+	clock.Advance(4 * time.Second)
+	if got := door.Actions(); len(got) != 0 {
+		t.Fatalf("expected nothing applied within the delay, got %v", got)
+	}
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return len(door.Actions()) == 1 })
+
+	pending(ActionLock)
+	if !sd.CancelPending() {
+		t.Fatal("expected a pending action to cancel")
+	}
+	if sd.CancelPending() {
+		t.Fatal("expected nothing left to cancel")
+	}
+
+	pending(ActionUnlock)
+	clock.Advance(5 * time.Second)
+	waitFor(t, func() bool { return len(door.Actions()) == 2 })
+	if got, want := door.Actions(), []DoorAction{ActionUnlock, ActionUnlock}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the cancelled lock skipped, got %v", got)
+	}
+	if n := sd.Stats().PendingCancelled; n != 1 {
+		t.Fatalf("expected one cancellation counted, got %d", n)
+	}
+}
+
+func TestCancelledUnlockIsNeverReasserted(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfirmDelay = 5 * time.Second
+	config.ReassertInterval = 10 * time.Second
+	config.MaxContinuousUnlock = 15 * time.Second
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{})
+	events := sd.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.executeActions(ctx, context.Background())
+
+	if d := sd.handleClassifications(dogBatch()); d.Action != ActionUnlock {
+		t.Fatalf("expected an unlock decided, got %+v", d)
+	}
+	for e := range events {
+		if e.Kind == EventPendingAction {
+			break
+		}
+	}
+	clock.BlockUntil(1)
+	if !sd.CancelPending() {
+		t.Fatal("expected the unlock pending")
+	}
+
+	for range 3 {
+		clock.Advance(10 * time.Second)
+		if d := sd.handleClassifications(noneBatch()); d.Action != ActionNone {
+			t.Fatalf("expected nothing decided for the cancelled unlock, got %+v", d)
+		}
+	}
+	if got := sd.IntendedState(); got != DoorStateUnknown {
+		t.Fatalf("expected the intended state rolled back, got %v", got)
+	}
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventAction || e.Kind == EventPendingAction {
+			t.Fatalf("expected the cancelled unlock never re-sent, got %+v", e)
+		}
+	}
+	if got := door.Actions(); len(got) != 0 {
+		t.Fatalf("expected the door left alone, got %v", got)
+	}
+}
@@ -0,0 +1,175 @@
+package smartdoor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NoActionReason explains why a cycle left the door alone.
+type NoActionReason int
+
+const (
+	// ReasonNone is the reason of a cycle that acted.
+	ReasonNone NoActionReason = iota
+	// ReasonNoMatch: no unlock or lock label was seen at all.
+	ReasonNoMatch
+	// ReasonBelowConfidence: a label was seen but under its MinConfidence
+	// or Config.MinConfidenceMargin.
+	ReasonBelowConfidence
+	// ReasonBelowQuorum: a label matched but Config.VoteThreshold or the
+	// MultiCameraMajority policy was not met.
+	ReasonBelowQuorum
+	// ReasonUnchanged: the detection calls for the state the door is
+	// already in.
+	ReasonUnchanged
+	// ReasonDeduped: the batch was identical to the last one and skipped
+	// under Config.SkipUnchangedClassifications.
+	ReasonDeduped
+	// ReasonOnCooldown: the last action was too recent.
+	ReasonOnCooldown
+	// ReasonOverridden: a ForceLock or ForceUnlock holds the door.
+	ReasonOverridden
+	// ReasonHeld: the cycle had no usable result, an error or no labels.
+	ReasonHeld
+	// ReasonIgnored: an ignore-list label was seen.
+	ReasonIgnored
+	// ReasonCapLatched: the dog is still there after the unlock cap
+	// locked.
+	ReasonCapLatched
+	// ReasonRelockPending: the dog is gone but the absence debounce,
+	// relock delay or a manual grace period has not run out.
+	ReasonRelockPending
+	// ReasonVetoed: the ActionVeto cancelled the action.
+	ReasonVetoed
+	// ReasonStabilizing: the door connected too recently to unlock under
+	// Config.ConnectivityStabilization.
+	ReasonStabilizing
+	// ReasonUnknown: something no list matches was seen and held the door
+	// under Config.UnknownPolicy.
+	ReasonUnknown
+	// ReasonWaitingForDog: a cat is there but so is the dog, which
+	// CatArrivalWaitForDogToClear lets leave before locking.
+	ReasonWaitingForDog
+	// ReasonNotPresentLongEnough: the trigger's label has not been seen for
+	// its MinPresentDuration yet.
+	ReasonNotPresentLongEnough
+	// ReasonDisarmed: the automation is disarmed.
+	ReasonDisarmed
+	// ReasonNotConfirmed: Config.ConfirmWithExtraCapture's extra capture
+	// did not see the same detection.
+	ReasonNotConfirmed
+)
+
+var reasonNames = [...]string{
+	ReasonNone:                 "none",
+	ReasonNoMatch:              "no match",
+	ReasonBelowConfidence:      "below confidence",
+	ReasonBelowQuorum:          "below quorum",
+	ReasonUnchanged:            "unchanged",
+	ReasonDeduped:              "deduped",
+	ReasonOnCooldown:           "on cooldown",
+	ReasonOverridden:           "overridden",
+	ReasonHeld:                 "held",
+	ReasonIgnored:              "ignored",
+	ReasonCapLatched:           "cap latched",
+	ReasonRelockPending:        "relock pending",
+	ReasonVetoed:               "vetoed",
+	ReasonStabilizing:          "stabilizing",
+	ReasonUnknown:              "unknown",
+	ReasonWaitingForDog:        "waiting for dog",
+	ReasonNotPresentLongEnough: "not present long enough",
+	ReasonDisarmed:             "disarmed",
+	ReasonNotConfirmed:         "not confirmed",
+}
+
+func (r NoActionReason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return fmt.Sprintf("NoActionReason(%d)", int(r))
+}
+
+// LastNoActionReason returns why the latest cycle took no action, or
+// ReasonNone if it acted.
+func (sd *SmartDoor) LastNoActionReason() NoActionReason {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.noActionReason
+}
+
+// LastClassifications returns a copy of the batch the latest decided cycle
+// used, to see in an admin UI exactly what the model said. Cycles that
+// failed or had no frames leave it unchanged.
+func (sd *SmartDoor) LastClassifications() [][]Classification {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return cloneClassifications(sd.lastClassifications)
+}
+
+// LastSnapshot returns the frame behind the trigger of the latest decided
+// cycle that had one.
+func (sd *SmartDoor) LastSnapshot() (Frame, bool) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.lastSnapshot, sd.lastSnapshot.Data != nil
+}
+
+// Now is the time on the SmartDoor's clock, for deadlines handed to
+// ForceLock and ForceUnlock.
+func (sd *SmartDoor) Now() time.Time {
+	return sd.clock.Now()
+}
+
+// LastDecision returns what the latest cycle decided: its detection, the
+// action it took, if any, and otherwise why it took none.
+func (sd *SmartDoor) LastDecision() (Detection, DoorAction, NoActionReason) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	d := sd.lastDecision
+	return d.Detection, d.Action, sd.noActionReason
+}
+
+// recordReason keeps the latest reason and emits EventNoAction whenever it
+// changes, so a stream of identical idle cycles stays quiet.
+func (sd *SmartDoor) recordReason(d decision) {
+	reason := d.Reason
+	if d.Action != ActionNone {
+		reason = ReasonNone
+	}
+	sd.mu.Lock()
+	changed := reason != sd.noActionReason
+	sd.noActionReason = reason
+	sd.lastDecision = d
+	sd.mu.Unlock()
+
+	if changed && reason != ReasonNone {
+		sd.emit(Event{Kind: EventNoAction, NoActionReason: reason, Message: reason.String()})
+	}
+}
+
+// noMatchReason explains a batch detected as DetectionNone.
+func (sd *SmartDoor) noMatchReason(classifications [][]Classification) NoActionReason {
+	config := sd.currentConfig()
+	lists := [][]ClassificationConfig{sd.adaptList(config.ClassificationUnlockList)}
+	if !config.UnlockOnly {
+		lists = append(lists, sd.adaptList(config.ClassificationLockList))
+	}
+
+	reason := ReasonNoMatch
+	for _, list := range lists {
+		if findMatch(classifications, list, config.MinConfidenceMargin) != nil {
+			return ReasonBelowQuorum
+		}
+		for _, frame := range classifications {
+			for _, c := range frame {
+				for _, entry := range list {
+					if strings.Contains(strings.ToLower(c.Label), strings.ToLower(entry.Label)) {
+						reason = ReasonBelowConfidence
+					}
+				}
+			}
+		}
+	}
+	return reason
+}
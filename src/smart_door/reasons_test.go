@@ -0,0 +1,166 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNoActionReasons(t *testing.T) {
+	decided := func(batch [][]Classification) CycleResult {
+		return CycleResult{Classifications: batch, Outcome: OutcomeDecided}
+	}
+	dog := decided(dogBatch())
+	cat := decided([][]Classification{{{Label: "cat", Confidence: 0.9}}})
+	tree := decided(noneBatch())
+
+	tests := []struct {
+		name   string
+		config func(*Config)
+		opts   []Option
+		setup  func(*SmartDoor, *fakeClock)
+		cycles []CycleResult
+		want   NoActionReason
+	}{
+		{name: "no match", cycles: []CycleResult{tree}, want: ReasonNoMatch},
+		{
+			name:   "below confidence",
+			cycles: []CycleResult{decided([][]Classification{{{Label: "dog", Confidence: 0.3}}})},
+			want:   ReasonBelowConfidence,
+		},
+		{
+			name:   "below quorum",
+			config: func(c *Config) { c.VoteThreshold = 1.5 },
+			cycles: []CycleResult{dog},
+			want:   ReasonBelowQuorum,
+		},
+		{name: "unchanged", cycles: []CycleResult{dog, dog}, want: ReasonUnchanged},
+		{
+			name:   "deduped",
+			config: func(c *Config) { c.SkipUnchangedClassifications = true },
+			cycles: []CycleResult{tree, tree},
+			want:   ReasonDeduped,
+		},
+		{
+			name:   "on cooldown",
+			config: func(c *Config) { c.MinimalDurationUnlocking = 10 * time.Second },
+			cycles: []CycleResult{cat, dog},
+			want:   ReasonOnCooldown,
+		},
+		{
+			name:   "overridden",
+			setup:  func(sd *SmartDoor, clock *fakeClock) { sd.ForceLock(clock.Now().Add(time.Hour)) },
+			cycles: []CycleResult{dog},
+			want:   ReasonOverridden,
+		},
+		{name: "held", cycles: []CycleResult{{Outcome: OutcomeError}}, want: ReasonHeld},
+		{
+			name:   "ignored",
+			config: func(c *Config) { c.IgnoreList = []ClassificationConfig{{Label: "person", MinConfidence: 0.5}} },
+			cycles: []CycleResult{decided([][]Classification{{{Label: "person", Confidence: 0.9}}})},
+			want:   ReasonIgnored,
+		},
+		{
+			name:   "cap latched",
+			config: func(c *Config) { c.MaxContinuousUnlock = time.Minute },
+			setup: func(sd *SmartDoor, clock *fakeClock) {
+				sd.handleClassifications(dogBatch())
+				clock.Advance(time.Minute)
+				sd.handleClassifications(dogBatch())
+			},
+			cycles: []CycleResult{dog},
+			want:   ReasonCapLatched,
+		},
+		{
+			name:   "relock pending",
+			config: func(c *Config) { c.AbsenceDebounce = 5 * time.Second },
+			cycles: []CycleResult{dog, tree},
+			want:   ReasonRelockPending,
+		},
+		{
+			name:   "vetoed",
+			opts:   []Option{WithVeto(&sensorVeto{})},
+			cycles: []CycleResult{dog},
+			want:   ReasonVetoed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := dogDoorConfig()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+			sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{}, tt.opts...)
+			if tt.setup != nil {
+				tt.setup(sd, clock)
+			}
+			var d decision
+			for _, cycle := range tt.cycles {
+				d = sd.handleCycle(context.Background(), cycle)
+			}
+			if d.Action != ActionNone {
+				t.Fatalf("expected no action, got %v", d.Action)
+			}
+			if got := sd.LastNoActionReason(); got != tt.want {
+				t.Fatalf("expected reason %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNoActionEventsOnlyOnChange(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	events := sd.Events()
+
+	for _, batch := range [][][]Classification{noneBatch(), noneBatch(), dogBatch(), dogBatch(), dogBatch()} {
+		sd.handleClassifications(batch)
+	}
+	if got := sd.LastNoActionReason(); got != ReasonUnchanged {
+		t.Fatalf("expected unchanged, got %v", got)
+	}
+
+	var reasons []NoActionReason
+	for len(events) > 0 {
+		if e := <-events; e.Kind == EventNoAction {
+			if e.Severity != SeverityDebug {
+				t.Fatalf("expected a debug event, got %v", e.Severity)
+			}
+			reasons = append(reasons, e.NoActionReason)
+		}
+	}
+	if len(reasons) != 2 || reasons[0] != ReasonNoMatch || reasons[1] != ReasonUnchanged {
+		t.Fatalf("expected no match then unchanged, got %v", reasons)
+	}
+}
+
+func TestLastClassificationsIsADefensiveCopy(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	if got := sd.LastClassifications(); len(got) != 0 {
+		t.Fatalf("expected no classifications before a cycle, got %v", got)
+	}
+
+	batch := dogBatch()
+	sd.handleClassifications(batch)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	batch[0][0].Label = "cat"
+
+	got := sd.LastClassifications()
+	if len(got) != 1 || got[0][0].Label != "dog" {
+		t.Fatalf("expected the decided dog batch, got %v", got)
+	}
+	got[0][0].Confidence = 0
+	if again := sd.LastClassifications(); again[0][0].Confidence != 0.9 {
+		t.Fatalf("expected the copy to be independent, got %v", again)
+	}
+	if detection, action, reason := sd.LastDecision(); detection != DetectionDog || action != ActionNone || reason != ReasonHeld {
+		t.Fatalf("expected the held error cycle last, got %v %v %v", detection, action, reason)
+	}
+
+	sd.handleClassifications(noneBatch())
+	if got := sd.LastClassifications(); got[0][0].Label != "tree" {
+		t.Fatalf("expected the latest batch, got %v", got)
+	}
+	if detection, action, _ := sd.LastDecision(); detection != DetectionNone || action != ActionLock {
+		t.Fatalf("expected the relock, got %v %v", detection, action)
+	}
+}
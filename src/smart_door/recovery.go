@@ -0,0 +1,55 @@
+package smartdoor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Recovery describes a classifier outage that has ended: a streak of
+// failed cycles that engaged the fail-safe or the classifier breaker,
+// followed by a successfully classified cycle.
+type Recovery struct {
+	// Since is when the first failed cycle of the streak was evaluated.
+	Since time.Time
+	// Duration is how long the outage lasted.
+	Duration time.Duration
+}
+
+// WithRecoveryCallback calls fn alongside each EventClassifierRecovered,
+// for alerting that does not consume events. It runs on the controller
+// goroutine and must not block.
+func WithRecoveryCallback(fn func(Recovery)) Option {
+	return func(sd *SmartDoor) {
+		sd.onRecovered = fn
+	}
+}
+
+// endOutage is called for each cycle that is not an error. Once the streak
+// that engaged the fail-safe or breaker gives way to a decided cycle, it
+// reports the recovery.
+func (sd *SmartDoor) endOutage(outcome CycleOutcome, now time.Time) {
+	if !sd.outageEngaged {
+		sd.outageSince = time.Time{}
+		return
+	}
+	if outcome != OutcomeDecided {
+		return
+	}
+
+	recovery := Recovery{Since: sd.outageSince, Duration: now.Sub(sd.outageSince)}
+	sd.outageSince = time.Time{}
+	sd.outageEngaged = false
+	sd.mu.Lock()
+	sd.stats.ClassifierRecoveries++
+	sd.mu.Unlock()
+
+	sd.emit(Event{
+		Kind:     EventClassifierRecovered,
+		Time:     now,
+		Message:  fmt.Sprintf("classifier recovered after %s", recovery.Duration),
+		Recovery: &recovery,
+	})
+	if sd.onRecovered != nil {
+		sd.onRecovered(recovery)
+	}
+}
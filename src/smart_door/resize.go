@@ -0,0 +1,97 @@
+package smartdoor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// Resolution is an image size in pixels.
+type Resolution struct {
+	Width, Height int
+}
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+// FrameResizer scales frames to Config.InputResolution before they are
+// classified.
+type FrameResizer interface {
+	ResizeFrame(frame Frame, size Resolution) (Frame, error)
+}
+
+// StandardFrameResizer resizes JPEG and PNG frames by nearest-neighbour
+// sampling and re-encodes them in their own format. Raw frames report
+// ErrUndecodableFrame.
+type StandardFrameResizer struct{}
+
+func (StandardFrameResizer) ResizeFrame(frame Frame, size Resolution) (Frame, error) {
+	src, err := StandardFrameDecoder{}.DecodeFrame(frame)
+	if err != nil {
+		return frame, err
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	for y := range size.Height {
+		sy := bounds.Min.Y + y*bounds.Dy()/size.Height
+		for x := range size.Width {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/size.Width, sy))
+		}
+	}
+
+	var buf bytes.Buffer
+	if frame.Format == FrameFormatPNG {
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, nil)
+	}
+	if err != nil {
+		return frame, err
+	}
+	frame.Data = buf.Bytes()
+	frame.Width, frame.Height = size.Width, size.Height
+	return frame, nil
+}
+
+// WithFrameResizer resizes frames with resizer instead of
+// StandardFrameResizer, for formats it cannot decode or a faster scaler.
+func WithFrameResizer(resizer FrameResizer) Option {
+	return func(sd *SmartDoor) {
+		sd.resizer = resizer
+	}
+}
+
+// resizeFrames scales frames to Config.InputResolution. A frame that
+// cannot be resized is classified as captured, with its Width and Height
+// left as they were.
+func (sd *SmartDoor) resizeFrames(frames []Frame) []Frame {
+	size := sd.currentConfig().InputResolution
+	if size.Width <= 0 || size.Height <= 0 {
+		return frames
+	}
+	resizer := sd.resizer
+	if resizer == nil {
+		resizer = StandardFrameResizer{}
+	}
+
+	resized := make([]Frame, len(frames))
+	for i, frame := range frames {
+		if frame.Width == size.Width && frame.Height == size.Height {
+			resized[i] = frame
+			continue
+		}
+		out, err := resizer.ResizeFrame(frame, size)
+		if err != nil {
+			if !errors.Is(err, ErrUndecodableFrame) {
+				sd.logger.Error(fmt.Sprintf("resize frame to %s: %v", size, err))
+			}
+			out = frame
+		}
+		resized[i] = out
+	}
+	return resized
+}
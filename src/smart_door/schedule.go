@@ -0,0 +1,123 @@
+package smartdoor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule is a set of daily windows evaluated in the wall-clock time of
+// Location, so "22:00-06:00" in America/New_York means 22:00 local time on
+// both sides of a DST transition rather than a fixed UTC offset.
+type Schedule struct {
+	Location *time.Location
+	Windows  []TimeWindow
+}
+
+// TimeWindow spans Start (inclusive) to End (exclusive) as offsets from
+// local midnight. An End before Start wraps past midnight.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// NewSchedule loads location by IANA name and parses windows written as
+// "HH:MM-HH:MM".
+func NewSchedule(location string, windows ...string) (Schedule, error) {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return Schedule{}, err
+	}
+	schedule := Schedule{Location: loc}
+	for _, w := range windows {
+		window, err := ParseTimeWindow(w)
+		if err != nil {
+			return Schedule{}, err
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return schedule, nil
+}
+
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("time window %q: expected HH:MM-HH:MM", s)
+	}
+	startOffset, err := parseTimeOfDay(strings.TrimSpace(start))
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("time window %q: %w", s, err)
+	}
+	endOffset, err := parseTimeOfDay(strings.TrimSpace(end))
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("time window %q: %w", s, err)
+	}
+	return TimeWindow{Start: startOffset, End: endOffset}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls inside any window. A schedule without a
+// location evaluates in UTC.
+func (s Schedule) Contains(t time.Time) bool {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	offset := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+
+	for _, w := range s.Windows {
+		if w.contains(offset) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleJSON is a Schedule with its location written by IANA name, since
+// a *time.Location has no JSON form of its own.
+type scheduleJSON struct {
+	Location string
+	Windows  []TimeWindow
+}
+
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	v := scheduleJSON{Windows: s.Windows}
+	if s.Location != nil {
+		v.Location = s.Location.String()
+	}
+	return json.Marshal(v)
+}
+
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var v scheduleJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Schedule{Windows: v.Windows}
+	if v.Location != "" {
+		loc, err := time.LoadLocation(v.Location)
+		if err != nil {
+			return err
+		}
+		s.Location = loc
+	}
+	return nil
+}
+
+func (w TimeWindow) contains(offset time.Duration) bool {
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
@@ -0,0 +1,69 @@
+package smartdoor
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestScheduleAcrossDSTTransitions(t *testing.T) {
+	schedule, err := NewSchedule("America/New_York", "22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utc := func(s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"winter evening start", utc("2024-03-09T03:00:00Z"), true},      // 22:00 EST
+		{"before spring forward", utc("2024-03-10T06:59:00Z"), true},     // 01:59 EST
+		{"after spring forward", utc("2024-03-10T07:00:00Z"), true},      // 03:00 EDT
+		{"summer morning inside", utc("2024-03-10T09:59:00Z"), true},     // 05:59 EDT
+		{"summer morning end", utc("2024-03-10T10:00:00Z"), false},       // 06:00 EDT
+		{"summer evening before", utc("2024-03-11T01:59:00Z"), false},    // 21:59 EDT
+		{"summer evening start", utc("2024-03-11T02:00:00Z"), true},      // 22:00 EDT
+		{"after fall back inside", utc("2024-11-03T10:59:00Z"), true},    // 05:59 EST
+		{"after fall back end", utc("2024-11-03T11:00:00Z"), false},      // 06:00 EST
+		{"winter evening again", utc("2024-11-04T03:00:00Z"), true},      // 22:00 EST
+		{"winter evening before", utc("2024-11-04T02:59:00Z"), false},    // 21:59 EST
+		{"winter afternoon outside", utc("2024-11-04T20:00:00Z"), false}, // 15:00 EST
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule.Contains(tt.at); got != tt.want {
+				t.Fatalf("Contains(%v) = %v, want %v", tt.at.In(schedule.Location), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleRepeatedHourOnFallBack(t *testing.T) {
+	schedule, err := NewSchedule("America/New_York", "01:30-02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []string{"2024-11-03T05:45:00Z", "2024-11-03T06:45:00Z"} {
+		parsed, _ := time.Parse(time.RFC3339, at)
+		if !schedule.Contains(parsed) {
+			t.Fatalf("expected both 01:45 occurrences inside, missed %v", parsed.In(schedule.Location))
+		}
+	}
+}
+
+func TestParseTimeWindowRejectsMalformed(t *testing.T) {
+	for _, s := range []string{"22:00", "25:00-06:00", "22:00-6pm"} {
+		if _, err := ParseTimeWindow(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
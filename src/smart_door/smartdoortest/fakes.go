@@ -0,0 +1,216 @@
+package smartdoortest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	smartdoor "smart_door"
+)
+
+// Clock is a smartdoor.Clock that only moves when Advance is called.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewClock starts at noon UTC on 1 January 2024.
+func NewClock() *Clock {
+	c := &Clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).ch
+}
+
+func (c *Clock) NewTicker(d time.Duration) smartdoor.Ticker {
+	return &ticker{clock: c, waiter: c.add(d, d)}
+}
+
+func (c *Clock) NewTimer(d time.Duration) smartdoor.Timer {
+	return &ticker{clock: c, waiter: c.add(d, 0)}
+}
+
+func (c *Clock) add(d, period time.Duration) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+func (c *Clock) remove(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers it
+// passes.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(c.now) {
+				w.at = w.at.Add(w.period)
+			}
+			kept = append(kept, w)
+		}
+	}
+	c.waiters = kept
+}
+
+// BlockUntil waits until n timers or tickers are registered on the clock.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+type ticker struct {
+	clock  *Clock
+	waiter *waiter
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *ticker) Stop() {
+	t.clock.remove(t.waiter)
+}
+
+// Camera is a smartdoor.DeviceCamera that captures the same frames every
+// time, a single empty one until SetFrames.
+type Camera struct {
+	mu     sync.Mutex
+	events chan smartdoor.DeviceCameraEvent
+	frames []smartdoor.Frame
+	err    error
+}
+
+func NewCamera() *Camera {
+	return &Camera{
+		events: make(chan smartdoor.DeviceCameraEvent, 16),
+		frames: []smartdoor.Frame{{}},
+	}
+}
+
+func (c *Camera) Subscribe() <-chan smartdoor.DeviceCameraEvent {
+	return c.events
+}
+
+func (c *Camera) CaptureFrames(ctx context.Context) ([]smartdoor.Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames, c.err
+}
+
+// SetFrames sets what every capture returns.
+func (c *Camera) SetFrames(frames []smartdoor.Frame, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames, c.err = frames, err
+}
+
+// Door is a smartdoor.DeviceDoor that records the actions applied to it.
+type Door struct {
+	mu      sync.Mutex
+	events  chan smartdoor.DeviceDoorEvent
+	actions []smartdoor.DoorAction
+}
+
+func NewDoor() *Door {
+	return &Door{events: make(chan smartdoor.DeviceDoorEvent, 16)}
+}
+
+func (d *Door) Subscribe() <-chan smartdoor.DeviceDoorEvent {
+	return d.events
+}
+
+func (d *Door) Lock(ctx context.Context) error {
+	return d.record(smartdoor.ActionLock)
+}
+
+func (d *Door) Unlock(ctx context.Context) error {
+	return d.record(smartdoor.ActionUnlock)
+}
+
+func (d *Door) record(action smartdoor.DoorAction) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = append(d.actions, action)
+	return nil
+}
+
+// Actions returns every action applied, oldest first.
+func (d *Door) Actions() []smartdoor.DoorAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]smartdoor.DoorAction(nil), d.actions...)
+}
+
+// Classifier is a smartdoor.ImageClassifier that answers every call with
+// the result of the latest Set.
+type Classifier struct {
+	mu              sync.Mutex
+	classifications [][]smartdoor.Classification
+	err             error
+	calls           int
+}
+
+// Set makes every following call return classifications and err.
+func (c *Classifier) Set(classifications [][]smartdoor.Classification, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.classifications, c.err = classifications, err
+}
+
+func (c *Classifier) ClassifyFrames(ctx context.Context, frames []smartdoor.Frame) ([][]smartdoor.Classification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return c.classifications, c.err
+}
+
+// Calls counts the calls made.
+func (c *Classifier) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
@@ -0,0 +1,37 @@
+package smartdoortest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockFiresWhatItPasses(t *testing.T) {
+	c := NewClock()
+	start := c.Now()
+	timer := c.NewTimer(time.Minute)
+	ticker := c.NewTicker(30 * time.Second)
+	stopped := c.NewTimer(time.Second)
+	stopped.Stop()
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("expected the timer to wait its minute")
+	default:
+	}
+	if at := <-ticker.C(); !at.Equal(start.Add(30 * time.Second)) {
+		t.Fatalf("expected the first tick 30s in, got %s", at)
+	}
+
+	c.Advance(30 * time.Second)
+	if at := <-timer.C(); !at.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected the timer a minute in, got %s", at)
+	}
+	<-ticker.C()
+	select {
+	case <-stopped.C():
+		t.Fatal("expected a stopped timer never to fire")
+	default:
+	}
+	c.BlockUntil(1)
+}
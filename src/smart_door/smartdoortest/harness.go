@@ -0,0 +1,124 @@
+// Package smartdoortest provides fake devices and a Harness for testing
+// code built on a SmartDoor, in the manner of net/http/httptest.
+package smartdoortest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	smartdoor "smart_door"
+)
+
+// cameraID and doorID are the IDs NewSmartDoor gives the camera and door
+// it is built with.
+const (
+	cameraID = "cam0"
+	doorID   = "door0"
+)
+
+// Harness wires a SmartDoor to the fakes and drives it one cycle at a time
+// through EvaluateOnce, so scenario tests read as a script without
+// goroutines or sleeps.
+type Harness struct {
+	t          testing.TB
+	SmartDoor  *smartdoor.SmartDoor
+	Camera     *Camera
+	Door       *Door
+	Classifier *Classifier
+	Clock      *Clock
+
+	events   <-chan smartdoor.Event
+	recorded []smartdoor.Event
+}
+
+// NewHarness builds a SmartDoor from config and opts on the fakes, its
+// clock first so opts may replace it. Config.AllowEventInjection is set, as
+// CameraEvent and DoorEvent inject their events.
+func NewHarness(t testing.TB, config smartdoor.Config, opts ...smartdoor.Option) *Harness {
+	t.Helper()
+	config.AllowEventInjection = true
+	h := &Harness{
+		t:          t,
+		Camera:     NewCamera(),
+		Door:       NewDoor(),
+		Classifier: &Classifier{},
+		Clock:      NewClock(),
+	}
+	opts = append([]smartdoor.Option{smartdoor.WithClock(h.Clock)}, opts...)
+	h.SmartDoor = smartdoor.NewSmartDoor(config, h.Camera, h.Door, h.Classifier, opts...)
+	h.events = h.SmartDoor.Events()
+	return h
+}
+
+// Cycle runs one cycle whose frames classify as batch and returns what it
+// decided for the door. A door error fails the test.
+func (h *Harness) Cycle(batch [][]smartdoor.Classification) (smartdoor.Detection, smartdoor.DoorAction) {
+	h.t.Helper()
+	h.Classifier.Set(batch, nil)
+	detection, actions, err := h.SmartDoor.EvaluateOnce(context.Background())
+	if err != nil {
+		h.t.Fatalf("cycle: %v", err)
+	}
+	return detection, actions[doorID]
+}
+
+// Fail runs one cycle whose classification fails with err.
+func (h *Harness) Fail(err error) (smartdoor.Detection, smartdoor.DoorAction) {
+	h.t.Helper()
+	h.Classifier.Set(nil, err)
+	detection, actions, _ := h.SmartDoor.EvaluateOnce(context.Background())
+	return detection, actions[doorID]
+}
+
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+func (h *Harness) CameraEvent(event smartdoor.DeviceCameraEvent) {
+	h.t.Helper()
+	if err := h.SmartDoor.InjectCameraEvent(cameraID, event); err != nil {
+		h.t.Fatalf("camera event: %v", err)
+	}
+}
+
+func (h *Harness) DoorEvent(event smartdoor.DeviceDoorEvent) {
+	h.t.Helper()
+	if err := h.SmartDoor.InjectDoorEvent(event); err != nil {
+		h.t.Fatalf("door event: %v", err)
+	}
+}
+
+// DoorActions returns every action the door has applied, oldest first.
+func (h *Harness) DoorActions() []smartdoor.DoorAction {
+	return h.Door.Actions()
+}
+
+// Events returns every event emitted since the harness was created. It
+// fails the test if the subscription overflowed and dropped any, since the
+// record would then be incomplete.
+func (h *Harness) Events() []smartdoor.Event {
+	h.t.Helper()
+	for {
+		select {
+		case e := <-h.events:
+			h.recorded = append(h.recorded, e)
+		default:
+			if dropped := h.SmartDoor.Stats().EventsDropped; dropped > 0 {
+				h.t.Fatalf("harness dropped %d events; drain Events more often", dropped)
+			}
+			return append([]smartdoor.Event(nil), h.recorded...)
+		}
+	}
+}
+
+// EventsOf returns the emitted events of kind.
+func (h *Harness) EventsOf(kind smartdoor.EventKind) []smartdoor.Event {
+	var matching []smartdoor.Event
+	for _, e := range h.Events() {
+		if e.Kind == kind {
+			matching = append(matching, e)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,108 @@
+package smartdoortest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	smartdoor "smart_door"
+)
+
+var errBusy = errors.New("classifier busy")
+
+func dogDoorConfig() smartdoor.Config {
+	return smartdoor.Config{
+		ClassificationUnlockList: []smartdoor.ClassificationConfig{{Label: "dog", MinConfidence: 0.5}},
+		ClassificationLockList:   []smartdoor.ClassificationConfig{{Label: "cat", MinConfidence: 0.5}},
+		StartArmed:               true,
+	}
+}
+
+func dogBatch() [][]smartdoor.Classification {
+	return [][]smartdoor.Classification{{{Label: "dog", Confidence: 0.9}}}
+}
+
+func noneBatch() [][]smartdoor.Classification {
+	return [][]smartdoor.Classification{{{Label: "tree", Confidence: 0.9}}}
+}
+
+func TestHarnessRecordsCyclesActionsAndEvents(t *testing.T) {
+	h := NewHarness(t, dogDoorConfig())
+
+	if detection, action := h.Cycle(dogBatch()); detection != smartdoor.DetectionDog || action != smartdoor.ActionUnlock {
+		t.Fatalf("expected dog/unlock, got %v/%v", detection, action)
+	}
+	if detection, action := h.Fail(errBusy); detection != smartdoor.DetectionDog || action != smartdoor.ActionNone {
+		t.Fatalf("expected a failed cycle to hold, got %v/%v", detection, action)
+	}
+	if got := h.DoorActions(); len(got) != 1 || got[0] != smartdoor.ActionUnlock {
+		t.Fatalf("expected the unlock applied, got %v", got)
+	}
+	if got := h.EventsOf(smartdoor.EventActionApplied); len(got) != 1 || got[0].Action != smartdoor.ActionUnlock {
+		t.Fatalf("expected one applied event, got %+v", got)
+	}
+	kinds := func() []smartdoor.EventKind {
+		var kinds []smartdoor.EventKind
+		for _, e := range h.Events() {
+			kinds = append(kinds, e.Kind)
+		}
+		return kinds
+	}
+	want := []smartdoor.EventKind{smartdoor.EventAction, smartdoor.EventActionApplied, smartdoor.EventNoAction}
+	if got := kinds(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	if got := kinds(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected Events to keep what it has recorded, got %v", got)
+	}
+
+	start := h.Clock.Now()
+	h.Advance(time.Minute)
+	h.CameraEvent(smartdoor.CameraEventConnected)
+	h.DoorEvent(smartdoor.DoorEventConnected)
+	c := h.SmartDoor.Connectivity()
+	if !c.Camera.Connected || !c.Door.Connected || !c.Door.ChangedAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected both devices connected a minute in, got %+v", c)
+	}
+}
+
+func TestScenarioDogArrivesAndLeaves(t *testing.T) {
+	config := dogDoorConfig()
+	config.AbsenceDebounce = 2 * time.Second
+	config.RelockDelay = time.Second
+	h := NewHarness(t, config)
+
+	// The dog walks up and the door unlocks.
+	h.Cycle(noneBatch())
+	h.Advance(time.Second)
+	if _, action := h.Cycle(dogBatch()); action != smartdoor.ActionUnlock {
+		t.Fatalf("expected the dog to unlock, got %v", action)
+	}
+	if state := h.SmartDoor.DoorState(); state != smartdoor.DoorStateUnlocked {
+		t.Fatalf("expected unlocked, got %v", state)
+	}
+
+	// The dog leaves; the door waits out the debounce and delay, then
+	// relocks.
+	var relockedAfter time.Duration
+	for elapsed := time.Duration(0); elapsed <= 5*time.Second; elapsed += time.Second {
+		h.Advance(time.Second)
+		if _, action := h.Cycle(noneBatch()); action == smartdoor.ActionLock {
+			relockedAfter = elapsed + time.Second
+			break
+		}
+	}
+	if relockedAfter != 4*time.Second {
+		t.Fatalf("expected the relock 4s after the dog left, got %s", relockedAfter)
+	}
+	if state := h.SmartDoor.DoorState(); state != smartdoor.DoorStateLocked {
+		t.Fatalf("expected locked, got %v", state)
+	}
+	if got := h.DoorActions(); len(got) != 2 || got[0] != smartdoor.ActionUnlock || got[1] != smartdoor.ActionLock {
+		t.Fatalf("expected unlock then lock, got %v", got)
+	}
+	if got := h.EventsOf(smartdoor.EventAction); len(got) != 2 {
+		t.Fatalf("expected two decided actions, got %+v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package smartdoor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Snapshot captures the current frames of every camera, in camera order,
+// for a live view. It runs outside the capture loop: it neither waits for
+// MinimalRateCameraProcess nor counts as a cycle, so the loop's cadence
+// and rate limiting are unaffected. The frames are not classified. A
+// camera is never asked for frames by Snapshot and the loop at once.
+func (sd *SmartDoor) Snapshot(ctx context.Context) ([]Frame, error) {
+	var frames []Frame
+	for _, slot := range sd.cameras {
+		captured, err := sd.capture(ctx, slot)
+		if err != nil {
+			return nil, fmt.Errorf("smartdoor: snapshot from %s: %w", slot.id, err)
+		}
+		frames = append(frames, captured...)
+	}
+	return frames, nil
+}
+
+// capture takes frames from slot's camera, one caller at a time, since
+// cameras need not allow concurrent captures.
+func (sd *SmartDoor) capture(ctx context.Context, slot cameraSlot) ([]Frame, error) {
+	sd.captureMu.Lock()
+	defer sd.captureMu.Unlock()
+	return slot.camera.CaptureFrames(ctx)
+}
@@ -0,0 +1,194 @@
+package smartdoor
+
+import (
+	"context"
+	"time"
+)
+
+// Summary aggregates the door's activity over a window, for daily logs and
+// email digests.
+type Summary struct {
+	Start time.Time
+	End   time.Time
+	// Unlocks and Locks count actions the door applied.
+	Unlocks int
+	Locks   int
+	// Detections counts, by label, the decided cycles in which a
+	// classification matched the unlock, lock or ignore list at its
+	// threshold, whether or not the cycle acted.
+	Detections map[string]int
+	// Errors counts failed cycles and door calls.
+	Errors int
+	// Uptime is how long Run had been running at End.
+	Uptime time.Duration
+	// MaxContinuousUnlock is the longest the door stayed unlocked within
+	// the window, an unlock still in progress included.
+	MaxContinuousUnlock time.Duration
+	// TimeUnlocked is how long the door was unlocked in all within the
+	// window. Time the door spent disconnected is left out, since nothing
+	// is known of it then.
+	TimeUnlocked time.Duration
+}
+
+// summaryWindow accumulates the current Summary. It is guarded by sd.mu.
+type summaryWindow struct {
+	start      time.Time
+	unlocks    int
+	locks      int
+	errors     int
+	detections map[string]int
+	maxUnlock  time.Duration
+	// unlockedAt is when the door was last unlocked, zero while locked.
+	// It carries over into the next window.
+	unlockedAt time.Time
+	// unlockedTotal is TimeUnlocked up to countingSince, which is when
+	// the current stretch of unlocked, connected time began, zero while
+	// the door is locked or disconnected. countingSince carries over too.
+	unlockedTotal time.Duration
+	countingSince time.Time
+}
+
+// timeUnlocked is TimeUnlocked as of now.
+func (w *summaryWindow) timeUnlocked(now time.Time) time.Duration {
+	if w.countingSince.IsZero() {
+		return w.unlockedTotal
+	}
+	return w.unlockedTotal + now.Sub(latest(w.countingSince, w.start))
+}
+
+// pauseUnlocked stops TimeUnlocked accumulating.
+func (w *summaryWindow) pauseUnlocked(now time.Time) {
+	w.unlockedTotal = w.timeUnlocked(now)
+	w.countingSince = time.Time{}
+}
+
+// unlockedFor is how long the door has been unlocked within the window.
+func (w *summaryWindow) unlockedFor(now time.Time) time.Duration {
+	if w.unlockedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(latest(w.unlockedAt, w.start))
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// EmitSummary closes the current summary window, emits it as EventSummary
+// and starts the next one. Run calls it every Config.SummaryInterval.
+func (sd *SmartDoor) EmitSummary() Summary {
+	now := sd.clock.Now()
+	sd.mu.Lock()
+	w := &sd.summary
+	summary := Summary{
+		Start:               w.start,
+		End:                 now,
+		Unlocks:             w.unlocks,
+		Locks:               w.locks,
+		Detections:          w.detections,
+		Errors:              w.errors,
+		MaxContinuousUnlock: max(w.maxUnlock, w.unlockedFor(now)),
+		TimeUnlocked:        w.timeUnlocked(now),
+	}
+	if !sd.startedAt.IsZero() {
+		summary.Uptime = now.Sub(sd.startedAt)
+	}
+	if summary.Detections == nil {
+		summary.Detections = map[string]int{}
+	}
+	sd.summary = summaryWindow{start: now, unlockedAt: w.unlockedAt, countingSince: w.countingSince}
+	sd.mu.Unlock()
+
+	sd.emit(Event{Kind: EventSummary, Time: now, Summary: &summary})
+	return summary
+}
+
+func (sd *SmartDoor) summarize(ctx context.Context) {
+	ticker := sd.clock.NewTicker(sd.currentConfig().SummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			sd.trace(TraceEntry{Kind: TraceKindTick, Tick: tickSummary})
+			sd.EmitSummary()
+		}
+	}
+}
+
+// countAppliedLocked must be called with sd.mu held.
+func (sd *SmartDoor) countAppliedLocked(action DoorAction, now time.Time) {
+	w := &sd.summary
+	if action == ActionUnlock {
+		w.unlocks++
+		if w.unlockedAt.IsZero() {
+			w.unlockedAt = now
+		}
+		if w.countingSince.IsZero() && !sd.doorDisconnectedLocked() {
+			w.countingSince = now
+		}
+		return
+	}
+	w.locks++
+	w.maxUnlock = max(w.maxUnlock, w.unlockedFor(now))
+	w.unlockedAt = time.Time{}
+	w.pauseUnlocked(now)
+}
+
+// countDoorConnectivityLocked pauses TimeUnlocked while the door is
+// disconnected and resumes it on reconnection if the door is still
+// unlocked. It must be called with sd.mu held.
+func (sd *SmartDoor) countDoorConnectivityLocked(connected bool, now time.Time) {
+	w := &sd.summary
+	if !connected {
+		w.pauseUnlocked(now)
+		return
+	}
+	if !w.unlockedAt.IsZero() && w.countingSince.IsZero() {
+		w.countingSince = now
+	}
+}
+
+// doorDisconnectedLocked reports whether the door has reported a
+// disconnection it has not recovered from. It must be called with sd.mu
+// held.
+func (sd *SmartDoor) doorDisconnectedLocked() bool {
+	door := sd.connectivity.Door
+	return !door.Connected && !door.ChangedAt.IsZero()
+}
+
+// countDetectionsLocked counts once every label of a decided cycle that
+// matches a list entry at its threshold. It must be called with sd.mu
+// held.
+func (sd *SmartDoor) countDetectionsLocked(classifications [][]Classification) {
+	config := sd.effectiveConfigLocked()
+	seen := make(map[string]bool)
+	for _, frame := range classifications {
+		for _, c := range frame {
+			if seen[c.Label] {
+				continue
+			}
+			for _, list := range [][]ClassificationConfig{config.ClassificationUnlockList, config.ClassificationLockList, config.IgnoreList} {
+				for _, entry := range list {
+					if !seen[c.Label] && labelMatches(c.Label, entry.Label) && c.Confidence >= sd.thresholdLocked(entry) {
+						seen[c.Label] = true
+					}
+				}
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return
+	}
+	if sd.summary.detections == nil {
+		sd.summary.detections = make(map[string]int)
+	}
+	for label := range seen {
+		sd.summary.detections[label]++
+	}
+}
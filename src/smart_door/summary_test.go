@@ -0,0 +1,97 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSummaryReflectsWindowActivity(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	events := sd.Events()
+	start := clock.Now()
+	cat := [][]Classification{{{Label: "cat", Confidence: 0.9}}}
+
+	sd.handleClassifications(dogBatch())
+	applyActions(sd)
+	clock.Advance(5 * time.Minute)
+	sd.handleClassifications(cat)
+	applyActions(sd)
+	clock.Advance(time.Minute)
+	sd.handleClassifications(dogBatch())
+	applyActions(sd)
+	clock.Advance(2 * time.Minute)
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+	clock.Advance(time.Minute)
+
+	summary := sd.EmitSummary()
+	if summary.Unlocks != 2 || summary.Locks != 1 || summary.Errors != 1 {
+		t.Fatalf("expected 2 unlocks, 1 lock and 1 error, got %+v", summary)
+	}
+	if summary.Detections["dog"] != 2 || summary.Detections["cat"] != 1 {
+		t.Fatalf("expected detections dog 2 and cat 1, got %v", summary.Detections)
+	}
+	if summary.MaxContinuousUnlock != 5*time.Minute {
+		t.Fatalf("expected the 5m unlock to be the longest, got %s", summary.MaxContinuousUnlock)
+	}
+	if !summary.Start.Equal(start) || !summary.End.Equal(start.Add(9*time.Minute)) {
+		t.Fatalf("expected the window to span the activity, got %s to %s", summary.Start, summary.End)
+	}
+	for event := range events {
+		if event.Kind == EventSummary {
+			if event.Summary == nil || event.Summary.Unlocks != 2 {
+				t.Fatalf("expected the emitted summary, got %+v", event.Summary)
+			}
+			break
+		}
+	}
+
+	clock.Advance(10 * time.Minute)
+	next := sd.EmitSummary()
+	if next.Unlocks != 0 || next.Locks != 0 || next.Errors != 0 || len(next.Detections) != 0 {
+		t.Fatalf("expected counts to reset with the window, got %+v", next)
+	}
+	if !next.Start.Equal(summary.End) || next.MaxContinuousUnlock != 10*time.Minute {
+		t.Fatalf("expected the ongoing unlock to carry into the next window, got %+v", next)
+	}
+}
+
+func TestTimeUnlockedPausesWhileDoorDisconnected(t *testing.T) {
+	sd, _, _, clock := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	sd.handleDoorEvent(DoorEventConnected)
+
+	sd.handleClassifications(dogBatch())
+	applyActions(sd)
+	clock.Advance(2 * time.Minute)
+	sd.handleDoorEvent(DoorEventDisconnected)
+	clock.Advance(10 * time.Minute)
+	if got := sd.EmitSummary().TimeUnlocked; got != 2*time.Minute {
+		t.Fatalf("expected 2m unlocked before the disconnection, got %s", got)
+	}
+
+	clock.Advance(5 * time.Minute)
+	sd.handleDoorEvent(DoorEventConnected)
+	clock.Advance(3 * time.Minute)
+	if got := sd.EmitSummary().TimeUnlocked; got != 3*time.Minute {
+		t.Fatalf("expected 3m unlocked since reconnecting, got %s", got)
+	}
+}
+
+func TestSummaryCountsDetectionsWithoutActions(t *testing.T) {
+	config := dogDoorConfig()
+	config.IgnoreList = []ClassificationConfig{{Label: "person", MinConfidence: 0.5}}
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{})
+
+	for range 3 {
+		sd.handleClassifications(dogBatch())
+		clock.Advance(time.Second)
+	}
+	expectActions(t, sd, ActionUnlock)
+	sd.handleClassifications([][]Classification{{{Label: "person", Confidence: 0.9}, {Label: "dog", Confidence: 0.2}}})
+	sd.handleCycle(context.Background(), CycleResult{Outcome: OutcomeError})
+
+	summary := sd.EmitSummary()
+	if summary.Detections["dog"] != 3 || summary.Detections["person"] != 1 || len(summary.Detections) != 2 {
+		t.Fatalf("expected dog in every unchanged cycle and one person, got %v", summary.Detections)
+	}
+}
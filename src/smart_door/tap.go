@@ -0,0 +1,80 @@
+package smartdoor
+
+import "time"
+
+const tapBufferSize = 16
+
+// ClassificationSnapshot is the raw input and output of one cycle's
+// classification, before any decision is made on it.
+type ClassificationSnapshot struct {
+	Time            time.Time
+	Frames          []FrameInfo
+	Classifications [][]Classification
+	Outcome         CycleOutcome
+	Err             error
+}
+
+// FrameInfo describes a classified frame without its data.
+type FrameInfo struct {
+	Camera     string
+	Format     FrameFormat
+	Size       int
+	CapturedAt time.Time
+}
+
+// ClassificationTap subscribes to a snapshot of every cycle the camera
+// pipeline classifies, for analysis while tuning. Like Events, delivery
+// never blocks: snapshots for a full buffer are dropped and counted in
+// Stats.TapDropped. Calling the returned func, or Run shutting down,
+// closes the channel.
+func (sd *SmartDoor) ClassificationTap() (<-chan ClassificationSnapshot, func()) {
+	ch := make(chan ClassificationSnapshot, tapBufferSize)
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	sd.taps = append(sd.taps, ch)
+	return ch, func() {
+		sd.mu.Lock()
+		defer sd.mu.Unlock()
+		for i, tap := range sd.taps {
+			if tap == ch {
+				sd.taps = append(sd.taps[:i], sd.taps[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+}
+
+// publishTap hands result to every ClassificationTap.
+func (sd *SmartDoor) publishTap(result CycleResult) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if len(sd.taps) == 0 {
+		return
+	}
+	snapshot := ClassificationSnapshot{
+		Time:            sd.clock.Now(),
+		Classifications: cloneClassifications(result.Classifications),
+		Outcome:         result.Outcome,
+		Err:             result.Err,
+	}
+	for i, frame := range result.Frames {
+		snapshot.Frames = append(snapshot.Frames, FrameInfo{
+			Camera:     result.cameraOf(i),
+			Format:     frame.Format,
+			Size:       len(frame.Data),
+			CapturedAt: frame.CapturedAt,
+		})
+	}
+	for _, tap := range sd.taps {
+		select {
+		case tap <- snapshot:
+		default:
+			sd.stats.TapDropped++
+		}
+	}
+}
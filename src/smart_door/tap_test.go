@@ -0,0 +1,38 @@
+package smartdoor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClassificationTapDeliversEachCycleUntilUnsubscribed(t *testing.T) {
+	classifier := &fakeClassifier{results: []fakeResult{
+		{classifications: dogBatch()},
+		{classifications: noneBatch()},
+	}}
+	sd, camera, _, clock := newTestSmartDoor(dogDoorConfig(), classifier)
+	captured := clock.Now().Add(-time.Second)
+	camera.frames = []Frame{{Data: []byte("abc"), Format: FrameFormatJPEG, CapturedAt: captured}}
+	tap, unsubscribe := sd.ClassificationTap()
+
+	for _, want := range [][][]Classification{dogBatch(), noneBatch()} {
+		sd.runCycle(context.Background())
+		snapshot := <-tap
+		if !reflect.DeepEqual(snapshot.Classifications, want) || snapshot.Outcome != OutcomeDecided {
+			t.Fatalf("expected %v, got %+v", want, snapshot)
+		}
+		wantFrames := []FrameInfo{{Camera: defaultCameraID, Format: FrameFormatJPEG, Size: 3, CapturedAt: captured}}
+		if !reflect.DeepEqual(snapshot.Frames, wantFrames) || !snapshot.Time.Equal(clock.Now()) {
+			t.Fatalf("expected frames %+v at %v, got %+v", wantFrames, clock.Now(), snapshot)
+		}
+	}
+
+	unsubscribe()
+	sd.runCycle(context.Background())
+	if _, ok := <-tap; ok {
+		t.Fatal("expected no delivery after unsubscribing")
+	}
+	unsubscribe()
+}
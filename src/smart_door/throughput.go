@@ -0,0 +1,39 @@
+package smartdoor
+
+import "time"
+
+// throughputWindow is how far back Stats.FramesPerSecond looks.
+const throughputWindow = 10 * time.Second
+
+type frameSample struct {
+	at     time.Time
+	frames int
+}
+
+// recordFramesLocked must be called with sd.mu held.
+func (sd *SmartDoor) recordFramesLocked(now time.Time, frames int) {
+	sd.stats.FramesProcessed += frames
+	sd.frameSamples = append(sd.frameSamples, frameSample{at: now, frames: frames})
+	sd.pruneFrameSamplesLocked(now)
+}
+
+func (sd *SmartDoor) pruneFrameSamplesLocked(now time.Time) {
+	cutoff := now.Add(-throughputWindow)
+	i := 0
+	for i < len(sd.frameSamples) && !sd.frameSamples[i].at.After(cutoff) {
+		i++
+	}
+	sd.frameSamples = sd.frameSamples[i:]
+}
+
+// framesPerSecondLocked averages classified frames over the last
+// throughputWindow, so it reflects what timeouts, skips and classifier
+// latency leave of the configured rate.
+func (sd *SmartDoor) framesPerSecondLocked(now time.Time) float64 {
+	sd.pruneFrameSamplesLocked(now)
+	var frames int
+	for _, s := range sd.frameSamples {
+		frames += s.frames
+	}
+	return float64(frames) / throughputWindow.Seconds()
+}
@@ -0,0 +1,465 @@
+package smartdoor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceKind int
+
+const (
+	// TraceKindCycle is a CycleResult handed to the decision loop.
+	TraceKindCycle TraceKind = iota
+	TraceKindCameraEvent
+	TraceKindDoorEvent
+	// TraceKindDoorState is a DoorStateReader reading.
+	TraceKindDoorState
+	// TraceKindTick is the heartbeat or summary ticker firing.
+	TraceKindTick
+	// TraceKindCall is a manual input such as ForceLock or UpdateConfig.
+	TraceKindCall
+)
+
+// The TraceEntry.Tick of each ticker traced.
+const (
+	tickHeartbeat = "heartbeat"
+	tickSummary   = "summary"
+)
+
+// The TraceEntry.Call of each method traced.
+const (
+	callForceLock     = "ForceLock"
+	callForceUnlock   = "ForceUnlock"
+	callClearOverride = "ClearOverride"
+	callArm           = "Arm"
+	callDisarm        = "Disarm"
+	callUpdateConfig  = "UpdateConfig"
+	callSetProfiles   = "SetProfiles"
+	callCancelPending = "CancelPending"
+)
+
+// TraceEntry is one input to the decision logic, stamped with the time
+// the controller took it.
+type TraceEntry struct {
+	Kind   TraceKind
+	Time   time.Time
+	Camera string
+	// Door is the ID of the secondary door of a TraceKindDoorEvent,
+	// empty for the primary door.
+	Door        string
+	CameraEvent DeviceCameraEvent
+	DoorEvent   DeviceDoorEvent
+	DoorState   DoorState
+	Cycle       *TraceCycle
+	// Tick names the ticker of a TraceKindTick: "heartbeat" or "summary".
+	Tick string
+	// Call names the method of a TraceKindCall, such as "ForceLock".
+	// Until, Config and Profiles are its arguments, the configs with their
+	// labels files already loaded so the replay does not need them.
+	Call     string
+	Until    time.Time
+	Config   *Config
+	Profiles []Profile
+}
+
+// TraceCycle is a CycleResult as traced, with each frame reduced to a hash.
+type TraceCycle struct {
+	Frames          []TraceFrame
+	Classifications [][]Classification
+	Cameras         []string
+	Outcome         CycleOutcome
+	Err             string
+}
+
+// TraceFrame describes a frame without its data. Hash is the hex SHA-256 of
+// Frame.Data.
+type TraceFrame struct {
+	Hash          string
+	Format        FrameFormat
+	CapturedAt    time.Time
+	Width, Height int
+}
+
+// TraceRecorder writes every input the decision logic takes, as JSON
+// lines, so a TracePlayer can replay a problematic run offline. It traces
+// device events, door state readings, cycles, the heartbeat and summary
+// ticks and the manual inputs ForceLock, ForceUnlock, ClearOverride, Arm,
+// Disarm, UpdateConfig, SetProfiles and CancelPending, each with its time.
+// Other calls, such as DisableLabel or SetCaptureRate, are not traced.
+type TraceRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewTraceRecorder writes the trace to w. Install it with
+// WithTraceRecorder.
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	return &TraceRecorder{enc: json.NewEncoder(w)}
+}
+
+// WithTraceRecorder records every input to recorder.
+func WithTraceRecorder(recorder *TraceRecorder) Option {
+	return func(sd *SmartDoor) {
+		sd.tracer = recorder
+	}
+}
+
+// Err is the first write error, after which nothing more is recorded.
+func (r *TraceRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *TraceRecorder) record(entry TraceEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(entry)
+	}
+}
+
+// trace stamps entry with the current time and records it, if a
+// TraceRecorder is installed.
+func (sd *SmartDoor) trace(entry TraceEntry) {
+	if sd.tracer == nil {
+		return
+	}
+	entry.Time = sd.clock.Now()
+	sd.tracer.record(entry)
+}
+
+func traceCycle(result CycleResult) *TraceCycle {
+	cycle := &TraceCycle{
+		Classifications: result.Classifications,
+		Cameras:         result.Cameras,
+		Outcome:         result.Outcome,
+	}
+	for _, frame := range result.Frames {
+		sum := sha256.Sum256(frame.Data)
+		cycle.Frames = append(cycle.Frames, TraceFrame{
+			Hash:       hex.EncodeToString(sum[:]),
+			Format:     frame.Format,
+			CapturedAt: frame.CapturedAt,
+			Width:      frame.Width,
+			Height:     frame.Height,
+		})
+	}
+	if result.Err != nil {
+		cycle.Err = result.Err.Error()
+	}
+	return cycle
+}
+
+// result rebuilds the CycleResult, its frames without data.
+func (c *TraceCycle) result() CycleResult {
+	result := CycleResult{
+		Classifications: c.Classifications,
+		Cameras:         c.Cameras,
+		Outcome:         c.Outcome,
+		Err:             traceError(c.Err),
+	}
+	for _, frame := range c.Frames {
+		result.Frames = append(result.Frames, Frame{
+			Format:     frame.Format,
+			CapturedAt: frame.CapturedAt,
+			Width:      frame.Width,
+			Height:     frame.Height,
+		})
+	}
+	return result
+}
+
+// traceSentinels are the errors the decision logic tells apart, restored
+// on replay so errors.Is still matches them.
+var traceSentinels = []error{
+	ErrBreakerOpen,
+	ErrStaleClassifier,
+	ErrDoorCallTimeout,
+	ErrUndecodableFrame,
+	context.DeadlineExceeded,
+	context.Canceled,
+}
+
+func traceError(message string) error {
+	if message == "" {
+		return nil
+	}
+	for _, sentinel := range traceSentinels {
+		if prefix, ok := strings.CutSuffix(message, sentinel.Error()); ok {
+			return fmt.Errorf("%s%w", prefix, sentinel)
+		}
+	}
+	return errors.New(message)
+}
+
+// TracePlayer replays a recorded trace through a SmartDoor's decision
+// logic, on a clock of its own that follows the trace.
+type TracePlayer struct {
+	entries []TraceEntry
+	clock   *traceClock
+}
+
+// NewTracePlayer reads a trace written by a TraceRecorder.
+func NewTracePlayer(r io.Reader) (*TracePlayer, error) {
+	p := &TracePlayer{clock: &traceClock{}}
+	dec := json.NewDecoder(r)
+	for {
+		var entry TraceEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read trace entry %d: %w", len(p.entries)+1, err)
+		}
+		p.entries = append(p.entries, entry)
+	}
+	if len(p.entries) > 0 {
+		p.clock.now = p.entries[0].Time
+	}
+	return p, nil
+}
+
+// Clock is the replay clock, which the SmartDoor handed to Play must be
+// built with through WithClock.
+func (p *TracePlayer) Clock() Clock {
+	return p.clock
+}
+
+// Play feeds every entry of the trace to sd, with its clock set to the
+// entry's time, applying the actions each decides to every door before
+// moving on, as EvaluateOnce does. sd should be built with the Config and
+// options of the recorded run and its own doors, which receive the replayed
+// actions; anything else sd asks its devices for, such as the extra capture
+// of Config.ConfirmWithExtraCapture, comes from them rather than the trace.
+// Actions wait out Config.ConfirmDelay in trace time, so a traced
+// CancelPending drops the same action it did in the recorded run. Like
+// Run, Play may only be called once, applies the actions still pending at
+// the end, and closes the event subscriptions when it returns.
+func (p *TracePlayer) Play(ctx context.Context, sd *SmartDoor) error {
+	if sd.clock != Clock(p.clock) {
+		return errors.New("smartdoor: replay needs a SmartDoor built WithClock(player.Clock())")
+	}
+	ctx, finish, err := sd.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	defer sd.closeSubscribers()
+
+	// pending holds the actions in or awaiting their confirm window, as
+	// the executor would; the head's window ends at due, which stays zero
+	// until it is announced.
+	var pending []replayPending
+	announce := func(start time.Time) {
+		if len(pending) == 0 || !pending[0].due.IsZero() {
+			return
+		}
+		delay := sd.currentConfig().ConfirmDelay
+		pending[0].due = start.Add(delay)
+		if delay > 0 {
+			sd.announcePending(pending[0].action, delay, start)
+		}
+	}
+	confirm := func(now time.Time) {
+		for len(pending) > 0 && !pending[0].due.After(now) {
+			due := pending[0].due
+			sd.executeAction(ctx, pending[0].action)
+			pending = pending[1:]
+			announce(due)
+		}
+	}
+	defer func() {
+		for _, queued := range pending {
+			sd.executeAction(ctx, queued.action)
+		}
+	}()
+
+	for _, entry := range p.entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.clock.set(entry.Time)
+		confirm(entry.Time)
+		switch entry.Kind {
+		case TraceKindCameraEvent:
+			sd.handleCameraEvent(entry.Camera, entry.CameraEvent)
+		case TraceKindDoorEvent:
+			if entry.Door != "" {
+				sd.handleSecondaryDoorEvent(entry.Door, entry.DoorEvent)
+				break
+			}
+			sd.handleDoorEvent(entry.DoorEvent)
+		case TraceKindDoorState:
+			sd.handleDoorState(entry.DoorState)
+		case TraceKindCycle:
+			if entry.Cycle == nil {
+				continue
+			}
+			result := entry.Cycle.result()
+			sd.logCycle(result, sd.handleCycle(ctx, result))
+		case TraceKindTick:
+			switch entry.Tick {
+			case tickHeartbeat:
+				sd.emitHeartbeat(entry.Time)
+			case tickSummary:
+				sd.EmitSummary()
+			}
+		case TraceKindCall:
+			if entry.Call == callCancelPending {
+				if len(pending) > 0 && !pending[0].due.IsZero() {
+					sd.logger.Info(fmt.Sprintf("pending %s cancelled", pending[0].action))
+					sd.dropPending(pending[0].action)
+					pending = pending[1:]
+					announce(entry.Time)
+				}
+				break
+			}
+			if err := p.call(sd, entry); err != nil {
+				return fmt.Errorf("replay %s at %s: %w", entry.Call, entry.Time.Format(time.RFC3339), err)
+			}
+		}
+		// Door failures are reported as events, as in Run.
+		for _, action := range sd.actions.Drain() {
+			if len(pending) == 0 && sd.currentConfig().ConfirmDelay <= 0 {
+				sd.executeAction(ctx, action)
+				continue
+			}
+			pending = append(pending, replayPending{action: action})
+			announce(entry.Time)
+		}
+		confirm(entry.Time)
+		sd.applyDoors(ctx)
+	}
+	return nil
+}
+
+// replayPending is an action a replay holds for its confirm window.
+type replayPending struct {
+	action DoorAction
+	due    time.Time
+}
+
+// call applies a traced manual input other than CancelPending, which Play
+// handles itself.
+func (p *TracePlayer) call(sd *SmartDoor, entry TraceEntry) error {
+	switch entry.Call {
+	case callForceLock:
+		sd.ForceLock(entry.Until)
+	case callForceUnlock:
+		sd.ForceUnlock(entry.Until)
+	case callClearOverride:
+		sd.ClearOverride()
+	case callArm:
+		sd.Arm()
+	case callDisarm:
+		sd.Disarm()
+	case callUpdateConfig:
+		if entry.Config == nil {
+			return errors.New("no config")
+		}
+		return sd.UpdateConfig(*entry.Config)
+	case callSetProfiles:
+		return sd.SetProfiles(entry.Profiles...)
+	default:
+		return fmt.Errorf("unknown call %q", entry.Call)
+	}
+	return nil
+}
+
+// traceClock is a Clock that only moves when the player sets it, firing
+// the timers and tickers it passes. Unlike the test fakes it ships with the
+// package, since offline replay through TracePlayer is part of the API; the
+// tickers of a replay are not running, so the ticks they fired in the
+// recorded run come from the trace instead.
+type traceClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*traceWaiter
+}
+
+type traceWaiter struct {
+	clock  *traceClock
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+func (c *traceClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *traceClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).ch
+}
+
+func (c *traceClock) NewTicker(d time.Duration) Ticker {
+	return c.add(d, d)
+}
+
+func (c *traceClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, 0)
+}
+
+func (c *traceClock) add(d, period time.Duration) *traceWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &traceWaiter{clock: c, at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// set moves the clock to t, never backwards.
+func (c *traceClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.now = t
+	}
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(c.now) {
+				w.at = w.at.Add(w.period)
+			}
+			kept = append(kept, w)
+		}
+	}
+	c.waiters = kept
+}
+
+func (w *traceWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *traceWaiter) Stop() {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
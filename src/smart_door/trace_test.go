@@ -0,0 +1,283 @@
+package smartdoor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// replayedKinds are the events a replay must reproduce.
+var replayedKinds = map[EventKind]bool{
+	EventAction:              true,
+	EventFailSafe:            true,
+	EventClassifierRecovered: true,
+}
+
+func drainReplayed(events <-chan Event) []string {
+	var got []string
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			if replayedKinds[event.Kind] {
+				got = append(got, fmt.Sprintf("%s %s %s", event.Kind, event.Action, event.Message))
+			}
+		default:
+			return got
+		}
+	}
+}
+
+func TestTracePlayerReproducesRecordedActions(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalDurationUnlocking = 30 * time.Second
+	config.FailSafeAfterErrors = 2
+	var trace bytes.Buffer
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{}, WithTraceRecorder(NewTraceRecorder(&trace)))
+	events := sd.Events()
+	ctx := context.Background()
+	frame := Frame{Data: []byte{1, 2, 3}, Format: FrameFormatJPEG, CapturedAt: clock.Now()}
+	dog := CycleResult{Frames: []Frame{frame}, Classifications: dogBatch(), Outcome: OutcomeDecided}
+	none := CycleResult{Frames: []Frame{frame}, Classifications: noneBatch(), Outcome: OutcomeDecided}
+	failed := CycleResult{Outcome: OutcomeError, Err: fmt.Errorf("classify frames from cam0: %w", errBusy)}
+
+	sd.handleCameraEvent(defaultCameraID, CameraEventConnected)
+	sd.handleDoorEvent(DoorEventConnected)
+	for _, step := range []struct {
+		after  time.Duration
+		result CycleResult
+	}{
+		{0, dog},
+		{10 * time.Second, none},
+		{40 * time.Second, none},
+		{time.Minute, dog},
+		{time.Minute, failed},
+		{time.Second, CycleResult{Outcome: OutcomeError, Err: fmt.Errorf("cam0: %w", ErrBreakerOpen)}},
+		{time.Minute, none},
+	} {
+		clock.Advance(step.after)
+		sd.handleCycle(ctx, step.result)
+		applyActions(sd)
+	}
+	sd.handleDoorEvent(DoorEventDisconnected)
+	want := drainReplayed(events)
+	if err := sd.tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actions := door.Actions(); !reflect.DeepEqual(actions, []DoorAction{ActionUnlock, ActionLock, ActionUnlock, ActionLock}) {
+		t.Fatalf("unexpected recorded actions %v", actions)
+	}
+
+	player, err := NewTracePlayer(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, _, replayDoor, _ := newTestSmartDoor(config, &fakeClassifier{}, WithClock(player.Clock()))
+	replayEvents := replay.Events()
+	if err := player.Play(ctx, replay); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replayDoor.Actions(); !reflect.DeepEqual(got, door.Actions()) {
+		t.Fatalf("expected replayed actions %v, got %v", door.Actions(), got)
+	}
+	if got := drainReplayed(replayEvents); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected replayed events %q, got %q", want, got)
+	}
+	if c := replay.Connectivity(); !c.Camera.Connected || c.Door.Connected {
+		t.Fatalf("expected replayed connectivity, got %+v", c)
+	}
+}
+
+func TestTracePlayerNeedsItsClock(t *testing.T) {
+	player, err := NewTracePlayer(&bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{})
+	if err := player.Play(context.Background(), sd); err == nil {
+		t.Fatal("expected a SmartDoor on another clock refused")
+	}
+}
+
+func nextSummary(t *testing.T, events <-chan Event) Summary {
+	t.Helper()
+	for event := range events {
+		if event.Kind == EventSummary {
+			return *event.Summary
+		}
+	}
+	t.Fatal("expected a summary event")
+	return Summary{}
+}
+
+func TestTracePlayerReplaysEveryDoorAndTick(t *testing.T) {
+	config := dogDoorConfig()
+	config.SummaryInterval = time.Minute
+	rules := DoorRules{DetectionDog: ActionLock}
+	var trace bytes.Buffer
+	inner := newFakeDoor()
+	sd, _, _, clock := newTestSmartDoor(config, &fakeClassifier{},
+		WithTraceRecorder(NewTraceRecorder(&trace)), WithDoor("inner", inner, rules))
+	events := sd.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sd.handleCycle(ctx, CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided})
+	applyActions(sd)
+	if err := sd.applyDoors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sd.summarize(ctx)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	want := nextSummary(t, events)
+	cancel()
+	<-done
+
+	player, err := NewTracePlayer(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayInner := newFakeDoor()
+	replay, _, _, _ := newTestSmartDoor(config, &fakeClassifier{},
+		WithClock(player.Clock()), WithDoor("inner", replayInner, rules))
+	replayEvents := replay.Events()
+	if err := player.Play(context.Background(), replay); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replayInner.Actions(); !reflect.DeepEqual(got, inner.Actions()) {
+		t.Fatalf("expected the secondary door to replay %v, got %v", inner.Actions(), got)
+	}
+	got := nextSummary(t, replayEvents)
+	if !got.End.Equal(want.End) || got.Unlocks != want.Unlocks || got.Locks != want.Locks {
+		t.Fatalf("expected the summary tick replayed as %+v, got %+v", want, got)
+	}
+}
+
+func TestTracePlayerReplaysManualInputs(t *testing.T) {
+	config := dogDoorConfig()
+	config.MinimalRateCameraProcess = time.Second
+	var trace bytes.Buffer
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{}, WithTraceRecorder(NewTraceRecorder(&trace)))
+	events := sd.Events()
+	ctx := context.Background()
+	dog := CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}
+	cycle := func(after time.Duration) {
+		clock.Advance(after)
+		sd.handleCycle(ctx, dog)
+		applyActions(sd)
+	}
+
+	sd.Disarm()
+	cycle(0)
+	sd.Arm()
+	cycle(time.Second)
+	sd.ForceLock(clock.Now().Add(time.Hour))
+	applyActions(sd)
+	cycle(10 * time.Second)
+	updated := config
+	hour := (clock.Now().UTC().Hour() + 6) % 24
+	quiet, err := NewSchedule("UTC", fmt.Sprintf("%02d:00-%02d:30", hour, hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated.QuietHours = quiet
+	if err := sd.UpdateConfig(updated); err != nil {
+		t.Fatal(err)
+	}
+	sd.ClearOverride()
+	cycle(10 * time.Second)
+	want := drainReplayed(events)
+	if err := sd.tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actions := door.Actions(); !reflect.DeepEqual(actions, []DoorAction{ActionUnlock, ActionLock, ActionUnlock}) {
+		t.Fatalf("unexpected recorded actions %v", actions)
+	}
+
+	player, err := NewTracePlayer(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, _, replayDoor, _ := newTestSmartDoor(config, &fakeClassifier{}, WithClock(player.Clock()))
+	replayEvents := replay.Events()
+	if err := player.Play(ctx, replay); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replayDoor.Actions(); !reflect.DeepEqual(got, door.Actions()) {
+		t.Fatalf("expected replayed actions %v, got %v", door.Actions(), got)
+	}
+	if got := drainReplayed(replayEvents); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected replayed events %q, got %q", want, got)
+	}
+	if got := replay.EffectiveConfig().QuietHours; got.Location == nil || got.Location.String() != "UTC" ||
+		!reflect.DeepEqual(got.Windows, quiet.Windows) {
+		t.Fatalf("expected the updated config replayed, got quiet hours %+v", got)
+	}
+}
+
+func TestTracePlayerReplaysCancelPending(t *testing.T) {
+	config := dogDoorConfig()
+	config.ConfirmDelay = 5 * time.Second
+	var trace bytes.Buffer
+	sd, _, door, clock := newTestSmartDoor(config, &fakeClassifier{}, WithTraceRecorder(NewTraceRecorder(&trace)))
+	events := sd.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.executeActions(ctx, context.Background())
+	dog := CycleResult{Classifications: dogBatch(), Outcome: OutcomeDecided}
+	none := CycleResult{Classifications: noneBatch(), Outcome: OutcomeDecided}
+	awaitPending := func() {
+		for e := range events {
+			if e.Kind == EventPendingAction {
+				break
+			}
+		}
+		clock.BlockUntil(1)
+	}
+
+	sd.handleCycle(ctx, dog)
+	awaitPending()
+	if !sd.CancelPending() {
+		t.Fatal("expected the unlock pending")
+	}
+	clock.Advance(time.Second)
+	sd.handleCycle(ctx, none)
+	clock.Advance(time.Second)
+	sd.handleCycle(ctx, dog)
+	awaitPending()
+	clock.Advance(5 * time.Second)
+	waitFor(t, func() bool { return len(door.Actions()) == 1 })
+	sd.handleCycle(ctx, dog)
+	if err := sd.tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := NewTracePlayer(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, _, replayDoor, _ := newTestSmartDoor(config, &fakeClassifier{}, WithClock(player.Clock()))
+	if err := player.Play(context.Background(), replay); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := replayDoor.Actions(); !reflect.DeepEqual(got, []DoorAction{ActionUnlock}) {
+		t.Fatalf("expected only the confirmed unlock replayed, got %v", got)
+	}
+	if got := replay.Stats().PendingCancelled; got != 1 {
+		t.Fatalf("expected one cancelled action replayed, got %d", got)
+	}
+}
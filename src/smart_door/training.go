@@ -0,0 +1,116 @@
+package smartdoor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TrainingRecord is one sampled cycle captured for building a labeled
+// dataset: the frames, what the classifier said, and what the controller
+// did about it.
+type TrainingRecord struct {
+	Time            time.Time
+	Frames          []Frame
+	Classifications [][]Classification
+	Detection       Detection
+	Action          DoorAction
+}
+
+type TrainingSink interface {
+	WriteTrainingRecord(record TrainingRecord) error
+}
+
+func WithTrainingSink(sink TrainingSink) Option {
+	return func(sd *SmartDoor) {
+		sd.trainingSink = sink
+	}
+}
+
+// recordTrainingData writes every Config.TrainingSampleEvery-th cycle to
+// the training sink when Config.CaptureTrainingData is set.
+func (sd *SmartDoor) recordTrainingData(result CycleResult, decision decision) {
+	config := sd.currentConfig()
+	if !config.CaptureTrainingData || sd.trainingSink == nil {
+		return
+	}
+
+	every := config.TrainingSampleEvery
+	if every < 1 {
+		every = 1
+	}
+	sd.trainingCycles++
+	if (sd.trainingCycles-1)%every != 0 {
+		return
+	}
+
+	err := sd.trainingSink.WriteTrainingRecord(TrainingRecord{
+		Time:            sd.clock.Now(),
+		Frames:          cloneFrames(result.Frames),
+		Classifications: cloneClassifications(result.Classifications),
+		Detection:       decision.Detection,
+		Action:          decision.Action,
+	})
+
+	sd.mu.Lock()
+	if err != nil {
+		sd.stats.TrainingWriteFailures++
+	} else {
+		sd.stats.TrainingRecords++
+	}
+	sd.mu.Unlock()
+}
+
+// DirTrainingSink writes each record as record-N.json alongside its frames
+// as record-N-frame-M.bin in Dir.
+type DirTrainingSink struct {
+	Dir string
+
+	mu   sync.Mutex
+	next int
+}
+
+func NewDirTrainingSink(dir string) (*DirTrainingSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirTrainingSink{Dir: dir}, nil
+}
+
+type trainingRecordFile struct {
+	Time            time.Time          `json:"time"`
+	Frames          []string           `json:"frames"`
+	Classifications [][]Classification `json:"classifications"`
+	Detection       Detection          `json:"detection"`
+	Action          DoorAction         `json:"action"`
+}
+
+func (s *DirTrainingSink) WriteTrainingRecord(record TrainingRecord) error {
+	s.mu.Lock()
+	n := s.next
+	s.next++
+	s.mu.Unlock()
+
+	file := trainingRecordFile{
+		Time:            record.Time,
+		Classifications: record.Classifications,
+		Detection:       record.Detection,
+		Action:          record.Action,
+	}
+	for i, frame := range record.Frames {
+		name := fmt.Sprintf("record-%d-frame-%d.bin", n, i)
+		if err := os.WriteFile(filepath.Join(s.Dir, name), frame.Data, 0o644); err != nil {
+			return err
+		}
+		file.Frames = append(file.Frames, name)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir, fmt.Sprintf("record-%d.json", n)), data, 0o644)
+}
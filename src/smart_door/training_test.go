@@ -0,0 +1,106 @@
+package smartdoor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type recordingTrainingSink struct {
+	mu      sync.Mutex
+	records []TrainingRecord
+}
+
+func (s *recordingTrainingSink) WriteTrainingRecord(record TrainingRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingTrainingSink) Records() []TrainingRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TrainingRecord(nil), s.records...)
+}
+
+func TestTrainingDataIsSampled(t *testing.T) {
+	sink := &recordingTrainingSink{}
+	config := dogDoorConfig()
+	config.CaptureTrainingData = true
+	config.TrainingSampleEvery = 2
+	sd, _, _, _ := newTestSmartDoor(config, &fakeClassifier{}, WithTrainingSink(sink))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sd.controlDoor(ctx)
+
+	for i := 0; i < 5; i++ {
+		sd.classificationCh <- CycleResult{
+			Frames:          []Frame{{Data: []byte{byte(i)}}},
+			Classifications: dogBatch(),
+		}
+	}
+	waitFor(t, func() bool { return sd.Stats().TrainingRecords == 3 })
+
+	records := sink.Records()
+	for i, want := range []byte{0, 2, 4} {
+		if got := records[i].Frames[0].Data[0]; got != want {
+			t.Fatalf("record %d: expected frame from cycle %d, got %d", i, want, got)
+		}
+	}
+	if records[0].Detection != DetectionDog || records[0].Action != ActionUnlock {
+		t.Fatalf("expected first record to capture the unlock decision, got %+v", records[0])
+	}
+	if records[1].Action != ActionNone {
+		t.Fatalf("expected no action on a repeated detection, got %v", records[1].Action)
+	}
+}
+
+func TestTrainingDataDisabledByDefault(t *testing.T) {
+	sink := &recordingTrainingSink{}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithTrainingSink(sink))
+
+	sd.recordTrainingData(CycleResult{Classifications: dogBatch()}, decision{})
+
+	if records := sink.Records(); len(records) != 0 {
+		t.Fatalf("expected no records, got %d", len(records))
+	}
+}
+
+func TestDirTrainingSinkWritesFramesAndMetadata(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewDirTrainingSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sink.WriteTrainingRecord(TrainingRecord{
+		Frames:          []Frame{{Data: []byte("jpeg")}},
+		Classifications: dogBatch(),
+		Detection:       DetectionDog,
+		Action:          ActionUnlock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame, err := os.ReadFile(filepath.Join(dir, "record-0-frame-0.bin"))
+	if err != nil || string(frame) != "jpeg" {
+		t.Fatalf("expected frame data, got %q (%v)", frame, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "record-0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file trainingRecordFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Action != ActionUnlock || file.Classifications[0][0].Label != "dog" {
+		t.Fatalf("unexpected metadata %+v", file)
+	}
+}
@@ -0,0 +1,52 @@
+package smartdoor
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// version is the release this package was cut from. Builds can stamp their
+// own with -ldflags "-X smart_door.version=1.2.3".
+var version = "0.1.0"
+
+// BuildInfo identifies the build a binary was produced from.
+type BuildInfo struct {
+	Version   string
+	GoVersion string
+	// Module is the main module path, empty when built without module
+	// support.
+	Module string
+	// Revision and Time come from the VCS stamp, and Modified reports
+	// uncommitted changes at build time.
+	Revision string
+	Time     string
+	Modified bool
+}
+
+var buildInfo = sync.OnceValue(readBuildInfo)
+
+// Version returns the build this package is running as.
+func Version() BuildInfo {
+	return buildInfo()
+}
+
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{Version: version}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	info.Module = build.Main.Path
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.Time = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,13 @@
+package smartdoor
+
+import "testing"
+
+func TestVersionIsPopulated(t *testing.T) {
+	info := Version()
+	if info.Version == "" || info.GoVersion == "" {
+		t.Fatalf("expected version and Go version, got %+v", info)
+	}
+	if Version() != info {
+		t.Fatal("expected Version to be stable")
+	}
+}
@@ -0,0 +1,28 @@
+package smartdoor
+
+import "context"
+
+// ActionVeto is a last say over the action a detection calls for, for
+// checks the classifier cannot make, such as a separate presence sensor.
+// Veto returning false lets the action proceed; returning true replaces it
+// with the returned action, and ActionNone cancels it. It is only consulted
+// when a detection would act, never for the relock, cap, hold or fail-safe
+// rules, and is handed every classification in the batch.
+type ActionVeto interface {
+	Veto(ctx context.Context, detection Detection, classifications []Classification) (DoorAction, bool)
+}
+
+// WithVeto installs veto on the decision loop.
+func WithVeto(veto ActionVeto) Option {
+	return func(sd *SmartDoor) {
+		sd.veto = veto
+	}
+}
+
+func flatten(classifications [][]Classification) []Classification {
+	var all []Classification
+	for _, frame := range classifications {
+		all = append(all, frame...)
+	}
+	return all
+}
@@ -0,0 +1,58 @@
+package smartdoor
+
+import (
+	"context"
+	"testing"
+)
+
+type sensorVeto struct {
+	present bool
+	calls   int
+	seen    []Classification
+}
+
+func (v *sensorVeto) Veto(ctx context.Context, detection Detection, classifications []Classification) (DoorAction, bool) {
+	v.calls++
+	v.seen = classifications
+	if detection == DetectionDog && !v.present {
+		return ActionNone, true
+	}
+	return ActionNone, false
+}
+
+func TestVetoSuppressesUnlock(t *testing.T) {
+	veto := &sensorVeto{}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithVeto(veto))
+
+	if d := sd.handleClassifications(dogBatch()); d.Detection != DetectionDog || d.Action != ActionNone {
+		t.Fatalf("expected vetoed unlock, got %+v", d)
+	}
+	expectActions(t, sd)
+	if len(veto.seen) == 0 || veto.seen[0].Label != "dog" {
+		t.Fatalf("expected the batch's classifications, got %+v", veto.seen)
+	}
+
+	// The vetoed detection is reconsidered, so the unlock follows once the
+	// sensor agrees.
+	veto.present = true
+	sd.handleClassifications(dogBatch())
+	expectActions(t, sd, ActionUnlock)
+	if veto.calls != 2 || sd.Stats().Vetoes != 1 {
+		t.Fatalf("expected 2 calls and 1 veto, got %d and %d", veto.calls, sd.Stats().Vetoes)
+	}
+}
+
+type replacingVeto struct{}
+
+func (replacingVeto) Veto(ctx context.Context, detection Detection, classifications []Classification) (DoorAction, bool) {
+	return ActionLock, true
+}
+
+func TestVetoReplacesAction(t *testing.T) {
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithVeto(replacingVeto{}))
+
+	if d := sd.handleClassifications(dogBatch()); d.Action != ActionLock {
+		t.Fatalf("expected replaced lock, got %+v", d)
+	}
+	expectActions(t, sd, ActionLock)
+}
@@ -0,0 +1,96 @@
+package smartdoor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookNotifier posts the ActionContext of every action the door applies
+// to a URL as JSON, for home automation and the like.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+	logger Logger
+	// encoder, when set, posts the whole event in its format instead.
+	encoder EventEncoder
+}
+
+// NewWebhookNotifier posts to url with client, or http.DefaultClient when
+// nil. Failed posts are logged to logger when it is not nil.
+func NewWebhookNotifier(url string, client *http.Client, logger Logger) *WebhookNotifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	return &WebhookNotifier{url: url, client: client, logger: logger}
+}
+
+// SetEncoder posts each EventActionApplied encoded by encoder, with its
+// content type, instead of the ActionContext as JSON. Call it before Run.
+func (w *WebhookNotifier) SetEncoder(encoder EventEncoder) {
+	w.encoder = encoder
+}
+
+// Run posts every EventActionApplied from events until it is closed or ctx
+// is done. Pass a subscription from SmartDoor.Events, or from
+// SmartDoor.Notifications to stay silent during Config.QuietHours.
+func (w *WebhookNotifier) Run(ctx context.Context, events <-chan Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Kind != EventActionApplied || event.Context == nil {
+				continue
+			}
+			if err := w.notify(ctx, event); err != nil {
+				w.logger.Error(fmt.Sprintf("webhook %s: %v", event.Action, err))
+			}
+		}
+	}
+}
+
+func (w *WebhookNotifier) notify(ctx context.Context, event Event) error {
+	if w.encoder == nil {
+		return w.Notify(ctx, *event.Context)
+	}
+	body, err := w.encoder.Encode(event)
+	if err != nil {
+		return err
+	}
+	return w.post(ctx, w.encoder.ContentType(), body)
+}
+
+// Notify posts ac.
+func (w *WebhookNotifier) Notify(ctx context.Context, ac ActionContext) error {
+	body, err := json.Marshal(ac)
+	if err != nil {
+		return err
+	}
+	return w.post(ctx, "application/json", body)
+}
+
+func (w *WebhookNotifier) post(ctx context.Context, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package smartdoor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActionContextReachesWebhookIntact(t *testing.T) {
+	payloads := make(chan ActionContext, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ac ActionContext
+		if err := json.NewDecoder(r.Body).Decode(&ac); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		payloads <- ac
+	}))
+	defer server.Close()
+
+	executor := &recordingExecutor{}
+	sd, _, _, _ := newTestSmartDoor(dogDoorConfig(), &fakeClassifier{}, WithActionExecutor(executor))
+	events := sd.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewWebhookNotifier(server.URL, server.Client(), nil).Run(ctx, events)
+
+	batch := [][]Classification{{{Label: "dog", Confidence: 0.94}}}
+	decided := sd.handleCycle(ctx, CycleResult{Classifications: batch, Cameras: []string{"porch"}, Outcome: OutcomeDecided})
+	if decided.Action != ActionUnlock {
+		t.Fatalf("expected an unlock, got %+v", decided)
+	}
+	for _, action := range sd.actions.Drain() {
+		if err := sd.executeAction(ctx, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := ActionContext{
+		Action:     ActionUnlock,
+		Reason:     "unlocked: dog 0.94 on porch",
+		Label:      "dog",
+		Confidence: 0.94,
+		Camera:     "porch",
+		Time:       sd.clock.Now(),
+	}
+	got := <-payloads
+	if !got.Time.Equal(want.Time) {
+		t.Fatalf("expected time %v, got %v", want.Time, got.Time)
+	}
+	got.Time = want.Time
+	if got != want {
+		t.Fatalf("expected payload %+v, got %+v", want, got)
+	}
+	if ac, ok := executor.lastContext(); !ok || ac.Reason != want.Reason {
+		t.Fatalf("expected the executor to see the context, got %+v", ac)
+	}
+}